@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_seed_translations_source_trgm;
+DROP EXTENSION IF EXISTS pg_trgm;
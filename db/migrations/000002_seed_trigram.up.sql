@@ -0,0 +1,4 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_seed_translations_source_trgm
+    ON seed_translations USING gin (source_text gin_trgm_ops);
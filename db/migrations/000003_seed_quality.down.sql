@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_seed_translations_quality;
+ALTER TABLE seed_translations DROP COLUMN IF EXISTS quality;
+ALTER TABLE seed_translations DROP COLUMN IF EXISTS provenance;
@@ -0,0 +1,4 @@
+ALTER TABLE seed_translations ADD COLUMN IF NOT EXISTS provenance TEXT NOT NULL DEFAULT '';
+ALTER TABLE seed_translations ADD COLUMN IF NOT EXISTS quality TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_seed_translations_quality ON seed_translations (quality);
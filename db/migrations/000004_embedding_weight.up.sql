@@ -0,0 +1 @@
+ALTER TABLE embeddings ADD COLUMN IF NOT EXISTS weight DOUBLE PRECISION NOT NULL DEFAULT 1;
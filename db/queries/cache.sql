@@ -8,3 +8,9 @@ ON CONFLICT (hash) DO UPDATE SET translated = EXCLUDED.translated;
 
 -- name: ListAllCachedTranslations :many
 SELECT hash, translated FROM translation_cache;
+
+-- name: ListCachedTranslationPairs :many
+SELECT hash, source, translated FROM translation_cache;
+
+-- name: DeleteCachedTranslationsByHash :execrows
+DELETE FROM translation_cache WHERE hash = ANY($1::text[]);
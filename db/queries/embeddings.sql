@@ -1,16 +1,36 @@
--- name: InsertEmbeddingWithVector :exec
-INSERT INTO embeddings (hash, source, context, file_path, embedding)
-VALUES ($1, $2, $3, $4, $5::vector)
-ON CONFLICT (hash) DO NOTHING;
+-- name: UpsertEmbeddingWithVector :exec
+INSERT INTO embeddings (hash, source, context, file_path, embedding, weight)
+VALUES ($1, $2, $3, $4, $5::vector, $6)
+ON CONFLICT (hash) DO UPDATE SET
+    context = EXCLUDED.context,
+    file_path = EXCLUDED.file_path,
+    embedding = EXCLUDED.embedding,
+    weight = EXCLUDED.weight;
 
 -- name: SearchSimilarEmbeddings :many
-SELECT source, context, (1 - (embedding <=> $1::vector))::float8 AS similarity
+SELECT source, context, weight, (1 - (embedding <=> $1::vector))::float8 AS similarity
 FROM embeddings
 WHERE embedding IS NOT NULL
-ORDER BY embedding <=> $1::vector
+ORDER BY embedding <=> $1::vector, hash
 LIMIT $2;
 
 -- name: GetEmbeddingByHash :one
 SELECT id, hash, source, context, file_path, created_at
 FROM embeddings
 WHERE hash = $1;
+
+-- name: GetEmbeddingVectorByHash :one
+SELECT embedding
+FROM embeddings
+WHERE hash = $1 AND embedding IS NOT NULL;
+
+-- name: ListExistingEmbeddingHashes :many
+SELECT hash
+FROM embeddings
+WHERE hash = ANY($1::text[]) AND embedding IS NOT NULL;
+
+-- name: ListEmbeddingSources :many
+SELECT hash, source FROM embeddings;
+
+-- name: DeleteEmbeddingsByHash :execrows
+DELETE FROM embeddings WHERE hash = ANY($1::text[]);
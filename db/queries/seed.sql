@@ -1,21 +1,45 @@
 -- name: UpsertSeedTranslation :execresult
-INSERT INTO seed_translations (hash, source_text, translated_text, file, function_name, entity_type)
-VALUES ($1, $2, $3, $4, $5, $6)
+INSERT INTO seed_translations (hash, source_text, translated_text, file, function_name, entity_type, provenance, quality)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 ON CONFLICT (hash) DO UPDATE SET
     translated_text = EXCLUDED.translated_text,
     file = EXCLUDED.file,
     function_name = EXCLUDED.function_name,
     entity_type = EXCLUDED.entity_type,
+    provenance = EXCLUDED.provenance,
+    quality = EXCLUDED.quality,
     updated_at = NOW();
 
 -- name: GetAllSeedTranslations :many
-SELECT hash, source_text, translated_text, file, function_name, entity_type
+SELECT hash, source_text, translated_text, file, function_name, entity_type, provenance, quality
 FROM seed_translations
 WHERE is_seed = TRUE
 ORDER BY created_at;
 
+-- name: FindSeedTranslationsForText :many
+-- The ILIKE branch can use idx_seed_translations_source_trgm, but the strpos branch
+-- tests every seed against the text and always scans the table. Seed corpora are
+-- small enough for this; a larger one needs the seeds matched in the application.
+SELECT source_text, translated_text
+FROM seed_translations
+WHERE is_seed = TRUE
+  AND (strpos(sqlc.arg(text)::text, source_text) > 0 OR source_text ILIKE '%' || sqlc.arg(pattern)::text || '%')
+  AND (sqlc.narg(qualities)::text[] IS NULL OR quality = ANY(sqlc.narg(qualities)::text[]))
+ORDER BY length(source_text) DESC, source_text
+LIMIT sqlc.arg(max_results);
+
 -- name: GetSeedTranslationsByEntityType :many
-SELECT hash, source_text, translated_text, file, function_name, entity_type
+SELECT hash, source_text, translated_text, file, function_name, entity_type, provenance, quality
 FROM seed_translations
 WHERE is_seed = TRUE AND entity_type = $1
 ORDER BY created_at;
+
+-- name: SearchSeedTranslations :many
+SELECT hash, source_text, translated_text, file, function_name, entity_type, provenance, quality
+FROM seed_translations
+WHERE is_seed = TRUE
+  AND (source_text ILIKE '%' || sqlc.arg(query)::text || '%' OR translated_text ILIKE '%' || sqlc.arg(query)::text || '%')
+  AND (sqlc.narg(entity_type)::text IS NULL OR entity_type = sqlc.narg(entity_type)::text)
+  AND (sqlc.narg(quality)::text IS NULL OR quality = sqlc.narg(quality)::text)
+ORDER BY created_at
+LIMIT sqlc.arg(max_results);
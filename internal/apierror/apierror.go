@@ -0,0 +1,73 @@
+// Package apierror classifies failed Gemini API responses so callers can tell a
+// rate limit from an auth failure or a bad request without matching strings.
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Kinds of API failure. Match them with errors.Is; use errors.As with *Error to
+// get the HTTP status and response body.
+var (
+	ErrRateLimited    = errors.New("rate limited")
+	ErrAuth           = errors.New("authentication failed")
+	ErrInvalidRequest = errors.New("invalid request")
+	ErrServer         = errors.New("server error")
+	ErrBlocked        = errors.New("blocked by safety filters")
+)
+
+// Error is a failed API response.
+type Error struct {
+	// Kind is one of the Err* sentinels.
+	Kind error
+	// Status is the HTTP status code.
+	Status int
+	// Message is the response body or the API's error message.
+	Message string
+}
+
+// New classifies a failed response by status code and message. Gemini reports an
+// invalid API key as 400 rather than 401, so that case is recognized by message.
+func New(status int, message string) *Error {
+	var kind error
+	switch {
+	case status == http.StatusTooManyRequests:
+		kind = ErrRateLimited
+	case status == http.StatusUnauthorized || status == http.StatusForbidden,
+		strings.Contains(message, "API_KEY_INVALID"):
+		kind = ErrAuth
+	case status >= 500:
+		kind = ErrServer
+	default:
+		kind = ErrInvalidRequest
+	}
+	return &Error{Kind: kind, Status: status, Message: message}
+}
+
+// Blocked reports a request the model refused to answer, e.g. for safety reasons.
+// The same request is refused every time.
+func Blocked(reason string) *Error {
+	return &Error{Kind: ErrBlocked, Status: http.StatusOK, Message: reason}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("API %s (status %d): %s", e.Kind, e.Status, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// Retryable reports whether err may succeed when sent again: rate limits, server
+// errors and failures that never produced an API response, such as timeouts.
+// Auth failures and invalid requests fail the same way every time.
+func Retryable(err error) bool {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		return true
+	}
+	return apiErr.Kind == ErrRateLimited || apiErr.Kind == ErrServer
+}
@@ -6,17 +6,29 @@ import (
 	"sync"
 
 	"rag-translator/internal/dbgen"
+	"rag-translator/internal/language"
 	"rag-translator/internal/textutil"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/zerolog/log"
 )
 
+// Cache stores translations by source text for one target language.
+type Cache interface {
+	Get(ctx context.Context, sourceText string) (string, bool)
+	Set(ctx context.Context, sourceText, translated string) error
+	SetBatch(ctx context.Context, pairs map[string]string) error
+	Preload(ctx context.Context) error
+	Entries(ctx context.Context) (map[string]string, error)
+}
+
 // TranslationCache provides in-memory + PostgreSQL-backed caching for translations.
+// It is the Cache used by the CLI.
 type TranslationCache struct {
-	queries *dbgen.Queries
-	mu      sync.RWMutex
-	memory  map[string]string // hash → translated text
+	queries   *dbgen.Queries
+	mu        sync.RWMutex
+	memory    map[string]string // hash → translated text
+	namespace string            // empty for the default target language
 }
 
 // NewTranslationCache creates a new cache backed by PostgreSQL.
@@ -27,9 +39,27 @@ func NewTranslationCache(pool *pgxpool.Pool) *TranslationCache {
 	}
 }
 
+// SetTargetLanguage keys entries by target language so runs for different locales
+// do not share translations. The default target keeps plain source hashes.
+func (c *TranslationCache) SetTargetLanguage(code string) {
+	if language.Base(code) == language.Base(language.DefaultTarget) {
+		c.namespace = ""
+		return
+	}
+	c.namespace = language.Base(code)
+}
+
+// key computes the cache key for a source text.
+func (c *TranslationCache) key(sourceText string) string {
+	if c.namespace == "" {
+		return textutil.CanonicalHash(sourceText)
+	}
+	return textutil.CanonicalHash(c.namespace + "\x00" + sourceText)
+}
+
 // Get retrieves a cached translation. Returns empty string and false if not found.
 func (c *TranslationCache) Get(ctx context.Context, sourceText string) (string, bool) {
-	hash := textutil.Hash(sourceText)
+	hash := c.key(sourceText)
 
 	// Check in-memory cache first.
 	c.mu.RLock()
@@ -55,7 +85,7 @@ func (c *TranslationCache) Get(ctx context.Context, sourceText string) (string,
 
 // Set stores a translation in both in-memory and PostgreSQL cache.
 func (c *TranslationCache) Set(ctx context.Context, sourceText, translated string) error {
-	hash := textutil.Hash(sourceText)
+	hash := c.key(sourceText)
 
 	// Update in-memory.
 	c.mu.Lock()
@@ -102,3 +132,54 @@ func (c *TranslationCache) Preload(ctx context.Context) error {
 	log.Info().Int("count", len(rows)).Msg("Preloaded translation cache")
 	return nil
 }
+
+// Entries returns every cached source → translation pair for the current target
+// language.
+func (c *TranslationCache) Entries(ctx context.Context) (map[string]string, error) {
+	rows, err := c.queries.ListCachedTranslationPairs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list cached translations: %w", err)
+	}
+
+	entries := make(map[string]string)
+	for _, row := range rows {
+		// Rows of other target languages share the table under different keys.
+		if row.Hash == c.key(row.Source) {
+			entries[row.Source] = row.Translated
+		}
+	}
+	return entries, nil
+}
+
+// Sources returns the source text of every cached entry, keyed by hash. Unlike
+// Entries it covers all target languages.
+func (c *TranslationCache) Sources(ctx context.Context) (map[string]string, error) {
+	rows, err := c.queries.ListCachedTranslationPairs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list cached translations: %w", err)
+	}
+	sources := make(map[string]string, len(rows))
+	for _, row := range rows {
+		sources[row.Hash] = row.Source
+	}
+	return sources, nil
+}
+
+// Delete removes the entries with the given hashes from memory and PostgreSQL and
+// returns how many rows were deleted.
+func (c *TranslationCache) Delete(ctx context.Context, hashes []string) (int64, error) {
+	if len(hashes) == 0 {
+		return 0, nil
+	}
+	c.mu.Lock()
+	for _, hash := range hashes {
+		delete(c.memory, hash)
+	}
+	c.mu.Unlock()
+
+	deleted, err := c.queries.DeleteCachedTranslationsByHash(ctx, hashes)
+	if err != nil {
+		return 0, fmt.Errorf("cache delete: %w", err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"context"
+	"maps"
+	"sync"
+)
+
+// MemoryCache is a Cache held only in memory, for tests and small runs that have
+// no database. Its contents are lost when the process exits.
+type MemoryCache struct {
+	mu      sync.RWMutex
+	entries map[string]string // source → translated text
+}
+
+// NewMemoryCache creates an empty in-memory cache.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]string)}
+}
+
+// Get retrieves a cached translation. Returns empty string and false if not found.
+func (c *MemoryCache) Get(ctx context.Context, sourceText string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	translated, ok := c.entries[sourceText]
+	return translated, ok
+}
+
+// Set stores a translation.
+func (c *MemoryCache) Set(ctx context.Context, sourceText, translated string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[sourceText] = translated
+	return nil
+}
+
+// SetBatch stores multiple translations.
+func (c *MemoryCache) SetBatch(ctx context.Context, pairs map[string]string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	maps.Copy(c.entries, pairs)
+	return nil
+}
+
+// Preload is a no-op: everything is already in memory.
+func (c *MemoryCache) Preload(ctx context.Context) error {
+	return nil
+}
+
+// Entries returns a copy of every cached source → translation pair.
+func (c *MemoryCache) Entries(ctx context.Context) (map[string]string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return maps.Clone(c.entries), nil
+}
@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"rag-translator/internal/config"
+	"rag-translator/internal/rag"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func benchmarkEmbeddingsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "benchmark-embeddings <pairs.tsv>",
+		Short: "Compare the retrieval quality of two embedding models on a labeled sample",
+		Long: `Reads query→expected-neighbor pairs, one "query<TAB>expected" line each (blank lines
+and lines starting with # are skipped), and scores the configured EMBEDDING_MODEL
+against --model. Each model embeds the expected neighbors, plus the lines of
+--corpus as distractors, and retrieves the top --k for every query. The table
+reports recall@1, recall@k and the mean reciprocal rank per model. No database
+is needed.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			model, _ := cmd.Flags().GetString("model")
+			dimensions, _ := cmd.Flags().GetInt("dimensions")
+			corpusPath, _ := cmd.Flags().GetString("corpus")
+			k, _ := cmd.Flags().GetInt("k")
+			if model == "" {
+				return fmt.Errorf("--model is required")
+			}
+			if k < 1 {
+				return fmt.Errorf("--k must be at least 1")
+			}
+			return runBenchmarkEmbeddings(args[0], corpusPath, model, dimensions, k)
+		},
+	}
+
+	cmd.Flags().String("model", "", "Embedding model to compare with EMBEDDING_MODEL")
+	cmd.Flags().Int("dimensions", 0, "Output dimensions of --model (default EMBEDDING_DIMENSIONS)")
+	cmd.Flags().String("corpus", "", "File of distractor texts, one per line, searched along with the expected neighbors")
+	cmd.Flags().Int("k", 5, "Number of nearest neighbors retrieved per query")
+
+	return cmd
+}
+
+// runBenchmarkEmbeddings handles the `benchmark-embeddings` command.
+func runBenchmarkEmbeddings(pairsPath, corpusPath, model string, dimensions, k int) error {
+	ctx, cancel := setupContext()
+	defer cancel()
+
+	cfg, err := config.Load(envFile)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	pairs, err := readBenchmarkPairs(pairsPath)
+	if err != nil {
+		return err
+	}
+	var distractors []string
+	if corpusPath != "" {
+		lines, err := readLines(corpusPath)
+		if err != nil {
+			return fmt.Errorf("read corpus: %w", err)
+		}
+		for _, line := range lines {
+			if line = strings.TrimSpace(line); line != "" {
+				distractors = append(distractors, line)
+			}
+		}
+	}
+
+	other := *cfg
+	other.EmbeddingModel = model
+	if dimensions > 0 {
+		other.EmbeddingDimensions = dimensions
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "MODEL\tDIMENSIONS\tQUERIES\tCORPUS\tRECALL@1\tRECALL@%d\tMRR\n", k)
+	for _, c := range []*config.Config{cfg, &other} {
+		embeddingClient, err := newEmbeddingClient(c)
+		if err != nil {
+			return err
+		}
+		log.Info().Str("model", c.EmbeddingModel).Int("pairs", len(pairs)).Msg("Benchmarking embedding model")
+		result, err := rag.BenchmarkRecall(ctx, embeddingClient, pairs, distractors, k)
+		if err != nil {
+			return fmt.Errorf("benchmark %s: %w", c.EmbeddingModel, err)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.3f\t%.3f\t%.3f\n",
+			c.EmbeddingModel, c.EmbeddingDimensions, result.Queries, result.Corpus, result.RecallAt1, result.RecallAtK, result.MRR)
+	}
+	tw.Flush()
+
+	return nil
+}
+
+// readBenchmarkPairs reads "query<TAB>expected" lines, skipping blank lines and
+// # comments.
+func readBenchmarkPairs(path string) ([]rag.BenchmarkPair, error) {
+	lines, err := readLines(path)
+	if err != nil {
+		return nil, fmt.Errorf("read benchmark pairs: %w", err)
+	}
+	var pairs []rag.BenchmarkPair
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		query, expected, ok := strings.Cut(line, "\t")
+		query, expected = strings.TrimSpace(query), strings.TrimSpace(expected)
+		if !ok || query == "" || expected == "" {
+			return nil, fmt.Errorf("%s:%d: expected query<TAB>expected", path, i+1)
+		}
+		pairs = append(pairs, rag.BenchmarkPair{Query: query, Expected: expected})
+	}
+	if len(pairs) == 0 {
+		return nil, fmt.Errorf("%s holds no pairs", path)
+	}
+	return pairs, nil
+}
@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"rag-translator/internal/parser"
+)
+
+// bilingualMode selects whether `translate` writes a bilingual review file per
+// output file.
+type bilingualMode string
+
+const (
+	bilingualOff  bilingualMode = "off"
+	bilingualAlso bilingualMode = "also" // next to the translated file
+	bilingualOnly bilingualMode = "only" // instead of the translated file
+)
+
+// bilingualSuffix is appended to an output path to name its review file.
+const bilingualSuffix = ".bilingual.tsv"
+
+// writeBilingual writes every extracted text of a file, in order, with its
+// translation as TSV: line, source, target. Untranslated texts have an empty
+// target, so editors can proofread a file without diffing it against the source.
+func writeBilingual(path string, texts []parser.ExtractedText, translations map[string]string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create bilingual file: %w", err)
+	}
+	defer f.Close()
+
+	fmt.Fprintln(f, "# line\tsource\ttarget")
+	for _, et := range texts {
+		fmt.Fprintf(f, "%d\t%s\t%s\n", et.Line, escapeCell(et.Text), escapeCell(translations[et.Text]))
+	}
+	return nil
+}
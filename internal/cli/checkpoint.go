@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// checkpointRecord is one line of a checkpoint file: the translations of one
+// completed batch.
+type checkpointRecord struct {
+	Batch        int               `json:"batch"`
+	Target       string            `json:"target"`
+	Translations map[string]string `json:"translations"`
+}
+
+// checkpoint appends the translations of each completed batch to a JSON Lines
+// file, so that a resumed run can skip them even when their cache writes failed.
+type checkpoint struct {
+	file   *os.File
+	target string // target language code; records for another target are ignored on resume
+}
+
+// openCheckpoint opens the checkpoint file at path for a run into target. With
+// resume, the translations it already records are returned and new records are
+// appended; otherwise it starts empty.
+func openCheckpoint(path, target string, resume bool) (*checkpoint, map[string]string, error) {
+	done := make(map[string]string)
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resume {
+		var err error
+		if done, err = readCheckpoint(path, target); err != nil {
+			return nil, nil, err
+		}
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open checkpoint file: %w", err)
+	}
+	if resume {
+		if err := endLine(file); err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+	}
+	return &checkpoint{file: file, target: target}, done, nil
+}
+
+// endLine terminates a torn last line of the checkpoint file opened for appending,
+// so that the next record starts on a line of its own.
+func endLine(file *os.File) error {
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("stat checkpoint file: %w", err)
+	}
+	if info.Size() == 0 {
+		return nil
+	}
+	rf, err := os.Open(file.Name())
+	if err != nil {
+		return fmt.Errorf("open checkpoint file: %w", err)
+	}
+	defer rf.Close()
+	last := make([]byte, 1)
+	if _, err := rf.ReadAt(last, info.Size()-1); err != nil {
+		return fmt.Errorf("read checkpoint file: %w", err)
+	}
+	if last[0] != '\n' {
+		if _, err := file.Write([]byte{'\n'}); err != nil {
+			return fmt.Errorf("write checkpoint: %w", err)
+		}
+	}
+	return nil
+}
+
+// readCheckpoint returns the translations recorded for target in the checkpoint
+// file at path. A missing file records nothing, and torn lines, left by a run
+// killed mid-write, are skipped.
+func readCheckpoint(path, target string) (map[string]string, error) {
+	done := make(map[string]string)
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return done, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open checkpoint file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxCheckpointLine)
+	for scanner.Scan() {
+		var rec checkpointRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+		if rec.Target != target {
+			continue
+		}
+		for source, translated := range rec.Translations {
+			done[source] = translated
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan checkpoint file: %w", err)
+	}
+	return done, nil
+}
+
+// maxCheckpointLine bounds one record of a checkpoint file.
+const maxCheckpointLine = 16 * 1024 * 1024
+
+// record appends the translations of completed batch batchNum. It is synced to
+// disk so that it survives the process being killed right after.
+func (c *checkpoint) record(batchNum int, translations map[string]string) error {
+	if len(translations) == 0 {
+		return nil
+	}
+	line, err := json.Marshal(checkpointRecord{Batch: batchNum, Target: c.target, Translations: translations})
+	if err != nil {
+		return fmt.Errorf("marshal checkpoint record: %w", err)
+	}
+	if _, err := c.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	return c.file.Sync()
+}
+
+// Close closes the checkpoint file.
+func (c *checkpoint) Close() error {
+	return c.file.Close()
+}
@@ -3,31 +3,47 @@ package cli
 import (
 	"context"
 	"fmt"
+	"math/rand/v2"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"syscall"
+	"time"
+	"unicode/utf8"
 
 	"rag-translator/internal/cache"
 	"rag-translator/internal/config"
 	"rag-translator/internal/filewalker"
 	"rag-translator/internal/graph"
+	"rag-translator/internal/httpclient"
 	"rag-translator/internal/interpolation"
+	"rag-translator/internal/language"
 	"rag-translator/internal/parser"
+	"rag-translator/internal/progress"
 	"rag-translator/internal/rag"
 	"rag-translator/internal/seed"
+	"rag-translator/internal/segment"
 	"rag-translator/internal/textutil"
 	"rag-translator/internal/translation"
 	"rag-translator/internal/worker"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mattn/go-isatty"
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
 
+// envFile is the --env-file flag shared by all commands.
+var envFile string
+
+// traceAPIDir is the --trace-api flag shared by all commands.
+var traceAPIDir string
+
 // Execute runs the CLI application.
 func Execute() {
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
@@ -37,37 +53,323 @@ func Execute() {
 		Use:   "rag-translator",
 		Short: "GraphRAG-based game localization tool for 剑侠世界2",
 		Long:  "A production-grade GraphRAG translation tool for localizing Chinese wuxia MMORPG games to Vietnamese.",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			logFormat, _ := cmd.Flags().GetString("log-format")
+			logLevel, _ := cmd.Flags().GetString("log-level")
+			showProgress, _ := cmd.Flags().GetBool("progress")
+			return setupLogging(logFormat, logLevel, showProgress)
+		},
 	}
 
+	rootCmd.PersistentFlags().StringVar(&envFile, "env-file", "", "Env file to load, overriding .env, .env.<APP_ENV> and the process environment")
+	rootCmd.PersistentFlags().StringVar(&traceAPIDir, "trace-api", "", "Write every Gemini API request and response to a file in this directory, with the API key redacted")
+	rootCmd.PersistentFlags().String("log-format", "console", "Log output format: console or json")
+	rootCmd.PersistentFlags().String("log-level", "info", "Minimum log level: trace, debug, info, warn, error")
+	rootCmd.PersistentFlags().Bool("progress", true, "Show progress bars for long phases (only on a terminal with console logs)")
+
 	rootCmd.AddCommand(ingestCmd())
 	rootCmd.AddCommand(translateCmd())
 	rootCmd.AddCommand(ingestSeedGitCmd())
+	rootCmd.AddCommand(estimateCmd())
+	rootCmd.AddCommand(diffCmd())
+	rootCmd.AddCommand(rebuildGraphCmd())
+	rootCmd.AddCommand(seedCmd())
+	rootCmd.AddCommand(warmCacheCmd())
+	rootCmd.AddCommand(lintCmd())
+	rootCmd.AddCommand(pruneCmd())
+	rootCmd.AddCommand(pingCmd())
+	rootCmd.AddCommand(glossaryReportCmd())
+	rootCmd.AddCommand(benchmarkEmbeddingsCmd())
+	rootCmd.AddCommand(importTermsCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
 	}
 }
 
+// concurrencyOptions holds the per-run concurrency overrides shared by `ingest`
+// and `translate`. Zero means use the configured value.
+type concurrencyOptions struct {
+	workers        int
+	apiConcurrency int
+	maxRetries     int // -1 means use the configured value
+	timeout        time.Duration
+	embedTimeout   time.Duration
+}
+
+// addConcurrencyFlags registers --workers, --api-concurrency, --max-retries and
+// the API timeout flags on cmd.
+func addConcurrencyFlags(cmd *cobra.Command) {
+	cmd.Flags().Int("workers", 0, "Number of file parsing workers (overrides WORKER_COUNT)")
+	cmd.Flags().Int("api-concurrency", 0, "Maximum concurrent API calls (overrides MAX_CONCURRENT_API_CALLS)")
+	cmd.Flags().Int("max-retries", 0, "Retries of a failed API request, 0 to fail fast (overrides API_MAX_RETRIES)")
+	cmd.Flags().Duration("translation-timeout", 0, "Timeout of one translation request, e.g. 5m (overrides TRANSLATION_TIMEOUT)")
+	cmd.Flags().Duration("embedding-timeout", 0, "Timeout of one embedding request, e.g. 30s (overrides EMBEDDING_TIMEOUT)")
+}
+
+// addSniffContentFlag registers --sniff-content on cmd.
+func addSniffContentFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("sniff-content", false, "Pick the parser of .lua, .ini and .txt files by their content when it contradicts the extension (overrides SNIFF_FILE_CONTENT)")
+}
+
+// addIncludeCommentsFlag registers --include-comments on cmd.
+func addIncludeCommentsFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("include-comments", false, "Extract source text in Lua and INI comments as translatable (overrides INCLUDE_COMMENTS)")
+}
+
+// readConcurrencyFlags reads the flags registered by addConcurrencyFlags.
+func readConcurrencyFlags(cmd *cobra.Command) (concurrencyOptions, error) {
+	opts := concurrencyOptions{maxRetries: -1}
+	if cmd.Flags().Changed("workers") {
+		opts.workers, _ = cmd.Flags().GetInt("workers")
+		if opts.workers < 1 {
+			return opts, fmt.Errorf("--workers must be at least 1")
+		}
+	}
+	if cmd.Flags().Changed("api-concurrency") {
+		opts.apiConcurrency, _ = cmd.Flags().GetInt("api-concurrency")
+		if opts.apiConcurrency < 1 {
+			return opts, fmt.Errorf("--api-concurrency must be at least 1")
+		}
+	}
+	if cmd.Flags().Changed("max-retries") {
+		opts.maxRetries, _ = cmd.Flags().GetInt("max-retries")
+		if opts.maxRetries < 0 {
+			return opts, fmt.Errorf("--max-retries must not be negative")
+		}
+	}
+	if cmd.Flags().Changed("translation-timeout") {
+		opts.timeout, _ = cmd.Flags().GetDuration("translation-timeout")
+		if opts.timeout <= 0 {
+			return opts, fmt.Errorf("--translation-timeout must be positive")
+		}
+	}
+	if cmd.Flags().Changed("embedding-timeout") {
+		opts.embedTimeout, _ = cmd.Flags().GetDuration("embedding-timeout")
+		if opts.embedTimeout <= 0 {
+			return opts, fmt.Errorf("--embedding-timeout must be positive")
+		}
+	}
+	return opts, nil
+}
+
+// apply overrides the config values for flags that were set.
+func (o concurrencyOptions) apply(cfg *config.Config) {
+	if o.workers > 0 {
+		cfg.WorkerCount = o.workers
+	}
+	if o.apiConcurrency > 0 {
+		cfg.MaxConcurrentAPICalls = o.apiConcurrency
+	}
+	if o.maxRetries >= 0 {
+		cfg.APIMaxRetries = o.maxRetries
+	}
+	if o.timeout > 0 {
+		cfg.TranslationTimeout = o.timeout
+	}
+	if o.embedTimeout > 0 {
+		cfg.EmbeddingTimeout = o.embedTimeout
+	}
+}
+
+// setupLogging switches the global logger to the requested format and level.
+// Progress bars are drawn only when asked for, with console logs on a terminal.
+func setupLogging(format, level string, showProgress bool) error {
+	lvl, err := zerolog.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("invalid --log-level %q: %w", level, err)
+	}
+	zerolog.SetGlobalLevel(lvl)
+
+	switch format {
+	case "console":
+		if showProgress && isatty.IsTerminal(os.Stderr.Fd()) {
+			progress.Enable(os.Stderr)
+		}
+		log.Logger = log.Output(zerolog.ConsoleWriter{Out: progress.Output(os.Stderr)})
+	case "json":
+		log.Logger = zerolog.New(os.Stderr).With().Timestamp().Logger()
+	default:
+		return fmt.Errorf("invalid --log-format %q: must be console or json", format)
+	}
+	return nil
+}
+
 func ingestCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "ingest <directory>",
 		Short: "Parse game files, generate embeddings, and build knowledge graph",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runIngest(args[0])
+			opts, err := readConcurrencyFlags(cmd)
+			if err != nil {
+				return err
+			}
+			sniffContent, _ := cmd.Flags().GetBool("sniff-content")
+			includeComments, _ := cmd.Flags().GetBool("include-comments")
+			failFast, _ := cmd.Flags().GetBool("fail-fast")
+			keepGoing, _ := cmd.Flags().GetBool("keep-going")
+			if failFast && keepGoing && cmd.Flags().Changed("keep-going") {
+				return fmt.Errorf("--fail-fast cannot be combined with --keep-going")
+			}
+			return runIngest(args[0], opts, sniffContent, includeComments, failFast || !keepGoing)
 		},
 	}
+
+	addConcurrencyFlags(cmd)
+	addSniffContentFlag(cmd)
+	addIncludeCommentsFlag(cmd)
+	cmd.Flags().Bool("fail-fast", false, "Abort on the first parse, graph or embedding error")
+	cmd.Flags().Bool("keep-going", true, "Continue past errors and report them all at the end, exiting non-zero if any occurred (default)")
+
+	return cmd
+}
+
+// translateOptions holds the flag-driven settings for the `translate` command.
+type translateOptions struct {
+	concurrencyOptions
+	termMatch      string
+	fullGlossary   bool
+	onlyCached     bool
+	seedSource     string // "db", "graph" or "none"
+	overridesPath  string
+	topK           int // 0 means use RETRIEVAL_TOP_K
+	protect        interpolation.Options
+	coverageReport string  // optional .json or .tsv coverage report path
+	minCoverage    float64 // fail the run below this translated fraction; 0 disables
+	promptTemplate string  // optional prompt template file
+	since          string  // git ref or timestamp limiting the input files; empty means all
+	sniffContent   bool
+	withComments   bool   // --include-comments
+	previewPrompt  bool   // print the prompt of one batch and exit without calling the API
+	previewText    string // preview the batch holding this text instead of the first
+	unresolvedPath string // optional TSV export of texts that failed to translate
+	outputSuffix   string // overrides OUTPUT_SUFFIX when set
+	bilingual      bilingualMode
+	async          bool
+	asyncPoll      time.Duration
+	checkpointPath string
+	resume         bool
+	shuffleSeed    int64 // shuffle texts before batching with this seed; 0 keeps file order
 }
 
 func translateCmd() *cobra.Command {
-	return &cobra.Command{
-		Use:   "translate <input-dir> <output-dir>",
+	cmd := &cobra.Command{
+		Use:   "translate <input> [output]",
 		Short: "Translate game files using GraphRAG pipeline",
-		Args:  cobra.ExactArgs(2),
+		Long: `Translates every supported file under the input directory into the output directory,
+mirroring the tree. The input may also be a single file, in which case the output is the
+translated file path (or a directory to write it into).
+
+With --output-suffix the suffix is inserted before each output file's extension, and
+the output may be omitted to write translations next to their sources: file.lua is
+translated to file.vi.lua with --output-suffix .vi. Files already named like an
+output are not read as input, and an input file is never overwritten.`,
+		Args: cobra.RangeArgs(1, 2),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runTranslate(args[0], args[1])
+			var opts translateOptions
+			var err error
+			if opts.concurrencyOptions, err = readConcurrencyFlags(cmd); err != nil {
+				return err
+			}
+			opts.termMatch, _ = cmd.Flags().GetString("term-match-mode")
+			if segmentTerms, _ := cmd.Flags().GetBool("segment-terms"); segmentTerms && opts.termMatch == "" {
+				opts.termMatch = string(segment.Segmented)
+			}
+			opts.fullGlossary, _ = cmd.Flags().GetBool("full-glossary")
+			opts.onlyCached, _ = cmd.Flags().GetBool("only-cached")
+			opts.seedSource, _ = cmd.Flags().GetString("seed-source")
+			switch opts.seedSource {
+			case "db", "graph", "none":
+			default:
+				return fmt.Errorf("--seed-source must be db, graph or none")
+			}
+			opts.overridesPath, _ = cmd.Flags().GetString("overrides")
+			opts.protect.Tags, _ = cmd.Flags().GetBool("protect-tags")
+			opts.protect.Numbers, _ = cmd.Flags().GetBool("protect-numbers")
+			opts.coverageReport, _ = cmd.Flags().GetString("coverage-report")
+			opts.promptTemplate, _ = cmd.Flags().GetString("prompt-template")
+			opts.since, _ = cmd.Flags().GetString("since")
+			opts.sniffContent, _ = cmd.Flags().GetBool("sniff-content")
+			opts.withComments, _ = cmd.Flags().GetBool("include-comments")
+			opts.previewPrompt, _ = cmd.Flags().GetBool("preview-prompt")
+			opts.previewText, _ = cmd.Flags().GetString("preview-text")
+			opts.unresolvedPath, _ = cmd.Flags().GetString("unresolved")
+			opts.shuffleSeed, _ = cmd.Flags().GetInt64("shuffle-seed")
+			opts.outputSuffix, _ = cmd.Flags().GetString("output-suffix")
+			opts.checkpointPath, _ = cmd.Flags().GetString("checkpoint")
+			opts.resume, _ = cmd.Flags().GetBool("resume")
+			if opts.resume && opts.checkpointPath == "" {
+				return fmt.Errorf("--resume needs --checkpoint")
+			}
+			opts.async, _ = cmd.Flags().GetBool("async")
+			opts.asyncPoll, _ = cmd.Flags().GetDuration("async-poll")
+			if opts.async && opts.onlyCached {
+				return fmt.Errorf("--async cannot be combined with --only-cached")
+			}
+			if opts.asyncPoll <= 0 {
+				return fmt.Errorf("--async-poll must be positive")
+			}
+			bilingual, _ := cmd.Flags().GetString("bilingual")
+			opts.bilingual = bilingualMode(bilingual)
+			switch opts.bilingual {
+			case bilingualOff, bilingualAlso, bilingualOnly:
+			default:
+				return fmt.Errorf("--bilingual must be off, also or only")
+			}
+			if opts.previewText != "" {
+				opts.previewPrompt = true
+			}
+			if opts.previewPrompt && opts.onlyCached {
+				return fmt.Errorf("--preview-prompt cannot be combined with --only-cached")
+			}
+			opts.minCoverage, _ = cmd.Flags().GetFloat64("min-coverage")
+			if opts.minCoverage < 0 || opts.minCoverage > 1 {
+				return fmt.Errorf("--min-coverage must be between 0 and 1")
+			}
+			if cmd.Flags().Changed("top-k") {
+				opts.topK, _ = cmd.Flags().GetInt("top-k")
+				if opts.topK < 1 {
+					return fmt.Errorf("--top-k must be at least 1")
+				}
+			}
+			var outputDir string
+			if len(args) == 2 {
+				outputDir = args[1]
+			}
+			return runTranslate(args[0], outputDir, opts)
 		},
 	}
+
+	cmd.Flags().String("term-match-mode", "", "How terminology is matched in texts: substring, longest or segmented (overrides TERM_MATCH_MODE)")
+	cmd.Flags().Bool("segment-terms", false, "Match terminology on word boundaries using a dictionary segmenter")
+	_ = cmd.Flags().MarkDeprecated("segment-terms", "use --term-match-mode=segmented")
+	cmd.Flags().Bool("full-glossary", false, "Send the whole terminology map as a stable, cacheable system prompt prefix instead of per-batch terms")
+	cmd.Flags().String("seed-source", "db", "Where verified seed translations are looked up for prompts: db, graph or none")
+	cmd.Flags().Bool("only-cached", false, "Rebuild output files from cached translations only, without calling any API")
+	cmd.Flags().String("overrides", "", "TSV file of source<TAB>target manual corrections that always win")
+	cmd.Flags().Int("top-k", 0, "Number of similar texts to retrieve per query (overrides RETRIEVAL_TOP_K)")
+	cmd.Flags().Bool("protect-tags", false, "Keep inline markup tags such as <b> or <color=#ff0000> verbatim and translate only the text between them")
+	cmd.Flags().Bool("protect-numbers", false, "Keep numeric literals and attached units such as 50% or 120px verbatim")
+	cmd.Flags().String("prompt-template", "", "text/template file defining system, batch and/or single prompts to use instead of the built-in ones")
+	cmd.Flags().String("coverage-report", "", "Write per-file translation coverage to this path (.json for JSON, otherwise TSV)")
+	cmd.Flags().Float64("min-coverage", 0, "Fail the run when less than this fraction (0-1) of extracted texts is translated")
+	cmd.Flags().Bool("preview-prompt", false, "Print the system and user prompt of the first batch to stderr and exit without calling the translation API")
+	cmd.Flags().String("preview-text", "", "Like --preview-prompt, but for the batch holding this source text")
+	cmd.Flags().String("unresolved", "", "Write texts that failed to translate, with file, line and reason, to this TSV path; fill in the target column and apply it with --overrides")
+	cmd.Flags().String("bilingual", "off", "Write a <file>"+bilingualSuffix+" review file of line, source and translation per output file: off, also (next to the translated file) or only (instead of it)")
+	cmd.Flags().Int64("shuffle-seed", 0, "Shuffle texts before batching so neighbours in a file rarely share a batch; the same seed gives the same batches (0 keeps file order)")
+	cmd.Flags().String("checkpoint", "", "Append the translations of every completed batch to this JSON Lines file")
+	cmd.Flags().Bool("resume", false, "Skip the texts recorded in the --checkpoint file by an interrupted run, restoring their translations, and keep appending to it")
+	cmd.Flags().Bool("async", false, "Submit all batches as asynchronous Gemini batch jobs, which are slower to finish but cheaper and not rate-limited per request; texts a job fails to translate are then translated synchronously")
+	cmd.Flags().Duration("async-poll", time.Minute, "How often to check the state of asynchronous batch jobs")
+	cmd.Flags().String("output-suffix", "", "Insert this suffix before each output file's extension (e.g. .vi for file.vi.lua); with no output argument, translations are written next to their sources (overrides OUTPUT_SUFFIX)")
+	cmd.Flags().String("since", "", "Only translate files changed since this git ref, or modified since this timestamp (RFC 3339 or YYYY-MM-DD)")
+	addConcurrencyFlags(cmd)
+	addSniffContentFlag(cmd)
+	addIncludeCommentsFlag(cmd)
+
+	return cmd
 }
 
 func ingestSeedGitCmd() *cobra.Command {
@@ -81,22 +383,49 @@ Generates embeddings, updates knowledge graph, and produces a seed corpus file.`
 		RunE: func(cmd *cobra.Command, args []string) error {
 			exportFormat, _ := cmd.Flags().GetString("export")
 			exportPath, _ := cmd.Flags().GetString("output")
-			return runIngestSeedGit(args[0], args[1], args[2], exportFormat, exportPath)
+			nearDuplicates, _ := cmd.Flags().GetString("near-duplicates")
+			quality, _ := cmd.Flags().GetString("quality")
+			mode := seed.NearDuplicateMode(nearDuplicates)
+			switch mode {
+			case seed.NearDuplicatesOff, seed.NearDuplicatesMerge, seed.NearDuplicatesFlag:
+			default:
+				return fmt.Errorf("--near-duplicates must be off, merge or flag")
+			}
+			variableMismatch, _ := cmd.Flags().GetString("variable-mismatch")
+			varMode := seed.VariableMismatchMode(variableMismatch)
+			switch varMode {
+			case seed.VariableMismatchReject, seed.VariableMismatchFlag, seed.VariableMismatchOff:
+			default:
+				return fmt.Errorf("--variable-mismatch must be reject, flag or off")
+			}
+			return runIngestSeedGit(args[0], args[1], args[2], exportFormat, exportPath, quality, mode, varMode)
 		},
 	}
 
 	cmd.Flags().String("export", "tsv", "Export format: tsv or json")
 	cmd.Flags().String("output", "seed_corpus", "Output path for seed corpus (without extension)")
+	cmd.Flags().String("near-duplicates", "off", "Seeds whose source differs only in surrounding punctuation: off, merge or flag")
+	cmd.Flags().String("quality", "", "Quality label stored with every extracted pair (e.g. expert), matched by SEED_QUALITIES")
+	cmd.Flags().String("variable-mismatch", "reject", "Pairs whose interpolation variables, format specifiers or tags differ between source and translation: reject, flag or off")
 
 	return cmd
 }
 
 // runIngestSeedGit handles the `ingest-seed-git` command.
-func runIngestSeedGit(commitBase, commitTarget, folder, exportFormat, exportPath string) error {
+func runIngestSeedGit(commitBase, commitTarget, folder, exportFormat, exportPath, quality string, nearDuplicates seed.NearDuplicateMode, variableMismatch seed.VariableMismatchMode) error {
 	ctx, cancel := setupContext()
 	defer cancel()
 
-	cfg := config.Load()
+	cfg, err := config.Load(envFile)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	if _, _, err := applyLanguages(cfg); err != nil {
+		return err
+	}
 
 	pgPool, neo4jDriver, err := initDependencies(ctx, cfg)
 	if err != nil {
@@ -119,6 +448,9 @@ func runIngestSeedGit(commitBase, commitTarget, folder, exportFormat, exportPath
 		Msg("Starting seed ingestion from Git")
 
 	gitIngestor := seed.NewGitIngestor()
+	gitIngestor.SetWorkers(cfg.WorkerCount)
+	gitIngestor.SetQuality(quality)
+	gitIngestor.SetVariableMismatchMode(variableMismatch)
 	entries, err := gitIngestor.IngestFromGit(ctx, repoRoot, commitBase, commitTarget, folder)
 	if err != nil {
 		return fmt.Errorf("git ingestion: %w", err)
@@ -133,6 +465,7 @@ func runIngestSeedGit(commitBase, commitTarget, folder, exportFormat, exportPath
 
 	// 2. Initialize stores.
 	seedStore := seed.NewSeedStore(pgPool)
+	seedStore.SetNearDuplicateMode(nearDuplicates)
 
 	vectorStore := rag.NewVectorStore(pgPool)
 
@@ -141,7 +474,12 @@ func runIngestSeedGit(commitBase, commitTarget, folder, exportFormat, exportPath
 		return fmt.Errorf("ensure graph seed schema: %w", err)
 	}
 
-	// 3. Store seed entries (deduplicated by hash).
+	// 3. Store seed entries (deduplicated by hash). Merged near-duplicates are dropped
+	// here so the later steps only see entries that are actually stored.
+	entries, err = seedStore.FilterNearDuplicates(ctx, entries)
+	if err != nil {
+		return fmt.Errorf("check near-duplicate seeds: %w", err)
+	}
 	inserted, _, err := seedStore.Upsert(ctx, entries)
 	if err != nil {
 		return fmt.Errorf("upsert seed entries: %w", err)
@@ -149,14 +487,18 @@ func runIngestSeedGit(commitBase, commitTarget, folder, exportFormat, exportPath
 	log.Info().Int("inserted", inserted).Msg("Seed entries stored")
 
 	// 4. Generate and store embeddings.
-	embeddingClient := rag.NewEmbeddingClient(cfg.GeminiAPIKey, cfg.EmbeddingModel, cfg.EmbeddingDimensions)
+	embeddingClient, err := newEmbeddingClient(cfg)
+	if err != nil {
+		return err
+	}
 	vectorSeeder := seed.NewVectorSeeder(embeddingClient, vectorStore)
+	vectorSeeder.SetWeight(cfg.SeedWeight)
 	if err := vectorSeeder.IngestEmbeddings(ctx, entries, cfg.BatchSize); err != nil {
 		return fmt.Errorf("ingest seed embeddings: %w", err)
 	}
 
 	// 5. Update knowledge graph.
-	if err := graphSeeder.UpsertSeedNodes(ctx, entries); err != nil {
+	if _, err := graphSeeder.UpsertSeedNodes(ctx, entries); err != nil {
 		return fmt.Errorf("upsert seed graph nodes: %w", err)
 	}
 
@@ -207,18 +549,11 @@ func setupContext() (context.Context, context.CancelFunc) {
 
 // initDependencies creates all shared dependencies and runs migrations.
 func initDependencies(ctx context.Context, cfg *config.Config) (*pgxpool.Pool, neo4j.DriverWithContext, error) {
-	// PostgreSQL pool.
-	pgPool, err := pgxpool.New(ctx, cfg.DatabaseURL)
+	pgPool, err := initPostgres(ctx, cfg)
 	if err != nil {
-		return nil, nil, fmt.Errorf("connect PostgreSQL: %w", err)
+		return nil, nil, err
 	}
 
-	if err := pgPool.Ping(ctx); err != nil {
-		pgPool.Close()
-		return nil, nil, fmt.Errorf("ping PostgreSQL: %w", err)
-	}
-	log.Info().Msg("Connected to PostgreSQL")
-
 	// Neo4j driver.
 	neo4jDriver, err := neo4j.NewDriverWithContext(cfg.Neo4jURI, neo4j.BasicAuth(cfg.Neo4jUser, cfg.Neo4jPassword, ""))
 	if err != nil {
@@ -236,12 +571,143 @@ func initDependencies(ctx context.Context, cfg *config.Config) (*pgxpool.Pool, n
 	return pgPool, neo4jDriver, nil
 }
 
+// applyLanguages resolves the configured language pair and points source-text
+// detection in the parsers at the source language's scripts (or SOURCE_SCRIPTS).
+func applyLanguages(cfg *config.Config) (source, target language.Language, err error) {
+	source, err = language.Lookup(cfg.SourceLang)
+	if err != nil {
+		return source, target, err
+	}
+	target, err = language.Lookup(cfg.TargetLang)
+	if err != nil {
+		return source, target, err
+	}
+	scripts := source.Scripts
+	if len(cfg.SourceScripts) > 0 {
+		scripts = cfg.SourceScripts
+	}
+	if err := textutil.SetSourceScripts(scripts...); err != nil {
+		return source, target, err
+	}
+	textutil.SetFoldWidth(cfg.HashFoldWidth)
+	return source, target, nil
+}
+
+// logConcurrency reports the effective concurrency settings after overrides.
+func logConcurrency(cfg *config.Config) {
+	log.Info().
+		Int("workers", cfg.WorkerCount).
+		Int("api_concurrency", cfg.MaxConcurrentAPICalls).
+		Int("api_max_retries", cfg.APIMaxRetries).
+		Msg("Concurrency settings")
+}
+
+// initPostgres connects to PostgreSQL for commands that do not need the graph.
+func initPostgres(ctx context.Context, cfg *config.Config) (*pgxpool.Pool, error) {
+	pgPool, err := pgxpool.New(ctx, cfg.DatabaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("connect PostgreSQL: %w", err)
+	}
+
+	if err := pgPool.Ping(ctx); err != nil {
+		pgPool.Close()
+		return nil, fmt.Errorf("ping PostgreSQL: %w", err)
+	}
+	log.Info().Msg("Connected to PostgreSQL")
+
+	return pgPool, nil
+}
+
+// apiTransport returns the transport of API requests: the configured proxy and CA
+// settings, traced to files with --trace-api.
+func apiTransport(cfg *config.Config) (http.RoundTripper, error) {
+	transport, err := httpclient.NewTransport(cfg.HTTPOptions())
+	if err != nil {
+		return nil, fmt.Errorf("API transport: %w", err)
+	}
+	if traceAPIDir == "" {
+		return transport, nil
+	}
+	return httpclient.Trace(transport, traceAPIDir)
+}
+
+// newEmbeddingClient creates the embedding client configured by cfg, sending
+// requests through apiTransport.
+func newEmbeddingClient(cfg *config.Config) (*rag.EmbeddingClient, error) {
+	transport, err := apiTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	ec := rag.NewEmbeddingClient(cfg.GeminiAPIKey, cfg.EmbeddingModel, cfg.EmbeddingDimensions, cfg.APIMaxRetries)
+	ec.SetTransport(transport)
+	ec.SetTimeout(cfg.EmbeddingTimeout)
+	return ec, nil
+}
+
+// newOpusClient creates a translation client for model, sending requests through
+// apiTransport.
+func newOpusClient(cfg *config.Config, model string) (*translation.OpusClient, error) {
+	transport, err := apiTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	oc := translation.NewOpusClient(cfg.GeminiAPIKey, model, cfg.APIMaxRetries)
+	oc.SetTransport(transport)
+	oc.SetTimeout(cfg.TranslationTimeout)
+	return oc, nil
+}
+
+// newTermMatcher creates the terminology matcher of TERM_MATCH_MODE. Longest and
+// segmented segment texts over the terms, and segmented also over the words of
+// TERM_SEGMENT_WORDS_FILE.
+func newTermMatcher(cfg *config.Config, terminologyMap map[string]string) (*segment.Matcher, error) {
+	mode, err := segment.ParseMode(cfg.TermMatchMode)
+	if err != nil {
+		return nil, err
+	}
+	if mode == segment.Substring {
+		return segment.NewMatcher(mode, nil), nil
+	}
+
+	words := graph.SortedTermKeys(terminologyMap)
+	if mode == segment.Segmented && cfg.TermSegmentWordsFile != "" {
+		lines, err := readLines(cfg.TermSegmentWordsFile)
+		if err != nil {
+			return nil, fmt.Errorf("read segment words: %w", err)
+		}
+		for _, line := range lines {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				words = append(words, line)
+			}
+		}
+	}
+	log.Info().Str("mode", string(mode)).Int("words", len(words)).Msg("Dictionary term matching enabled")
+	return segment.NewMatcher(mode, words), nil
+}
+
 // runIngest handles the `ingest` command.
-func runIngest(inputDir string) error {
+func runIngest(inputDir string, opts concurrencyOptions, sniffContent, includeComments, failFast bool) error {
 	ctx, cancel := setupContext()
 	defer cancel()
 
-	cfg := config.Load()
+	cfg, err := config.Load(envFile)
+	if err != nil {
+		return err
+	}
+	opts.apply(cfg)
+	if sniffContent {
+		cfg.SniffContent = true
+	}
+	if includeComments {
+		cfg.IncludeComments = true
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	if _, _, err := applyLanguages(cfg); err != nil {
+		return err
+	}
+	logConcurrency(cfg)
 
 	pgPool, neo4jDriver, err := initDependencies(ctx, cfg)
 	if err != nil {
@@ -254,6 +720,8 @@ func runIngest(inputDir string) error {
 	vectorStore := rag.NewVectorStore(pgPool)
 
 	graphBuilder := graph.NewGraphBuilder(neo4jDriver)
+	graphBuilder.SetMissingEndpointMode(graph.MissingEndpointMode(cfg.MissingTermEndpoints))
+	graphBuilder.SetMaxConcurrent(cfg.Neo4jMaxConcurrent)
 	if err := graphBuilder.EnsureSchema(ctx); err != nil {
 		return fmt.Errorf("ensure graph schema: %w", err)
 	}
@@ -261,8 +729,17 @@ func runIngest(inputDir string) error {
 		return fmt.Errorf("seed terminology: %w", err)
 	}
 
+	weightRules, err := rag.ParseWeightRules(cfg.EmbeddingWeights)
+	if err != nil {
+		return fmt.Errorf("EMBEDDING_WEIGHTS: %w", err)
+	}
+	inputAbs, _ := filepath.Abs(inputDir)
+
 	// Walk and parse files.
-	w := filewalker.NewWalker()
+	w, err := newWalker(cfg)
+	if err != nil {
+		return err
+	}
 	entries, err := w.Walk(inputDir)
 	if err != nil {
 		return fmt.Errorf("walk input directory: %w", err)
@@ -277,16 +754,26 @@ func runIngest(inputDir string) error {
 		},
 	)
 
+	parseBar := progress.New("Parsing", len(entries))
+	parsePool.SetProgress(parseBar.Set)
 	parseResults := parsePool.Execute(ctx, entries)
+	parseBar.Finish()
 
 	// Collect all unique texts for embedding.
 	textSet := make(map[string]struct{})
+	variantSet := make(map[string]struct{})
 	var allTexts []string
 	var textContexts []string
+	var textFiles []string
+	var textWeights []float64
+	var entities []graph.TextEntity
 
+	ingestErrs := &ingestErrors{failFast: failFast}
 	for _, pr := range parseResults {
 		if pr.Err != nil {
-			log.Error().Err(pr.Err).Str("file", pr.Input.Path).Msg("Parse failed")
+			if err := ingestErrs.add("parse "+pr.Input.Path, pr.Err); err != nil {
+				return err
+			}
 			continue
 		}
 		if pr.Result == nil {
@@ -298,284 +785,1033 @@ func runIngest(inputDir string) error {
 				continue
 			}
 			textSet[et.Text] = struct{}{}
-			allTexts = append(allTexts, et.Text)
 
 			// Build context string.
 			var ctxParts []string
 			for k, v := range et.Context {
 				ctxParts = append(ctxParts, fmt.Sprintf("%s=%s", k, v))
 			}
-			textContexts = append(textContexts, strings.Join(ctxParts, "; "))
-
-			// Add entity to graph.
 			ctxStr := strings.Join(ctxParts, "; ")
-			if err := graphBuilder.AddEntityFromText(ctx, et.Text, et.File, ctxStr); err != nil {
-				log.Warn().Err(err).Str("text", textutil.Truncate(et.Text, 30)).Msg("Failed to add entity to graph")
+			entities = append(entities, graph.TextEntity{Text: et.Text, File: et.File, Context: ctxStr})
+
+			// Variants of a text already embedded would only add a near-copy of its vector.
+			if cfg.MergeVariants {
+				key := textutil.VariantKey(et.Text)
+				if _, exists := variantSet[key]; exists {
+					continue
+				}
+				variantSet[key] = struct{}{}
+			}
+			// A text found in several files takes the path and weight of the first.
+			relPath := filepath.Base(et.File)
+			if rel, err := filepath.Rel(inputAbs, et.File); err == nil && rel != "." {
+				relPath = filepath.ToSlash(rel)
 			}
+			allTexts = append(allTexts, et.Text)
+			textContexts = append(textContexts, ctxStr)
+			textFiles = append(textFiles, relPath)
+			textWeights = append(textWeights, rag.FileWeight(weightRules, relPath))
 		}
 	}
 
 	log.Info().Int("unique_texts", len(allTexts)).Msg("Extracted unique texts")
 
-	// Generate embeddings.
-	embeddingClient := rag.NewEmbeddingClient(cfg.GeminiAPIKey, cfg.EmbeddingModel, cfg.EmbeddingDimensions)
-	embeddings, err := embeddingClient.EmbedBatch(ctx, allTexts, cfg.BatchSize)
-	if err != nil {
-		return fmt.Errorf("generate embeddings: %w", err)
+	// Add entities to graph.
+	if err := graphBuilder.AddEntitiesFromTexts(ctx, entities); err != nil {
+		if err := ingestErrs.add("add entities to graph", err); err != nil {
+			return err
+		}
 	}
 
-	// Store embeddings.
-	var records []rag.EmbeddingRecord
+	// Embed the texts not embedded by an earlier run.
+	records := make([]rag.EmbeddingRecord, len(allTexts))
 	for i, text := range allTexts {
-		if i >= len(embeddings) || embeddings[i] == nil {
-			continue
-		}
-		records = append(records, rag.EmbeddingRecord{
-			Hash:     textutil.Hash(text),
+		records[i] = rag.EmbeddingRecord{
+			Hash:     textutil.CanonicalHash(text),
 			Source:   text,
 			Context:  textContexts[i],
-			FilePath: "",
-			Vector:   embeddings[i],
-		})
+			FilePath: textFiles[i],
+			Weight:   textWeights[i],
+		}
 	}
-
-	if err := vectorStore.Store(ctx, records); err != nil {
-		return fmt.Errorf("store embeddings: %w", err)
+	embeddingClient, err := newEmbeddingClient(cfg)
+	if err != nil {
+		return err
+	}
+	skipped, stored, err := storeEmbeddings(ctx, records, vectorStore, embeddingClient, cfg.BatchSize, ingestErrs.add)
+	if err != nil {
+		return err
 	}
 
 	log.Info().
 		Int("files", len(entries)).
 		Int("texts", len(allTexts)).
-		Int("embeddings", len(records)).
+		Int("skipped", skipped).
+		Int("embeddings", stored).
+		Int("errors", len(ingestErrs.errs)).
 		Msg("Ingestion complete")
 
-	return nil
+	return ingestErrs.err()
 }
 
-// runTranslate handles the `translate` command.
-func runTranslate(inputDir, outputDir string) error {
-	ctx, cancel := setupContext()
-	defer cancel()
+// embeddingStore is the part of rag.VectorStore that ingest writes to.
+type embeddingStore interface {
+	ExistingHashes(ctx context.Context, hashes []string) (map[string]bool, error)
+	Store(ctx context.Context, records []rag.EmbeddingRecord) error
+}
 
-	cfg := config.Load()
+// batchEmbedder is the part of rag.EmbeddingClient that ingest uses.
+type batchEmbedder interface {
+	EmbedEach(ctx context.Context, texts []string, batchSize int, fn func(start int, embeddings [][]float32) error, onErr func(err error) error) error
+}
 
-	pgPool, neo4jDriver, err := initDependencies(ctx, cfg)
+// storeEmbeddings embeds the sources of records and stores each batch as soon as
+// it is ready. Records whose hash already has a stored embedding are skipped, so
+// an interrupted ingest resumes where it stopped. A failed batch is passed to
+// fail, which may return nil to go on with the next one. It returns the numbers
+// of records skipped and stored.
+func storeEmbeddings(ctx context.Context, records []rag.EmbeddingRecord, store embeddingStore, embedder batchEmbedder, batchSize int, fail func(what string, err error) error) (skipped, stored int, err error) {
+	hashes := make([]string, len(records))
+	for i, r := range records {
+		hashes[i] = r.Hash
+	}
+	existing, err := store.ExistingHashes(ctx, hashes)
 	if err != nil {
-		return err
+		return 0, 0, fmt.Errorf("check existing embeddings: %w", err)
 	}
-	defer pgPool.Close()
-	defer neo4jDriver.Close(ctx)
-
-	// Initialize components.
-	vectorStore := rag.NewVectorStore(pgPool)
-	embeddingClient := rag.NewEmbeddingClient(cfg.GeminiAPIKey, cfg.EmbeddingModel, cfg.EmbeddingDimensions)
-	graphQuerier := graph.NewGraphQuerier(neo4jDriver)
-	retriever := rag.NewRetriever(vectorStore, embeddingClient, graphQuerier)
-	promptBuilder := translation.NewPromptBuilder()
-	opusClient := translation.NewOpusClient(cfg.GeminiAPIKey, cfg.TranslationModel)
-	translationCache := cache.NewTranslationCache(pgPool)
-
-	// Preload cache.
-	if err := translationCache.Preload(ctx); err != nil {
-		log.Warn().Err(err).Msg("Failed to preload cache")
+	var pending []rag.EmbeddingRecord
+	for _, r := range records {
+		if !existing[r.Hash] {
+			pending = append(pending, r)
+		}
+	}
+	skipped = len(records) - len(pending)
+	if skipped > 0 {
+		log.Info().Int("skipped", skipped).Int("remaining", len(pending)).Msg("Resuming from stored embeddings")
 	}
 
-	// Get terminology map for batch prompts.
-	terminologyMap, err := graphQuerier.GetAllTerminology(ctx)
-	if err != nil {
-		log.Warn().Err(err).Msg("Failed to load terminology")
-		terminologyMap = make(map[string]string)
+	texts := make([]string, len(pending))
+	for i, r := range pending {
+		texts[i] = r.Source
 	}
+	embedBar := progress.New("Embedding", len(texts))
+	err = embedder.EmbedEach(ctx, texts, batchSize, func(start int, embeddings [][]float32) error {
+		embedBar.Add(len(embeddings))
+		var batch []rag.EmbeddingRecord
+		for k, vec := range embeddings {
+			if vec == nil {
+				continue
+			}
+			r := pending[start+k]
+			r.Vector = vec
+			batch = append(batch, r)
+		}
+		if err := store.Store(ctx, batch); err != nil {
+			return fail("store embeddings", err)
+		}
+		stored += len(batch)
+		return nil
+	}, func(err error) error {
+		return fail("generate embeddings", err)
+	})
+	embedBar.Finish()
+	return skipped, stored, err
+}
 
+// translationPlan is the parsed input tree and the deduplicated texts still needing translation.
+type translationPlan struct {
+	entries      []filewalker.FileEntry
+	parseResults []worker.Task[filewalker.FileEntry, *parser.ParseResult]
+	uniqueTexts  int
+	toTranslate  []string
+	formatTexts  map[string]bool // texts where % may be a format specifier
+	glossaries   map[string]*filewalker.Glossary
+	registers    map[string]string                    // text → register; absent means the default
+	splits       map[string]translation.SentenceSplit // long texts translated sentence by sentence
+	variants     map[string]string                    // variant → text whose translation it takes; see textutil.VariantKey
+}
+
+// planTranslation walks and parses inputDir, limited to files changed since since
+// when it is set (see filterSince), then collects unique texts for which isDone
+// reports false. Texts over cfg.SentenceSplitRunes are queued as their sentences
+// instead, and with cfg.MergeVariants a variant of an earlier text is not queued
+// but recorded in variants, unless it differs in more than padding, no-break
+// spaces and punctuation width (see textutil.VariantForms). It performs no API
+// calls.
+func planTranslation(ctx context.Context, cfg *config.Config, inputDir, since string, isDone func(text string) bool) (*translationPlan, error) {
 	// Walk and parse files.
-	w := filewalker.NewWalker()
+	w, err := newWalker(cfg)
+	if err != nil {
+		return nil, err
+	}
 	entries, err := w.Walk(inputDir)
 	if err != nil {
-		return fmt.Errorf("walk input directory: %w", err)
+		return nil, fmt.Errorf("walk input directory: %w", err)
+	}
+	if entries, err = filterSince(ctx, entries, inputDir, since); err != nil {
+		return nil, err
 	}
 
 	log.Info().Int("files", len(entries)).Msg("Starting translation pipeline")
 
-	// Ensure output directory exists.
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("create output directory: %w", err)
-	}
-
 	// Parse all files first.
 	parsePool := worker.NewPool[filewalker.FileEntry, *parser.ParseResult](cfg.WorkerCount,
 		func(ctx context.Context, entry filewalker.FileEntry) (*parser.ParseResult, error) {
 			return entry.Parser.Parse(entry.Path)
 		},
 	)
+	parseBar := progress.New("Parsing", len(entries))
+	parsePool.SetProgress(parseBar.Set)
 	parseResults := parsePool.Execute(ctx, entries)
+	parseBar.Finish()
 
 	// Collect deduplicated texts needing translation.
 	textSet := make(map[string]struct{})
+	formatTexts := make(map[string]bool)
+	glossaries := make(map[string]*filewalker.Glossary)
+	registers := make(map[string]string)
+	splits := make(map[string]translation.SentenceSplit)
+	variants := make(map[string]string)
+	// Variants merge only within one glossary and register, whose terminology
+	// shapes the translation.
+	type variantGroup struct {
+		key   string
+		group batchGroup
+	}
+	representatives := make(map[variantGroup]string) // first text of each group
+	queued := make(map[string]bool)
 	var textsToTranslate []string
 
+	// queue adds text to the texts to translate once, with the settings of from.
+	queue := func(text, from string) {
+		if queued[text] || isDone(text) {
+			return
+		}
+		queued[text] = true
+		if formatTexts[from] {
+			formatTexts[text] = true
+		}
+		if _, ok := registers[text]; !ok && registers[from] != "" {
+			registers[text] = registers[from]
+		}
+		if _, ok := glossaries[text]; !ok && glossaries[from] != nil {
+			glossaries[text] = glossaries[from]
+		}
+		textsToTranslate = append(textsToTranslate, text)
+	}
+
 	for _, pr := range parseResults {
 		if pr.Err != nil || pr.Result == nil {
 			continue
 		}
 		for _, et := range pr.Result.Texts {
+			if !cfg.LuaLiteralPercent || !parser.PercentIsLiteral(pr.Result.FileType, et) {
+				formatTexts[et.Text] = true
+			}
+			// A text used in several registers takes the first one found.
+			if _, ok := registers[et.Text]; !ok {
+				if r := graph.RegisterFromContext(et.Context); r != "" {
+					registers[et.Text] = r
+				}
+			}
 			if _, exists := textSet[et.Text]; exists {
 				continue
 			}
 			textSet[et.Text] = struct{}{}
+			// A text shared by several directories uses the glossary of the first.
+			if pr.Input.Glossary != nil {
+				glossaries[et.Text] = pr.Input.Glossary
+			}
 
-			// Check cache.
-			if _, cached := translationCache.Get(ctx, et.Text); cached {
+			var rep string
+			if cfg.MergeVariants {
+				key := variantGroup{
+					key:   textutil.VariantKey(et.Text),
+					group: batchGroup{glossary: glossaries[et.Text], register: registers[et.Text]},
+				}
+				if rep = representatives[key]; rep == "" {
+					representatives[key] = et.Text
+				} else if _, ok := textutil.VariantForms(rep, et.Text); !ok {
+					rep = "" // its translation would not carry over; translate it on its own
+				}
+			}
+
+			if isDone(et.Text) {
+				continue
+			}
+			if rep != "" {
+				variants[et.Text] = rep
 				continue
 			}
 
-			textsToTranslate = append(textsToTranslate, et.Text)
+			if split, ok := splitLong(et.Text, cfg.SentenceSplitRunes); ok {
+				splits[et.Text] = split
+				for _, sentence := range split.Sentences {
+					if textutil.ContainsSource(sentence) {
+						queue(sentence, et.Text)
+					}
+				}
+				continue
+			}
+			queue(et.Text, et.Text)
 		}
 	}
 
 	log.Info().
 		Int("total_unique", len(textSet)).
 		Int("to_translate", len(textsToTranslate)).
+		Int("merged_variants", len(variants)).
 		Msg("Translation plan")
 
-	// Translate texts in batches with concurrency control.
-	semaphore := make(chan struct{}, cfg.MaxConcurrentAPICalls)
-	systemPrompt := promptBuilder.GetSystemPrompt()
+	return &translationPlan{
+		entries:      entries,
+		parseResults: parseResults,
+		uniqueTexts:  len(textSet),
+		toTranslate:  textsToTranslate,
+		formatTexts:  formatTexts,
+		glossaries:   glossaries,
+		registers:    registers,
+		splits:       splits,
+		variants:     variants,
+	}, nil
+}
+
+// splitLong splits text into sentences when it is longer than maxRunes and holds
+// more than one sentence. A maxRunes of 0 never splits.
+func splitLong(text string, maxRunes int) (translation.SentenceSplit, bool) {
+	if maxRunes == 0 || utf8.RuneCountInString(text) <= maxRunes {
+		return translation.SentenceSplit{}, false
+	}
+	split := translation.SplitSentences(text)
+	return split, len(split.Sentences) > 1
+}
+
+// batchSteps are the stages of translating one batch with translateSplitting.
+type batchSteps struct {
+	// send requests the translation of batch and reports whether the response
+	// was cut off at the output token limit.
+	send func(ctx context.Context, batch []string) (response string, truncated bool, err error)
+	// fail handles a failed request; an error it returns stops the run.
+	fail func(ctx context.Context, batch []string, err error) error
+	// resolve restores and caches the segments of a complete response.
+	resolve func(ctx context.Context, batch []string, response string) error
+	sizer   *translation.BatchSizer
+}
 
-	batches := worker.Batch(textsToTranslate, cfg.BatchSize)
+// translateSplitting translates batch with steps. A response cut off at the
+// output token limit, or holding fewer segments than texts, means the batch was
+// too large: it is split in half and each half translated on its own, as
+// batchID.1 and batchID.2. Every message about the batch logs its batch_id.
+func translateSplitting(ctx context.Context, batchID string, batch []string, steps batchSteps) error {
+	ctx = translation.WithBatchID(ctx, batchID)
+	response, truncated, err := steps.send(ctx, batch)
+	if err != nil {
+		return steps.fail(ctx, batch, err)
+	}
+
+	segmentCount := translation.BatchSegmentCount(response)
+	if truncated || segmentCount != len(batch) {
+		steps.sizer.RecordMisaligned()
+	} else {
+		steps.sizer.RecordClean()
+	}
 
-	for batchIdx, batch := range batches {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
+	if len(batch) > 1 && (truncated || segmentCount < len(batch)) {
+		half := len(batch) / 2
+		translation.Logger(ctx).Warn().
+			Bool("truncated", truncated).
+			Int("size", len(batch)).
+			Int("split", half).
+			Msg("Batch response too short, splitting batch")
+		if err := translateSplitting(ctx, batchID+".1", batch[:half], steps); err != nil {
+			return err
 		}
+		return translateSplitting(ctx, batchID+".2", batch[half:], steps)
+	}
+	return steps.resolve(ctx, batch, response)
+}
 
-		semaphore <- struct{}{} // Acquire.
+// resolveBatch passes each text of batch to accept with its segment of response,
+// "" when the response has none, and to fallback when accept returns false. Each
+// index is handled on its own, so a missing segment never skips the ones after
+// it. It stops at the first error of fallback.
+func resolveBatch(batch []string, response string, accept func(i int, text, segment string) bool, fallback func(text string) error) error {
+	segments := translation.SplitBatchResponse(response, len(batch))
+	for i, text := range batch {
+		if accept(i, text, segments[i]) {
+			continue
+		}
+		if err := fallback(text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// batchGroup is what the texts of one batch must share: the terminology sent with
+// a batch depends on both.
+type batchGroup struct {
+	glossary *filewalker.Glossary
+	register string
+}
+
+// groupTexts splits texts into groups in which every text shares one directory
+// glossary and register, keeping the order of texts within each group. Batches
+// are cut from one group at a time.
+func groupTexts(texts []string, glossaries map[string]*filewalker.Glossary, registers map[string]string) [][]string {
+	var order []batchGroup
+	groups := make(map[batchGroup][]string)
+	for _, text := range texts {
+		g := batchGroup{glossary: glossaries[text], register: registers[text]}
+		if _, ok := groups[g]; !ok {
+			order = append(order, g)
+		}
+		groups[g] = append(groups[g], text)
+	}
+
+	grouped := make([][]string, 0, len(order))
+	for _, g := range order {
+		grouped = append(grouped, groups[g])
+	}
+	return grouped
+}
+
+// shuffleTexts returns a copy of texts in an order determined by seed alone. Batch
+// results are routed back by source text, so the order only affects which texts
+// share a batch.
+func shuffleTexts(texts []string, seed int64) []string {
+	shuffled := make([]string, len(texts))
+	copy(shuffled, texts)
+	rng := rand.New(rand.NewPCG(uint64(seed), 0))
+	rng.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	return shuffled
+}
+
+// runTranslate handles the `translate` command.
+func runTranslate(inputDir, outputDir string, opts translateOptions) error {
+	ctx, cancel := setupContext()
+	defer cancel()
+
+	cfg, err := config.Load(envFile)
+	if err != nil {
+		return err
+	}
+	opts.apply(cfg)
+	if opts.topK > 0 {
+		cfg.RetrievalTopK = opts.topK
+	}
+	if opts.sniffContent {
+		cfg.SniffContent = true
+	}
+	if opts.withComments {
+		cfg.IncludeComments = true
+	}
+	if opts.outputSuffix != "" {
+		cfg.OutputSuffix = opts.outputSuffix
+	}
+	if opts.termMatch != "" {
+		cfg.TermMatchMode = opts.termMatch
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	if outputDir == "" && cfg.OutputSuffix == "" {
+		return fmt.Errorf("an output path is required unless --output-suffix or OUTPUT_SUFFIX is set")
+	}
+	sourceLang, targetLang, err := applyLanguages(cfg)
+	if err != nil {
+		return err
+	}
+	logConcurrency(cfg)
+
+	if opts.onlyCached {
+		return runTranslateCached(ctx, cfg, targetLang, inputDir, outputDir, opts)
+	}
+
+	pgPool, neo4jDriver, err := initDependencies(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pgPool.Close()
+	defer neo4jDriver.Close(ctx)
+
+	// Initialize components.
+	vectorStore := rag.NewVectorStore(pgPool)
+	embeddingClient, err := newEmbeddingClient(cfg)
+	if err != nil {
+		return err
+	}
+	graphQuerier := graph.NewGraphQuerier(neo4jDriver)
+	// Everything but Neo4j-specific setup goes through the interface.
+	var graphContext rag.GraphContextProvider = graphQuerier
+	retriever := rag.NewRetriever(vectorStore, rag.NewCachedEmbedder(embeddingClient, vectorStore), graphContext)
+	retriever.SetCandidateFactor(cfg.CandidateFactor)
+	retriever.SetMinSimilarity(cfg.MinSimilarity)
+	retriever.SetDedupNormalized(cfg.DedupNormalized)
+	promptBuilder := translation.NewPromptBuilder()
+	opusClient, err := newOpusClient(cfg, cfg.TranslationModel)
+	if err != nil {
+		return err
+	}
+	// A request the primary model still fails after its retries, or refuses, is
+	// sent once more to the fallback model.
+	var fallbackClient *translation.OpusClient
+	if cfg.FallbackModel != "" {
+		if fallbackClient, err = newOpusClient(cfg, cfg.FallbackModel); err != nil {
+			return err
+		}
+		log.Info().Str("model", cfg.FallbackModel).Msg("Fallback translation model enabled")
+	}
+	translationCache := cache.NewTranslationCache(pgPool)
+
+	promptBuilder.SetLanguages(sourceLang, targetLang)
+	promptBuilder.SetSeedStrictness(translation.SeedStrictness(cfg.SeedStrictness))
+	if opts.promptTemplate != "" {
+		if err := promptBuilder.LoadTemplates(opts.promptTemplate); err != nil {
+			return err
+		}
+		log.Info().Str("path", opts.promptTemplate).Msg("Loaded prompt templates")
+	}
+	graphQuerier.SetTargetLanguage(targetLang.Code)
+	translationCache.SetTargetLanguage(targetLang.Code)
+	log.Info().Str("source", sourceLang.Code).Str("target", targetLang.Code).Msg("Language pair")
+
+	// Attach the seed corpus so verified translations reach the prompts.
+	seedStore := seed.NewSeedStore(pgPool)
+	seedStore.SetQualities(cfg.SeedQualities)
+	graphSeeder := seed.NewGraphSeeder(neo4jDriver)
+	graphSeeder.SetQualities(cfg.SeedQualities)
+	seedQuerier := selectSeedQuerier(opts.seedSource, targetLang, seedStore, graphSeeder)
+	if seedQuerier != nil {
+		retriever.SetSeedQuerier(seedQuerier)
+		log.Info().Str("source", opts.seedSource).Strs("qualities", cfg.SeedQualities).Msg("Seed retrieval enabled")
+	} else {
+		log.Info().Msg("Seed retrieval disabled")
+	}
+
+	// Preload cache.
+	if err := translationCache.Preload(ctx); err != nil {
+		log.Warn().Err(err).Msg("Failed to preload cache")
+	}
+
+	if err := applyOverrides(ctx, translationCache, opts.overridesPath); err != nil {
+		return err
+	}
+
+	// Get terminology map for batch prompts.
+	terminologyMap, err := graphContext.GetAllTerminology(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to load terminology")
+		terminologyMap = make(map[string]string)
+	} else if len(terminologyMap) == 0 {
+		log.Warn().
+			Str("target", targetLang.Code).
+			Str("property", graph.TermProperty(targetLang.Code)).
+			Msg("Knowledge graph has no terminology for the target language; load it with import-terms")
+	}
+
+	termMatcher, err := newTermMatcher(cfg, terminologyMap)
+	if err != nil {
+		return err
+	}
+	graphQuerier.SetTermMatcher(termMatcher)
+	promptBuilder.SetTermMatcher(termMatcher)
 
+	if opts.fullGlossary {
+		promptBuilder.SetGlossary(terminologyMap)
 		log.Info().
-			Int("batch", batchIdx+1).
-			Int("total_batches", len(batches)).
-			Int("size", len(batch)).
-			Msg("Translating batch")
+			Int("terms", len(terminologyMap)).
+			Int("prefix_tokens", translation.EstimateTokens(promptBuilder.GetSystemPrompt())).
+			Msg("Full glossary sent as stable prompt prefix")
+	}
+
+	// Numeric units left in translations are rewritten to the target convention.
+	var units map[string]string
+	if cfg.NumberUnitsFile != "" {
+		if units, err = translation.LoadNumberUnits(cfg.NumberUnitsFile); err != nil {
+			return err
+		}
+		log.Info().Int("units", len(units)).Str("path", cfg.NumberUnitsFile).Msg("Loaded number units")
+	} else if language.Base(targetLang.Code) == "vi" {
+		units = translation.DefaultVietnameseNumberUnits
+	}
+	numberUnits := translation.NewNumberUnits(units)
+
+	// Implausible model outputs are rejected before they reach the cache.
+	rejectRules, err := translation.ParseRejectRules(cfg.RejectRules)
+	if err != nil {
+		return fmt.Errorf("REJECT_TRANSLATIONS: %w", err)
+	}
+	var refusals []*regexp.Regexp
+	if cfg.RefusalPatternsFile != "" {
+		if refusals, err = translation.LoadRefusalPatterns(cfg.RefusalPatternsFile); err != nil {
+			return err
+		}
+		log.Info().Int("patterns", len(refusals)).Str("path", cfg.RefusalPatternsFile).Msg("Loaded refusal patterns")
+	}
+	plausibility := translation.NewPlausibilityCheck(rejectRules, refusals)
+
+	// Ensure the output location exists.
+	outputDir, err = prepareOutput(inputDir, outputDir, cfg.OutputSuffix)
+	if err != nil {
+		return err
+	}
+
+	plan, err := planTranslation(ctx, cfg, inputDir, opts.since, func(text string) bool {
+		_, cached := translationCache.Get(ctx, text)
+		return cached
+	})
+	if err != nil {
+		return err
+	}
+	entries := plan.entries
+	parseResults := plan.parseResults
+	textsToTranslate := plan.toTranslate
+
+	// protect hides placeholders from the model and strips padding whitespace, which
+	// restore puts back. Percent patterns are skipped for texts that only occur
+	// where % is literal.
+	protect := func(text string) (string, []interpolation.Mapping) {
+		protectOpts := opts.protect
+		protectOpts.LiteralPercent = !plan.formatTexts[text]
+		_, core, _ := textutil.SplitPadding(text)
+		return interpolation.ProtectWithOptions(core, protectOpts)
+	}
+	restore := func(text, translated string, mapping []interpolation.Mapping) string {
+		return numberUnits.Apply(text, textutil.Repad(text, interpolation.Restore(translated, mapping)))
+	}
+
+	if seedQuerier != nil && translation.SeedStrictness(cfg.SeedStrictness) == translation.SeedStrictnessHard {
+		textsToTranslate = applyExactSeeds(ctx, seedQuerier, translationCache, textsToTranslate)
+	}
 
+	// Translate texts in batches with concurrency control.
+	semaphore := make(chan struct{}, cfg.MaxConcurrentAPICalls)
+	systemPrompt := promptBuilder.GetSystemPrompt()
+	breaker := translation.NewCircuitBreaker(cfg.FailureThreshold)
+	sizer := translation.NewBatchSizer(cfg.BatchSize, cfg.BatchSizeMin, cfg.BatchSizeMax)
+	unresolved := newUnresolvedTexts()
+	fallbackTexts := 0 // texts whose translation came from the fallback model
+
+	// translateSingle translates one text with full RAG context and caches the result.
+	// It is the fallback when a batch response is missing or rejects a segment.
+	translateSingle := func(ctx context.Context, text string) {
+		retrievalResult, _ := retriever.RetrieveInRegister(ctx, text, plan.registers[text], cfg.RetrievalTopK)
+		protectedText, mapping := protect(text)
+		userPrompt := promptBuilder.BuildUserPrompt(protectedText, mapping, retriever, retrievalResult)
+		individual, err := opusClient.Translate(ctx, systemPrompt, userPrompt)
+		if err != nil && fallbackClient != nil && ctx.Err() == nil {
+			translation.Logger(ctx).Warn().Err(err).Str("text", textutil.Truncate(text, 30)).Msg("Individual translation failed, trying fallback model")
+			if individual, err = fallbackClient.Translate(ctx, systemPrompt, userPrompt); err == nil {
+				fallbackTexts++
+			}
+		}
+		if err != nil {
+			translation.Logger(ctx).Error().Err(err).Str("text", textutil.Truncate(text, 30)).Msg("Individual translation failed")
+			unresolved.add(text, err.Error())
+			breaker.RecordFailure(err)
+			return
+		}
+		breaker.RecordSuccess()
+		translated := restore(text, individual, mapping)
+		if err := translation.ValidateBalance(text, translated); err != nil {
+			translation.Logger(ctx).Error().Err(err).Str("text", textutil.Truncate(text, 30)).Msg("Unbalanced individual translation, leaving text untranslated")
+			unresolved.add(text, err.Error())
+			return
+		}
+		if err := plausibility.Check(text, translated); err != nil {
+			translation.Logger(ctx).Error().Err(err).Str("text", textutil.Truncate(text, 30)).Msg("Implausible individual translation, leaving text untranslated")
+			unresolved.add(text, err.Error())
+			return
+		}
+		if err := translationCache.Set(ctx, text, translated); err != nil {
+			translation.Logger(ctx).Warn().Err(err).Msg("Failed to cache translation")
+		}
+	}
+
+	// registerTerms returns the register renderings of the terminology, loading each
+	// register once. Batches are built and translated one at a time, so no locking
+	// is needed.
+	registerTermCache := make(map[string]map[string]string)
+	registerTerms := func(register string) map[string]string {
+		if terms, ok := registerTermCache[register]; ok {
+			return terms
+		}
+		terms, err := graphContext.GetRegisterTerminology(ctx, register)
+		if err != nil {
+			log.Warn().Err(err).Str("register", register).Msg("Failed to load register terminology")
+		}
+		registerTermCache[register] = terms
+		return terms
+	}
+
+	// buildBatchPrompt protects the texts of batch and builds its user prompt. It
+	// returns the prompt and the placeholder mappings to restore per text.
+	buildBatchPrompt := func(batch []string) (string, [][]interpolation.Mapping) {
 		// Protect interpolation variables.
 		protectedTexts := make([]string, len(batch))
 		mappings := make([][]interpolation.Mapping, len(batch))
 		for i, text := range batch {
-			protectedTexts[i], mappings[i] = interpolation.Protect(text)
+			protectedTexts[i], mappings[i] = protect(text)
 		}
 
-		// Build batch prompt with terminology.
-		relevantTerms := make(map[string]string)
-		for _, text := range batch {
-			for zh, vi := range terminologyMap {
-				if strings.Contains(text, zh) {
-					relevantTerms[zh] = vi
-				}
+		// Build batch prompt with terminology, unless the glossary is already in the prefix.
+		var relevantTerms map[string]string
+		if !opts.fullGlossary {
+			relevantTerms = promptBuilder.SelectTerms(batch, terminologyMap)
+		}
+		if register := plan.registers[batch[0]]; register != "" {
+			relevantTerms = promptBuilder.MergeTerms(batch, relevantTerms, registerTerms(register))
+		}
+		if g := plan.glossaries[batch[0]]; g != nil {
+			relevantTerms = promptBuilder.MergeTerms(batch, relevantTerms, g.Terms)
+		}
+
+		// Gather retrieval context for every text and merge it into one bounded section.
+		var batchContext *rag.RetrievalResult
+		if cfg.BatchContextItems > 0 {
+			results := make([]*rag.RetrievalResult, 0, len(batch))
+			for _, text := range batch {
+				r, _ := retriever.Retrieve(ctx, text, cfg.RetrievalTopK)
+				results = append(results, r)
 			}
+			batchContext = rag.MergeResults(results, cfg.BatchContextItems)
 		}
 
-		userPrompt := promptBuilder.BuildBatchUserPrompt(protectedTexts, relevantTerms)
+		return promptBuilder.BuildBatchUserPrompt(protectedTexts, mappings, relevantTerms, batchContext), mappings
+	}
+
+	// steps translate one batch and cache the results, see translateSplitting. A
+	// malformed response is retried once by TranslateBatchDetailed.
+	steps := batchSteps{sizer: sizer}
+	steps.send = func(ctx context.Context, batch []string) (string, bool, error) {
+		userPrompt, _ := buildBatchPrompt(batch)
 
 		// Call API.
-		response, err := opusClient.Translate(ctx, systemPrompt, userPrompt)
+		semaphore <- struct{}{} // Acquire.
+		response, truncated, err := opusClient.TranslateBatchDetailed(ctx, systemPrompt, userPrompt, len(batch))
+		if err != nil && fallbackClient != nil && ctx.Err() == nil {
+			translation.Logger(ctx).Warn().Err(err).Msg("Batch translation failed, trying fallback model")
+			if response, truncated, err = fallbackClient.TranslateBatchDetailed(ctx, systemPrompt, userPrompt, len(batch)); err == nil {
+				fallbackTexts += len(batch)
+			}
+		}
 		<-semaphore // Release.
-
-		if err != nil {
-			log.Error().Err(err).Int("batch", batchIdx+1).Msg("Batch translation failed")
-			continue
+		if err == nil {
+			breaker.RecordSuccess()
 		}
-
-		// Parse response.
-		parts := strings.Split(response, "|||")
+		return response, truncated, err
+	}
+	steps.fail = func(ctx context.Context, batch []string, err error) error {
+		translation.Logger(ctx).Error().Err(err).Msg("Batch translation failed")
+		for _, text := range batch {
+			unresolved.add(text, "batch failed: "+err.Error())
+		}
+		breaker.RecordFailure(err)
+		return breaker.Err()
+	}
+	steps.resolve = func(ctx context.Context, batch []string, response string) error {
+		mappings := make([][]interpolation.Mapping, len(batch))
 		for i, text := range batch {
-			var translated string
-			if i < len(parts) {
-				translated = strings.TrimSpace(parts[i])
-			} else {
-				log.Warn().Str("text", textutil.Truncate(text, 30)).Msg("Missing translation in batch response, using fallback")
-				// Fallback: try individual translation.
-				retrievalResult, _ := retriever.Retrieve(ctx, text, 3)
-				protectedText, mapping := interpolation.Protect(text)
-				userPrompt := promptBuilder.BuildUserPrompt(protectedText, retriever, retrievalResult)
-				individual, err := opusClient.Translate(ctx, systemPrompt, userPrompt)
-				if err != nil {
-					log.Error().Err(err).Str("text", textutil.Truncate(text, 30)).Msg("Individual translation failed")
-					continue
-				}
-				translated = interpolation.Restore(individual, mapping)
-				if err := translationCache.Set(ctx, text, translated); err != nil {
-					log.Warn().Err(err).Msg("Failed to cache translation")
-				}
-				continue
+			_, mappings[i] = protect(text)
+		}
+
+		// Parse response. Present segments are restored and cached, and only missing
+		// or rejected ones fall back to individual calls.
+		accept := func(i int, text, segment string) bool {
+			if segment == "" {
+				translation.Logger(ctx).Warn().Int("index", i).Str("text", textutil.Truncate(text, 30)).Msg("Missing translation in batch response, using fallback")
+				return false
 			}
 
 			// Restore interpolation variables.
-			translated = interpolation.Restore(translated, mappings[i])
+			translated := restore(text, segment, mappings[i])
+
+			if err := translation.ValidateBalance(text, translated); err != nil {
+				translation.Logger(ctx).Warn().Err(err).Str("text", textutil.Truncate(text, 30)).Msg("Unbalanced translation in batch response, retrying individually")
+				return false
+			}
+			if err := plausibility.Check(text, translated); err != nil {
+				translation.Logger(ctx).Warn().Err(err).Str("text", textutil.Truncate(text, 30)).Msg("Implausible translation in batch response, retrying individually")
+				return false
+			}
 
 			// Cache the result.
 			if err := translationCache.Set(ctx, text, translated); err != nil {
-				log.Warn().Err(err).Msg("Failed to cache translation")
+				translation.Logger(ctx).Warn().Err(err).Msg("Failed to cache translation")
 			}
+			return true
 		}
+		return resolveBatch(batch, response, accept, func(text string) error {
+			translateSingle(ctx, text)
+			return breaker.Err()
+		})
 	}
 
-	// Reconstruct files with translations.
-	inputAbs, _ := filepath.Abs(inputDir)
-	outputAbs, _ := filepath.Abs(outputDir)
+	if opts.shuffleSeed != 0 {
+		textsToTranslate = shuffleTexts(textsToTranslate, opts.shuffleSeed)
+		log.Info().Int64("seed", opts.shuffleSeed).Msg("Shuffled texts before batching")
+	}
+	groups := groupTexts(textsToTranslate, plan.glossaries, plan.registers)
 
-	for _, pr := range parseResults {
-		if pr.Err != nil || pr.Result == nil {
-			continue
+	if opts.previewPrompt {
+		var batches [][]string
+		for _, group := range groups {
+			batches = append(batches, worker.Batch(group, cfg.BatchSize)...)
+		}
+		batchNum, batch := previewBatch(batches, opts.previewText)
+		if batch == nil {
+			log.Info().Msg("Nothing to translate, no prompt to preview")
+			return nil
 		}
+		userPrompt, _ := buildBatchPrompt(batch)
+		writePromptPreview(os.Stderr, systemPrompt, userPrompt, batchNum, len(batch))
+		return nil
+	}
 
-		// Build translations map for this file.
-		fileTranslations := make(map[string]string)
-		for _, et := range pr.Result.Texts {
-			if translated, ok := translationCache.Get(ctx, et.Text); ok {
-				fileTranslations[et.Text] = translated
+	// Translations of batches completed by an interrupted run are restored from the
+	// checkpoint and cached again, in case their first cache write failed.
+	var cp *checkpoint
+	if opts.checkpointPath != "" {
+		var done map[string]string
+		cp, done, err = openCheckpoint(opts.checkpointPath, targetLang.Code, opts.resume)
+		if err != nil {
+			return err
+		}
+		defer cp.Close()
+		if len(done) > 0 {
+			var remaining []string
+			for _, text := range textsToTranslate {
+				translated, ok := done[text]
+				if !ok {
+					remaining = append(remaining, text)
+					continue
+				}
+				if err := translationCache.Set(ctx, text, translated); err != nil {
+					log.Warn().Err(err).Msg("Failed to cache checkpointed translation")
+				}
 			}
+			log.Info().
+				Str("path", opts.checkpointPath).
+				Int("restored", len(textsToTranslate)-len(remaining)).
+				Int("remaining", len(remaining)).
+				Msg("Resuming from checkpoint")
+			textsToTranslate = remaining
+			groups = groupTexts(textsToTranslate, plan.glossaries, plan.registers)
 		}
+	}
 
-		// Reconstruct the file.
-		entry := pr.Input
-		reconstructed, err := entry.Parser.Reconstruct(pr.Result, fileTranslations)
-		if err != nil {
-			log.Error().Err(err).Str("file", entry.Path).Msg("Reconstruct failed")
-			continue
+	// checkpointBatch records the translations of a completed batch. Texts that
+	// failed are left out, so a resumed run tries them again.
+	checkpointBatch := func(batchNum int, batch []string) {
+		if cp == nil {
+			return
+		}
+		translations := make(map[string]string, len(batch))
+		for _, text := range batch {
+			if translated, ok := translationCache.Get(ctx, text); ok {
+				translations[text] = translated
+			}
 		}
+		if err := cp.record(batchNum, translations); err != nil {
+			log.Warn().Err(err).Int("batch", batchNum).Msg("Failed to write checkpoint")
+		}
+	}
 
-		// Compute output path.
-		relPath, err := filepath.Rel(inputAbs, entry.Path)
-		if err != nil {
-			log.Error().Err(err).Msg("Compute relative path")
-			continue
+	// translateAsync submits batches as asynchronous batch jobs, waits for them and
+	// caches every translation that passes the same checks as a synchronous one.
+	// Texts of failed jobs, failed or short responses and rejected segments are left
+	// uncached for the synchronous pass.
+	translateAsync := func(batches [][]string) {
+		type jobBatch struct {
+			num      int
+			texts    []string
+			mappings [][]interpolation.Mapping
+		}
+		pending := make(map[string]jobBatch, len(batches))
+		var jobs [][]translation.BatchRequest
+		var job []translation.BatchRequest
+		jobBytes := 0
+		for i, batch := range batches {
+			userPrompt, mappings := buildBatchPrompt(batch)
+			key := translation.NewBatchID(i+1, batch)
+			pending[key] = jobBatch{num: i + 1, texts: batch, mappings: mappings}
+			size := len(systemPrompt) + len(userPrompt)
+			if len(job) > 0 && jobBytes+size > translation.MaxBatchJobBytes {
+				jobs = append(jobs, job)
+				job, jobBytes = nil, 0
+			}
+			job = append(job, translation.BatchRequest{Key: key, SystemPrompt: systemPrompt, UserPrompt: userPrompt})
+			jobBytes += size
+		}
+		if len(job) > 0 {
+			jobs = append(jobs, job)
 		}
-		outPath := filepath.Join(outputAbs, relPath)
 
-		// Create parent directories.
-		if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
-			log.Error().Err(err).Str("path", outPath).Msg("Create output directory")
-			continue
+		// Jobs run concurrently on the API side, so all are submitted before polling.
+		var names []string
+		for i, job := range jobs {
+			name, err := opusClient.SubmitBatch(ctx, fmt.Sprintf("rag-translator %d/%d", i+1, len(jobs)), job)
+			if err != nil {
+				log.Error().Err(err).Int("job", i+1).Int("requests", len(job)).Msg("Batch job submission failed, translating its texts synchronously")
+				continue
+			}
+			names = append(names, name)
 		}
 
-		// Write translated file.
-		if err := os.WriteFile(outPath, reconstructed, 0644); err != nil {
-			log.Error().Err(err).Str("path", outPath).Msg("Write output file")
-			continue
+		accepted, failedRequests := 0, 0
+		for _, name := range names {
+			results, err := opusClient.PollBatch(ctx, name, opts.asyncPoll)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				log.Error().Err(err).Str("job", name).Msg("Batch job failed, translating its texts synchronously")
+				continue
+			}
+			for key, result := range results {
+				b, ok := pending[key]
+				if !ok {
+					continue
+				}
+				if result.Err != nil || result.Truncated || translation.BatchSegmentCount(result.Text) < len(b.texts) {
+					log.Warn().Err(result.Err).Str("batch_id", key).Bool("truncated", result.Truncated).Msg("Batch job request incomplete, translating its texts synchronously")
+					failedRequests++
+					continue
+				}
+				segments := translation.SplitBatchResponse(result.Text, len(b.texts))
+				for i, text := range b.texts {
+					if segments[i] == "" {
+						continue
+					}
+					translated := restore(text, segments[i], b.mappings[i])
+					if translation.ValidateBalance(text, translated) != nil || plausibility.Check(text, translated) != nil {
+						continue
+					}
+					if err := translationCache.Set(ctx, text, translated); err != nil {
+						log.Warn().Err(err).Msg("Failed to cache translation")
+						continue
+					}
+					accepted++
+				}
+				checkpointBatch(b.num, b.texts)
+			}
 		}
 
 		log.Info().
-			Str("input", entry.Path).
-			Str("output", outPath).
-			Int("translations", len(fileTranslations)).
-			Msg("File translated")
+			Int("jobs", len(jobs)).
+			Int("requests", len(batches)).
+			Int("failed_requests", failedRequests).
+			Int("translated", accepted).
+			Int("texts", len(textsToTranslate)).
+			Msg("Batch jobs complete")
 	}
 
+	if opts.async && len(textsToTranslate) > 0 {
+		var batches [][]string
+		for _, group := range groups {
+			batches = append(batches, worker.Batch(group, cfg.BatchSize)...)
+		}
+		translateAsync(batches)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var remaining []string
+		for _, text := range textsToTranslate {
+			if _, cached := translationCache.Get(ctx, text); !cached {
+				remaining = append(remaining, text)
+			}
+		}
+		if len(remaining) > 0 {
+			log.Info().Int("texts", len(remaining)).Msg("Translating texts left by the batch jobs synchronously")
+		}
+		textsToTranslate = remaining
+		groups = groupTexts(textsToTranslate, plan.glossaries, plan.registers)
+	}
+
+	// Batches are cut as the run goes, so each one uses the size tuned so far.
+	translated, batchNum := 0, 0
+	translateBar := progress.New("Translating", len(textsToTranslate))
+	defer translateBar.Finish()
+	for _, group := range groups {
+		for start := 0; start < len(group); {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+
+			end := min(start+sizer.Size(), len(group))
+			batch := group[start:end]
+			start = end
+			batchNum++
+
+			batchID := translation.NewBatchID(batchNum, batch)
+			log.Info().
+				Int("batch", batchNum).
+				Str("batch_id", batchID).
+				Int("size", len(batch)).
+				Int("done", translated).
+				Int("total", len(textsToTranslate)).
+				Msg("Translating batch")
+
+			if err := translateSplitting(ctx, batchID, batch, steps); err != nil {
+				return err
+			}
+			checkpointBatch(batchNum, batch)
+			translated += len(batch)
+			translateBar.Add(len(batch))
+		}
+	}
+	translateBar.Finish()
+
+	joinSentences(ctx, plan.splits, translationCache)
+	applyVariants(ctx, plan.variants, translationCache)
+
+	// Reconstruct files with translations.
+	cov := writeOutputs(ctx, parseResults, translationCache, inputDir, outputDir, cfg.OutputSuffix, opts.bilingual)
+
+	failed := unresolved.remaining(func(text string) bool {
+		_, cached := translationCache.Get(ctx, text)
+		return cached
+	})
+	if len(failed) > 0 {
+		log.Warn().Int("texts", len(failed)).Msg("Some texts could not be translated")
+	}
+	if opts.unresolvedPath != "" {
+		if err := writeUnresolved(opts.unresolvedPath, failed, unresolved.reasons, parseResults, inputDir); err != nil {
+			return err
+		}
+	}
+
+	if fallbackClient != nil {
+		log.Info().Int("texts", fallbackTexts).Str("model", cfg.FallbackModel).Msg("Texts translated by the fallback model")
+	}
 	log.Info().
 		Int("files", len(entries)).
+		Int("untranslated", cov.untranslated()).
+		Float64("coverage", cov.Ratio).
 		Str("output", outputDir).
 		Msg("Translation pipeline complete")
 
+	return checkCoverage(cov, opts.coverageReport, opts.minCoverage)
+}
+
+// selectSeedQuerier returns the seed querier --seed-source names: db, graph, or
+// nil for none. The seed corpus holds translations into the default target
+// language only, so other targets get no seeds.
+func selectSeedQuerier(source string, target language.Language, dbSeeds, graphSeeds rag.SeedQuerier) rag.SeedQuerier {
+	if language.Base(target.Code) != language.Base(language.DefaultTarget) {
+		return nil
+	}
+	switch source {
+	case "db":
+		return dbSeeds
+	case "graph":
+		return graphSeeds
+	}
 	return nil
 }
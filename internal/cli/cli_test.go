@@ -0,0 +1,388 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"rag-translator/internal/graph"
+	"rag-translator/internal/language"
+	"rag-translator/internal/rag"
+	"rag-translator/internal/textutil"
+	"rag-translator/internal/translation"
+	"rag-translator/internal/worker"
+)
+
+// stubSeeds is a rag.SeedQuerier returning the seeds whose source occurs in the text.
+type stubSeeds map[string]string
+
+func (s stubSeeds) FindSeedTranslations(ctx context.Context, text string) (map[string]string, error) {
+	found := make(map[string]string)
+	for src, dst := range s {
+		if strings.Contains(text, src) {
+			found[src] = dst
+		}
+	}
+	return found, nil
+}
+
+// noEmbedder fails every query, leaving vector search out of the prompt.
+type noEmbedder struct{}
+
+func (noEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	return nil, errors.New("no embeddings")
+}
+
+func TestResolveBatch(t *testing.T) {
+	batch := []string{"一", "二", "三", "四"}
+	tests := []struct {
+		name     string
+		response string
+		reject   map[string]bool // segments accept turns down
+		cached   []string
+		fallback []string
+	}{
+		{"complete", "1 ||| 2 ||| 3 ||| 4", nil, []string{"一", "二", "三", "四"}, nil},
+		{"missing in the middle", "1 |||  ||| 3 ||| 4", nil, []string{"一", "三", "四"}, []string{"二"}},
+		{"missing first", " ||| 2 ||| 3 ||| 4", nil, []string{"二", "三", "四"}, []string{"一"}},
+		{"missing tail", "1 ||| 2", nil, []string{"一", "二"}, []string{"三", "四"}},
+		{"rejected segment", "1 ||| 2 ||| 3 ||| 4", map[string]bool{"2": true}, []string{"一", "三", "四"}, []string{"二"}},
+		{"empty response", "", nil, nil, []string{"一", "二", "三", "四"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cached, fallback []string
+			accept := func(i int, text, segment string) bool {
+				if segment == "" || tt.reject[segment] {
+					return false
+				}
+				cached = append(cached, text)
+				return true
+			}
+			err := resolveBatch(batch, tt.response, accept, func(text string) error {
+				fallback = append(fallback, text)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cached, tt.cached) {
+				t.Errorf("cached %q, want %q", cached, tt.cached)
+			}
+			if !reflect.DeepEqual(fallback, tt.fallback) {
+				t.Errorf("fell back for %q, want %q", fallback, tt.fallback)
+			}
+		})
+	}
+}
+
+func TestResolveBatchStopsOnFallbackError(t *testing.T) {
+	tripped := errors.New("tripped")
+	var accepted []int
+	err := resolveBatch([]string{"一", "二", "三"}, "1 |||  ||| 3", func(i int, text, segment string) bool {
+		accepted = append(accepted, i)
+		return segment != ""
+	}, func(string) error { return tripped })
+	if !errors.Is(err, tripped) {
+		t.Errorf("err = %v, want %v", err, tripped)
+	}
+	if !reflect.DeepEqual(accepted, []int{0, 1}) {
+		t.Errorf("accept called for %v, want [0 1]", accepted)
+	}
+}
+
+func TestSeedSourceReachesPrompt(t *testing.T) {
+	dbSeeds := stubSeeds{"张无忌": "Trương Vô Kỵ (db)"}
+	graphSeeds := stubSeeds{"张无忌": "Trương Vô Kỵ (graph)"}
+	text := "张无忌加入了明教"
+	tests := []struct {
+		name   string
+		source string
+		target string
+		want   string // seed line expected in the prompts, empty for none
+	}{
+		{"db", "db", "vi-VN", "• 张无忌 → Trương Vô Kỵ (db)"},
+		{"graph", "graph", "vi-VN", "• 张无忌 → Trương Vô Kỵ (graph)"},
+		{"none", "none", "vi-VN", ""},
+		{"target without seeds", "db", "th-TH", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := language.Lookup(tt.target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := rag.NewRetriever(rag.NewMemoryVectorStore(), noEmbedder{}, graph.NewStaticGraph(nil, nil))
+			if sq := selectSeedQuerier(tt.source, target, dbSeeds, graphSeeds); sq != nil {
+				r.SetSeedQuerier(sq)
+			}
+			result, err := r.Retrieve(context.Background(), text, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pb := translation.NewPromptBuilder()
+			prompts := map[string]string{
+				"single": pb.BuildUserPrompt(text, nil, r, result),
+				"batch":  pb.BuildBatchUserPrompt([]string{text}, nil, nil, rag.MergeResults([]*rag.RetrievalResult{result}, 5)),
+			}
+			for kind, prompt := range prompts {
+				hasSeeds := strings.Contains(prompt, "Verified Seed Translations")
+				if tt.want == "" && hasSeeds {
+					t.Errorf("%s prompt has seeds:\n%s", kind, prompt)
+				}
+				if tt.want != "" && !strings.Contains(prompt, tt.want) {
+					t.Errorf("%s prompt missing %q:\n%s", kind, tt.want, prompt)
+				}
+			}
+		})
+	}
+}
+
+// roundTripFunc adapts a function to http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// numberedText matches a text line of a batch prompt.
+var numberedText = regexp.MustCompile(`(?m)^\[\d+\] (.*)$`)
+
+// maxTokensClient answers batch prompts with "T:" and each text, and cuts a
+// prompt of more than limit texts off after limit segments with finish reason
+// MAX_TOKENS, as the API does at the output token limit.
+func maxTokensClient(t *testing.T, limit int) *translation.OpusClient {
+	t.Helper()
+	oc := translation.NewOpusClient("key", "model", 0)
+	oc.SetTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		var req struct {
+			Contents []struct {
+				Parts []struct {
+					Text string `json:"text"`
+				} `json:"parts"`
+			} `json:"contents"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		var segments []string
+		for _, m := range numberedText.FindAllStringSubmatch(req.Contents[0].Parts[0].Text, -1) {
+			segments = append(segments, "T:"+m[1])
+		}
+		finish := "STOP"
+		if len(segments) > limit {
+			segments, finish = segments[:limit], "MAX_TOKENS"
+		}
+		body, _ := json.Marshal(map[string]any{"candidates": []any{map[string]any{
+			"content":      map[string]any{"parts": []any{map[string]any{"text": strings.Join(segments, " ||| ")}}},
+			"finishReason": finish,
+		}}})
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(string(body)))}, nil
+	}))
+	return oc
+}
+
+func TestTranslateSplittingTruncated(t *testing.T) {
+	tests := []struct {
+		name     string
+		batch    []string
+		limit    int
+		resolved []string // batch ID: response, in the order resolved
+		size     int      // sizer size afterwards, starting at 5 within [1, 8]
+	}{
+		{
+			name:     "fits",
+			batch:    []string{"一", "二", "三"},
+			limit:    3,
+			resolved: []string{"b: T:一 ||| T:二 ||| T:三"},
+			size:     6,
+		},
+		{
+			name:  "halved until it fits",
+			batch: []string{"一", "二", "三", "四", "五"},
+			limit: 2,
+			resolved: []string{
+				"b.1: T:一 ||| T:二",
+				"b.2.1: T:三",
+				"b.2.2: T:四 ||| T:五",
+			},
+			size: 3, // 5 → 2 → 3 → 1 → 2 → 3
+		},
+		{
+			name:  "down to single texts",
+			batch: []string{"一", "二", "三"},
+			limit: 1,
+			resolved: []string{
+				"b.1: T:一",
+				"b.2.1: T:二",
+				"b.2.2: T:三",
+			},
+			size: 3, // 5 → 2 → 3 → 1 → 2 → 3
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oc := maxTokensClient(t, tt.limit)
+			var resolved []string
+			steps := batchSteps{
+				sizer: translation.NewBatchSizer(5, 1, 8),
+				send: func(ctx context.Context, batch []string) (string, bool, error) {
+					var prompt strings.Builder
+					for i, text := range batch {
+						fmt.Fprintf(&prompt, "[%d] %s\n", i+1, text)
+					}
+					return oc.TranslateBatchDetailed(ctx, "system", prompt.String(), len(batch))
+				},
+				fail: func(ctx context.Context, batch []string, err error) error {
+					t.Fatalf("batch %v failed: %v", batch, err)
+					return err
+				},
+				resolve: func(ctx context.Context, batch []string, response string) error {
+					resolved = append(resolved, translation.BatchID(ctx)+": "+response)
+					return nil
+				},
+			}
+			if err := translateSplitting(context.Background(), "b", tt.batch, steps); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(resolved, tt.resolved) {
+				t.Errorf("resolved %q, want %q", resolved, tt.resolved)
+			}
+			if got := steps.sizer.Size(); got != tt.size {
+				t.Errorf("sizer size = %d, want %d", got, tt.size)
+			}
+		})
+	}
+}
+
+func TestShuffledBatchesMapBack(t *testing.T) {
+	texts := []string{"一", "二", "三", "四", "五", "六", "七", "八", "九", "十"}
+	original := slices.Clone(texts)
+
+	shuffled := shuffleTexts(texts, 42)
+	if !slices.Equal(texts, original) {
+		t.Fatalf("shuffleTexts modified its input: %q", texts)
+	}
+	if slices.Equal(shuffled, texts) {
+		t.Errorf("seed 42 left the order unchanged")
+	}
+	if again := shuffleTexts(texts, 42); !slices.Equal(again, shuffled) {
+		t.Errorf("seed 42 gave %q, then %q", shuffled, again)
+	}
+	sorted := slices.Clone(shuffled)
+	slices.Sort(sorted)
+	want := slices.Clone(texts)
+	slices.Sort(want)
+	if !slices.Equal(sorted, want) {
+		t.Fatalf("shuffled %q is not a permutation of %q", shuffled, texts)
+	}
+
+	// Translate the shuffled batches and cache each segment under its source text.
+	cached := make(map[string]string)
+	for _, batch := range worker.Batch(shuffled, 3) {
+		segments := make([]string, len(batch))
+		for i, text := range batch {
+			segments[i] = "T:" + text
+		}
+		accept := func(i int, text, segment string) bool {
+			cached[text] = segment
+			return true
+		}
+		fallback := func(text string) error {
+			t.Errorf("fallback for %q", text)
+			return nil
+		}
+		if err := resolveBatch(batch, strings.Join(segments, " ||| "), accept, fallback); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, text := range texts {
+		if got := cached[text]; got != "T:"+text {
+			t.Errorf("%q translated as %q, want %q", text, got, "T:"+text)
+		}
+	}
+}
+
+// interruptedEmbedder embeds texts in batches and fails every batch from the
+// failAt-th on, as an ingest stopped by an outage would; failAt 0 never fails.
+type interruptedEmbedder struct {
+	failAt   int
+	embedded []string
+}
+
+func (e *interruptedEmbedder) EmbedEach(ctx context.Context, texts []string, batchSize int, fn func(start int, embeddings [][]float32) error, onErr func(err error) error) error {
+	for start, n := 0, 1; start < len(texts); start, n = start+batchSize, n+1 {
+		batch := texts[start:min(start+batchSize, len(texts))]
+		if e.failAt > 0 && n >= e.failAt {
+			if err := onErr(errors.New("embedding service unavailable")); err != nil {
+				return err
+			}
+			continue
+		}
+		embeddings := make([][]float32, len(batch))
+		for i, text := range batch {
+			embeddings[i] = []float32{float32(len(text)), 1}
+		}
+		e.embedded = append(e.embedded, batch...)
+		if err := fn(start, embeddings); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestStoreEmbeddingsResume(t *testing.T) {
+	texts := []string{"一", "二", "三", "四", "五", "六", "七"}
+	records := make([]rag.EmbeddingRecord, len(texts))
+	for i, text := range texts {
+		records[i] = rag.EmbeddingRecord{Hash: textutil.CanonicalHash(text), Source: text, FilePath: "a.lua"}
+	}
+	store := rag.NewMemoryVectorStore()
+	ctx := context.Background()
+	failFast := func(what string, err error) error { return fmt.Errorf("%s: %w", what, err) }
+
+	// The first run stops at its third batch of two.
+	first := &interruptedEmbedder{failAt: 3}
+	skipped, stored, err := storeEmbeddings(ctx, records, store, first, 2, failFast)
+	if err == nil {
+		t.Fatal("interrupted run succeeded")
+	}
+	if skipped != 0 || stored != 4 {
+		t.Errorf("first run skipped %d and stored %d, want 0 and 4", skipped, stored)
+	}
+
+	// The second run embeds only the texts the first did not store.
+	second := &interruptedEmbedder{}
+	skipped, stored, err = storeEmbeddings(ctx, records, store, second, 2, failFast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if skipped != 4 || stored != 3 {
+		t.Errorf("resumed run skipped %d and stored %d, want 4 and 3", skipped, stored)
+	}
+	if want := []string{"五", "六", "七"}; !slices.Equal(second.embedded, want) {
+		t.Errorf("resumed run embedded %q, want %q", second.embedded, want)
+	}
+
+	hashes := make([]string, len(records))
+	for i, r := range records {
+		hashes[i] = r.Hash
+	}
+	existing, _ := store.ExistingHashes(ctx, hashes)
+	if len(existing) != len(texts) {
+		t.Errorf("%d of %d texts stored after resuming", len(existing), len(texts))
+	}
+
+	// A third run has nothing left to do.
+	third := &interruptedEmbedder{}
+	if skipped, stored, err = storeEmbeddings(ctx, records, store, third, 2, failFast); err != nil || skipped != len(texts) || stored != 0 || len(third.embedded) != 0 {
+		t.Errorf("complete run skipped %d, stored %d, embedded %q, err %v", skipped, stored, third.embedded, err)
+	}
+}
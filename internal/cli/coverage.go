@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"rag-translator/internal/parser"
+
+	"github.com/rs/zerolog/log"
+)
+
+// fileCoverage counts the extracted texts of one output file by what
+// reconstruction did with them.
+type fileCoverage struct {
+	File              string   `json:"file"`
+	Extracted         int      `json:"extracted"`
+	Applied           int      `json:"applied"`
+	SkippedMissing    int      `json:"skipped_missing"`
+	SkippedOutOfRange int      `json:"skipped_out_of_range"`
+	Skipped           []string `json:"skipped,omitempty"`
+}
+
+// coverage summarizes how much of a run's source text was actually translated.
+type coverage struct {
+	Files     []fileCoverage `json:"files"`
+	Extracted int            `json:"extracted"`
+	Applied   int            `json:"applied"`
+	Ratio     float64        `json:"ratio"`
+}
+
+// add records the reconstruct report of one file and updates the totals.
+func (c *coverage) add(file string, report parser.ReconstructReport) {
+	fc := fileCoverage{
+		File:              file,
+		Extracted:         report.Applied + report.SkippedMissing + report.SkippedOutOfRange,
+		Applied:           report.Applied,
+		SkippedMissing:    report.SkippedMissing,
+		SkippedOutOfRange: report.SkippedOutOfRange,
+		Skipped:           report.Skipped,
+	}
+	c.Files = append(c.Files, fc)
+	c.Extracted += fc.Extracted
+	c.Applied += fc.Applied
+	c.Ratio = 1
+	if c.Extracted > 0 {
+		c.Ratio = float64(c.Applied) / float64(c.Extracted)
+	}
+}
+
+// untranslated returns the number of extracted texts left in the source language.
+func (c *coverage) untranslated() int {
+	return c.Extracted - c.Applied
+}
+
+// writeCoverageReport writes c as JSON when path ends in .json, otherwise as TSV
+// with one row per file followed by a total row. Only the JSON form lists the
+// skipped sources.
+func writeCoverageReport(path string, c *coverage) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create coverage report: %w", err)
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(c); err != nil {
+			return fmt.Errorf("encode coverage report: %w", err)
+		}
+	} else {
+		fmt.Fprintln(f, "file\textracted\tapplied\tskipped_missing\tskipped_out_of_range\tratio")
+		missing, outOfRange := 0, 0
+		for _, fc := range c.Files {
+			ratio := 1.0
+			if fc.Extracted > 0 {
+				ratio = float64(fc.Applied) / float64(fc.Extracted)
+			}
+			fmt.Fprintf(f, "%s\t%d\t%d\t%d\t%d\t%.4f\n", fc.File, fc.Extracted, fc.Applied, fc.SkippedMissing, fc.SkippedOutOfRange, ratio)
+			missing += fc.SkippedMissing
+			outOfRange += fc.SkippedOutOfRange
+		}
+		fmt.Fprintf(f, "TOTAL\t%d\t%d\t%d\t%d\t%.4f\n", c.Extracted, c.Applied, missing, outOfRange, c.Ratio)
+	}
+
+	log.Info().Str("path", path).Int("files", len(c.Files)).Float64("coverage", c.Ratio).Msg("Wrote coverage report")
+	return f.Close()
+}
+
+// checkCoverage writes the optional coverage report and fails when coverage is
+// below minCoverage. A minCoverage of 0 never fails.
+func checkCoverage(c *coverage, reportPath string, minCoverage float64) error {
+	if reportPath != "" {
+		if err := writeCoverageReport(reportPath, c); err != nil {
+			return err
+		}
+	}
+	if c.Ratio < minCoverage {
+		return fmt.Errorf("translation coverage %.2f%% is below the required %.2f%% (%d of %d texts untranslated)",
+			c.Ratio*100, minCoverage*100, c.untranslated(), c.Extracted)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"rag-translator/internal/config"
+	"rag-translator/internal/parser"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func diffCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff <old-output-dir> <new-output-dir>",
+		Short: "Report strings whose translation differs between two translate runs",
+		Long: `Pairs files in two output trees by their path relative to the original input tree,
+aligns every extracted source string using the file parsers, and prints each string
+whose translation changed. The original input tree is given with --source.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sourceDir, _ := cmd.Flags().GetString("source")
+			return runDiff(sourceDir, args[0], args[1])
+		},
+	}
+
+	cmd.Flags().String("source", "", "Original input directory both outputs were translated from")
+	_ = cmd.MarkFlagRequired("source")
+
+	return cmd
+}
+
+// translationChange is one source string whose translation differs between runs.
+type translationChange struct {
+	location string
+	source   string
+	old      string
+	new      string
+}
+
+// runDiff handles the `diff` command.
+func runDiff(sourceDir, oldDir, newDir string) error {
+	cfg, err := config.Load(envFile)
+	if err != nil {
+		return err
+	}
+	if _, _, err := applyLanguages(cfg); err != nil {
+		return err
+	}
+
+	w, err := newWalker(cfg)
+	if err != nil {
+		return err
+	}
+	entries, err := w.Walk(sourceDir)
+	if err != nil {
+		return fmt.Errorf("walk source directory: %w", err)
+	}
+
+	sourceAbs, _ := filepath.Abs(sourceDir)
+
+	var changes []translationChange
+	compared := 0
+
+	for _, entry := range entries {
+		extractor, ok := entry.Parser.(parser.TranslationExtractor)
+		if !ok {
+			continue
+		}
+
+		relPath, err := filepath.Rel(sourceAbs, entry.Path)
+		if err != nil {
+			log.Error().Err(err).Msg("Compute relative path")
+			continue
+		}
+
+		oldLines, oldErr := readLines(filepath.Join(oldDir, relPath))
+		newLines, newErr := readLines(filepath.Join(newDir, relPath))
+		if oldErr != nil || newErr != nil {
+			log.Warn().Str("file", relPath).Bool("in_old", oldErr == nil).Bool("in_new", newErr == nil).Msg("File missing from an output tree, skipping")
+			continue
+		}
+
+		result, err := entry.Parser.Parse(entry.Path)
+		if err != nil {
+			log.Error().Err(err).Str("file", entry.Path).Msg("Parse failed")
+			continue
+		}
+
+		oldValues := extractor.ExtractTranslations(result, oldLines)
+		newValues := extractor.ExtractTranslations(result, newLines)
+		compared++
+
+		for i, et := range result.Texts {
+			if oldValues[i] == newValues[i] {
+				continue
+			}
+			changes = append(changes, translationChange{
+				location: fmt.Sprintf("%s:%d", relPath, et.Line),
+				source:   et.Text,
+				old:      oldValues[i],
+				new:      newValues[i],
+			})
+		}
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LOCATION\tSOURCE\tOLD\tNEW")
+	for _, c := range changes {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", c.location, oneLine(c.source), oneLine(c.old), oneLine(c.new))
+	}
+	tw.Flush()
+
+	log.Info().
+		Int("files", compared).
+		Int("changed", len(changes)).
+		Msg("Translation diff complete")
+
+	return nil
+}
+
+// readLines reads a file as lines without the trailing newline.
+func readLines(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"), nil
+}
+
+// oneLine escapes tabs and newlines so a value fits in a single table cell.
+func oneLine(s string) string {
+	return strings.NewReplacer("\t", `\t`, "\n", `\n`, "\r", `\r`).Replace(s)
+}
@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"rag-translator/internal/cache"
+	"rag-translator/internal/config"
+	"rag-translator/internal/interpolation"
+	"rag-translator/internal/language"
+	"rag-translator/internal/seed"
+	"rag-translator/internal/translation"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func estimateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "estimate <input-dir>",
+		Short: "Estimate translation token usage and cost without calling any API",
+		Long: `Parses and deduplicates the input tree exactly like translate, skips texts already
+covered by the translation cache or the seed corpus, and estimates input/output tokens
+with a CJK-aware heuristic. Prices come from TRANSLATION_INPUT_PRICE_PER_MTOK and
+TRANSLATION_OUTPUT_PRICE_PER_MTOK.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runEstimate(args[0])
+		},
+	}
+}
+
+// typeEstimate accumulates estimated usage for one file type.
+type typeEstimate struct {
+	texts        int
+	inputTokens  int
+	outputTokens int
+}
+
+// runEstimate handles the `estimate` command.
+func runEstimate(inputDir string) error {
+	ctx, cancel := setupContext()
+	defer cancel()
+
+	cfg, err := config.Load(envFile)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	sourceLang, targetLang, err := applyLanguages(cfg)
+	if err != nil {
+		return err
+	}
+
+	pgPool, err := initPostgres(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pgPool.Close()
+
+	translationCache := cache.NewTranslationCache(pgPool)
+	translationCache.SetTargetLanguage(targetLang.Code)
+	if err := translationCache.Preload(ctx); err != nil {
+		log.Warn().Err(err).Msg("Failed to preload cache")
+	}
+
+	// The seed corpus holds translations into the default target language only.
+	seedMap := make(map[string]string)
+	if language.Base(targetLang.Code) == language.Base(language.DefaultTarget) {
+		seedMap, err = seed.NewSeedStore(pgPool).BuildTranslationMap(ctx)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to load seed corpus, estimating without seed hits")
+			seedMap = make(map[string]string)
+		}
+	}
+
+	cacheHits, seedHits := 0, 0
+	plan, err := planTranslation(ctx, cfg, inputDir, "", func(text string) bool {
+		if _, cached := translationCache.Get(ctx, text); cached {
+			cacheHits++
+			return true
+		}
+		if _, ok := seedMap[text]; ok {
+			seedHits++
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return err
+	}
+
+	pending := make(map[string]struct{}, len(plan.toTranslate))
+	for _, text := range plan.toTranslate {
+		pending[text] = struct{}{}
+	}
+
+	// Attribute each pending text to the file type where it first appears.
+	byType := make(map[string]*typeEstimate)
+	for _, pr := range plan.parseResults {
+		if pr.Err != nil || pr.Result == nil {
+			continue
+		}
+		for _, et := range pr.Result.Texts {
+			if _, ok := pending[et.Text]; !ok {
+				continue
+			}
+			delete(pending, et.Text)
+
+			est, ok := byType[pr.Result.FileType]
+			if !ok {
+				est = &typeEstimate{}
+				byType[pr.Result.FileType] = est
+			}
+			protected, _ := interpolation.Protect(et.Text)
+			est.texts++
+			est.inputTokens += translation.EstimateTokens(protected)
+			est.outputTokens += translation.EstimateOutputTokens(protected)
+		}
+	}
+
+	// Spread the fixed per-batch prompt overhead across texts.
+	batchSize := max(cfg.BatchSize, 1)
+	promptBuilder := translation.NewPromptBuilder()
+	promptBuilder.SetLanguages(sourceLang, targetLang)
+	overheadPerText := float64(promptBuilder.EstimateBatchOverhead()) / float64(batchSize)
+
+	types := make([]string, 0, len(byType))
+	for t := range byType {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tTEXTS\tINPUT TOKENS\tOUTPUT TOKENS\tCOST (USD)")
+
+	var total typeEstimate
+	var totalCost float64
+	for _, t := range types {
+		est := byType[t]
+		est.inputTokens += int(math.Ceil(overheadPerText * float64(est.texts)))
+		cost := tokenCost(est.inputTokens, cfg.InputPricePerMTok) + tokenCost(est.outputTokens, cfg.OutputPricePerMTok)
+
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.4f\n", t, est.texts, est.inputTokens, est.outputTokens, cost)
+
+		total.texts += est.texts
+		total.inputTokens += est.inputTokens
+		total.outputTokens += est.outputTokens
+		totalCost += cost
+	}
+	fmt.Fprintf(tw, "TOTAL\t%d\t%d\t%d\t%.4f\n", total.texts, total.inputTokens, total.outputTokens, totalCost)
+	tw.Flush()
+
+	log.Info().
+		Str("model", cfg.TranslationModel).
+		Int("unique_texts", plan.uniqueTexts).
+		Int("cache_hits", cacheHits).
+		Int("seed_hits", seedHits).
+		Int("to_translate", total.texts).
+		Float64("estimated_cost_usd", totalCost).
+		Msg("Cost estimate complete")
+
+	return nil
+}
+
+// tokenCost converts a token count into USD given a per-million-token price.
+func tokenCost(tokens int, pricePerMTok float64) float64 {
+	return float64(tokens) / 1_000_000 * pricePerMTok
+}
@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"rag-translator/internal/config"
+	"rag-translator/internal/graph"
+	"rag-translator/internal/translation"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func glossaryReportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "glossary-report <input-dir>",
+		Short: "Report glossary term usage and frequent source substrings missing from the glossary",
+		Long: `Parses the input tree like translate and writes a TSV report with one row per
+knowledge graph term and how often it occurs in the extracted texts (unused terms
+included), followed by candidate additions: source-script substrings of --min-n to
+--max-n characters occurring at least --min-count times that match no term.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			outputPath, _ := cmd.Flags().GetString("output")
+			minN, _ := cmd.Flags().GetInt("min-n")
+			maxN, _ := cmd.Flags().GetInt("max-n")
+			minCount, _ := cmd.Flags().GetInt("min-count")
+			limit, _ := cmd.Flags().GetInt("limit")
+			if minN < 1 || maxN < minN {
+				return fmt.Errorf("--min-n must be at least 1 and not above --max-n")
+			}
+			if minCount < 1 {
+				return fmt.Errorf("--min-count must be at least 1")
+			}
+			return runGlossaryReport(args[0], outputPath, minN, maxN, minCount, limit)
+		},
+	}
+
+	cmd.Flags().String("output", "", "Write the TSV report to this path instead of stdout")
+	cmd.Flags().Int("min-n", 2, "Shortest candidate substring, in characters")
+	cmd.Flags().Int("max-n", 4, "Longest candidate substring, in characters")
+	cmd.Flags().Int("min-count", 5, "Minimum occurrences of a candidate substring")
+	cmd.Flags().Int("limit", 200, "Maximum number of candidates to list; 0 lists all")
+
+	return cmd
+}
+
+// runGlossaryReport handles the `glossary-report` command.
+func runGlossaryReport(inputDir, outputPath string, minN, maxN, minCount, limit int) error {
+	ctx, cancel := setupContext()
+	defer cancel()
+
+	cfg, err := config.Load(envFile)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	_, targetLang, err := applyLanguages(cfg)
+	if err != nil {
+		return err
+	}
+
+	pgPool, neo4jDriver, err := initDependencies(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pgPool.Close()
+	defer neo4jDriver.Close(ctx)
+
+	graphQuerier := graph.NewGraphQuerier(neo4jDriver)
+	graphQuerier.SetTargetLanguage(targetLang.Code)
+	terminology, err := graphQuerier.GetAllTerminology(ctx)
+	if err != nil {
+		return fmt.Errorf("load terminology: %w", err)
+	}
+	terms := make([]string, 0, len(terminology))
+	for term := range terminology {
+		terms = append(terms, term)
+	}
+
+	plan, err := planTranslation(ctx, cfg, inputDir, "", func(string) bool { return true })
+	if err != nil {
+		return err
+	}
+	texts := make(map[string]int)
+	for _, pr := range plan.parseResults {
+		if pr.Err != nil || pr.Result == nil {
+			continue
+		}
+		for _, et := range pr.Result.Texts {
+			texts[et.Text]++
+		}
+	}
+
+	frequencies := translation.TermFrequencies(terms, texts)
+	candidates := translation.CandidateTerms(terms, texts, minN, maxN, minCount)
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+
+	var w io.Writer = os.Stdout
+	if outputPath != "" {
+		f, err := os.Create(outputPath)
+		if err != nil {
+			return fmt.Errorf("create glossary report: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	fmt.Fprintln(w, "kind\ttext\tcount\ttranslation")
+	unused := 0
+	for _, tc := range frequencies {
+		if tc.Count == 0 {
+			unused++
+		}
+		fmt.Fprintf(w, "term\t%s\t%d\t%s\n", escapeCell(tc.Text), tc.Count, escapeCell(terminology[tc.Text]))
+	}
+	for _, tc := range candidates {
+		fmt.Fprintf(w, "candidate\t%s\t%d\t\n", escapeCell(tc.Text), tc.Count)
+	}
+
+	log.Info().
+		Int("unique_texts", len(texts)).
+		Int("terms", len(terms)).
+		Int("unused_terms", unused).
+		Int("candidates", len(candidates)).
+		Msg("Glossary report complete")
+
+	return nil
+}
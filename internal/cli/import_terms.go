@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"fmt"
+
+	"rag-translator/internal/config"
+	"rag-translator/internal/filewalker"
+	"rag-translator/internal/graph"
+	"rag-translator/internal/language"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func importTermsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import-terms <glossary.tsv>",
+		Short: "Load the knowledge graph terminology of a target language from a glossary file",
+		Long: `Reads "source<TAB>rendering" lines (blank lines and lines starting with # are
+skipped) and stores each rendering on its Term node for the target language, --target
+or TARGET_LANG. Terms not yet in the graph are created. The built-in terminology is
+Vietnamese only, so other targets need their glossary imported before translate can
+use it. Safe to run repeatedly; a later import replaces earlier renderings.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			target, _ := cmd.Flags().GetString("target")
+			return runImportTerms(args[0], target)
+		},
+	}
+
+	cmd.Flags().String("target", "", "Target language code of the renderings (default TARGET_LANG)")
+
+	return cmd
+}
+
+// runImportTerms handles the `import-terms` command.
+func runImportTerms(path, target string) error {
+	ctx, cancel := setupContext()
+	defer cancel()
+
+	cfg, err := config.Load(envFile)
+	if err != nil {
+		return err
+	}
+	if target != "" {
+		cfg.TargetLang = target
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	_, targetLang, err := applyLanguages(cfg)
+	if err != nil {
+		return err
+	}
+
+	renderings, err := filewalker.LoadGlossary(path)
+	if err != nil {
+		return err
+	}
+
+	pgPool, neo4jDriver, err := initDependencies(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pgPool.Close()
+	defer neo4jDriver.Close(ctx)
+
+	graphBuilder := graph.NewGraphBuilder(neo4jDriver)
+	if err := graphBuilder.EnsureSchema(ctx); err != nil {
+		return fmt.Errorf("ensure graph schema: %w", err)
+	}
+	if language.Base(targetLang.Code) == language.Base(language.DefaultTarget) {
+		log.Warn().Msg("Importing over the built-in Vietnamese terminology; rebuild-graph restores it")
+	}
+	imported, err := graphBuilder.ImportRenderings(ctx, targetLang.Code, renderings)
+	if err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("target", targetLang.Code).
+		Str("property", graph.TermProperty(targetLang.Code)).
+		Int("terms", imported).
+		Msg("Imported terminology")
+	return nil
+}
@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ingestErrors applies the error policy of an ingest run. With failFast the first
+// error stops the run; otherwise errors are collected so the run can finish and
+// report them all at the end.
+type ingestErrors struct {
+	failFast bool
+	errs     []error
+}
+
+// add records err, which occurred in phase. It returns the error to abort with
+// under --fail-fast, and nil when the run should go on.
+func (e *ingestErrors) add(phase string, err error) error {
+	err = fmt.Errorf("%s: %w", phase, err)
+	if e.failFast {
+		return err
+	}
+	log.Warn().Err(err).Msg("Ingest error, continuing")
+	e.errs = append(e.errs, err)
+	return nil
+}
+
+// err returns every collected error joined, or nil when there were none.
+func (e *ingestErrors) err() error {
+	if len(e.errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("ingest finished with %d errors: %w", len(e.errs), errors.Join(e.errs...))
+}
@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"rag-translator/internal/cache"
+	"rag-translator/internal/config"
+	"rag-translator/internal/graph"
+	"rag-translator/internal/translation"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// nameCategories are the graph term categories treated as proper nouns.
+var nameCategories = []string{"character", "location"}
+
+func lintCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Check cached translations for inconsistently rendered proper nouns",
+		Long: `Groups cached translations by the proper nouns their sources contain (character and
+location terms from the knowledge graph, plus an optional --names list) and reports every
+name rendered more than one way, or not rendered with any known form. With --fix, texts
+using a minority rendering are rewritten in the cache to the most frequent one.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namesPath, _ := cmd.Flags().GetString("names")
+			fix, _ := cmd.Flags().GetBool("fix")
+			return runLint(namesPath, fix)
+		},
+	}
+
+	cmd.Flags().String("names", "", "File of proper nouns, one per line, optionally followed by tab-separated known renderings")
+	cmd.Flags().Bool("fix", false, "Rewrite minority renderings in the cache to the most frequent one")
+
+	return cmd
+}
+
+// runLint handles the `lint` command.
+func runLint(namesPath string, fix bool) error {
+	ctx, cancel := setupContext()
+	defer cancel()
+
+	cfg, err := config.Load(envFile)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	_, targetLang, err := applyLanguages(cfg)
+	if err != nil {
+		return err
+	}
+
+	pgPool, neo4jDriver, err := initDependencies(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pgPool.Close()
+	defer neo4jDriver.Close(ctx)
+
+	graphQuerier := graph.NewGraphQuerier(neo4jDriver)
+	graphQuerier.SetTargetLanguage(targetLang.Code)
+	terms, err := graphQuerier.GetTermsByCategory(ctx, nameCategories)
+	if err != nil {
+		return fmt.Errorf("load name terms: %w", err)
+	}
+	names := make(map[string][]string, len(terms))
+	for zh, rendering := range terms {
+		names[zh] = []string{rendering}
+	}
+	if namesPath != "" {
+		listed, err := translation.LoadNameList(namesPath)
+		if err != nil {
+			return err
+		}
+		for name, renderings := range listed {
+			names[name] = append(names[name], renderings...)
+		}
+	}
+
+	translationCache := cache.NewTranslationCache(pgPool)
+	translationCache.SetTargetLanguage(targetLang.Code)
+	cached, err := translationCache.Entries(ctx)
+	if err != nil {
+		return err
+	}
+
+	issues := translation.CheckNameConsistency(names, cached)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tRENDERING\tTEXTS")
+	for _, issue := range issues {
+		preferred := issue.Preferred()
+		renderings := make([]string, 0, len(issue.Renderings))
+		for rendering := range issue.Renderings {
+			renderings = append(renderings, rendering)
+		}
+		sort.Strings(renderings)
+		for _, rendering := range renderings {
+			mark := ""
+			if rendering == preferred {
+				mark = " (preferred)"
+			}
+			fmt.Fprintf(tw, "%s\t%s%s\t%d\n", issue.Name, rendering, mark, len(issue.Renderings[rendering]))
+		}
+		if len(issue.Unmatched) > 0 {
+			fmt.Fprintf(tw, "%s\t(none of the known renderings)\t%d\n", issue.Name, len(issue.Unmatched))
+		}
+	}
+	tw.Flush()
+
+	unresolved := len(issues)
+	if fix && len(issues) > 0 {
+		fixed := translation.NormalizeNames(issues, cached)
+		if err := translationCache.SetBatch(ctx, fixed); err != nil {
+			return fmt.Errorf("write normalized translations: %w", err)
+		}
+		unresolved = 0
+		for _, issue := range issues {
+			if len(issue.Unmatched) > 0 {
+				unresolved++
+			}
+		}
+		log.Info().Int("texts", len(fixed)).Msg("Normalized proper noun renderings")
+	}
+
+	log.Info().
+		Int("names", len(names)).
+		Int("cached_texts", len(cached)).
+		Int("inconsistent", len(issues)).
+		Int("unresolved", unresolved).
+		Msg("Lint complete")
+
+	if unresolved > 0 {
+		return fmt.Errorf("%d proper nouns are rendered inconsistently", unresolved)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"fmt"
+
+	"rag-translator/internal/config"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func pingCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ping",
+		Short: "Check configuration, database connectivity and API credentials",
+		Long: `Connects to PostgreSQL and Neo4j the way every job does, then embeds one word and
+sends one tiny translation request to confirm the API key and models work. Exits
+non-zero on the first failure, so CI can run it before a real job. Pass --skip-api
+to check only the databases.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			skipAPI, _ := cmd.Flags().GetBool("skip-api")
+			return runPing(skipAPI)
+		},
+	}
+
+	cmd.Flags().Bool("skip-api", false, "Only check PostgreSQL and Neo4j, without calling the API")
+
+	return cmd
+}
+
+// runPing handles the `ping` command.
+func runPing(skipAPI bool) error {
+	ctx, cancel := setupContext()
+	defer cancel()
+
+	cfg, err := config.Load(envFile)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	pgPool, neo4jDriver, err := initDependencies(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pgPool.Close()
+	defer neo4jDriver.Close(ctx)
+
+	if skipAPI {
+		log.Info().Msg("Ping complete (API skipped)")
+		return nil
+	}
+
+	embeddingClient, err := newEmbeddingClient(cfg)
+	if err != nil {
+		return err
+	}
+	vec, err := embeddingClient.EmbedQuery(ctx, "ping")
+	if err != nil {
+		return fmt.Errorf("embedding API: %w", err)
+	}
+	log.Info().Str("model", cfg.EmbeddingModel).Int("dimensions", len(vec)).Msg("Embedding API reachable")
+
+	opusClient, err := newOpusClient(cfg, cfg.TranslationModel)
+	if err != nil {
+		return err
+	}
+	if _, err := opusClient.Translate(ctx, "Reply with the single word OK.", "ping"); err != nil {
+		return fmt.Errorf("translation API: %w", err)
+	}
+	log.Info().Str("model", cfg.TranslationModel).Msg("Translation API reachable")
+
+	log.Info().Msg("Ping complete")
+	return nil
+}
@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+)
+
+// previewBatch picks the batch to preview: the one holding text, or the first when
+// text is empty. A text that is cached or not in the input is previewed as a batch
+// of its own. It returns the 1-based batch number, 0 for such a standalone batch,
+// and nil when there is nothing to preview.
+func previewBatch(batches [][]string, text string) (int, []string) {
+	if text == "" {
+		if len(batches) == 0 {
+			return 0, nil
+		}
+		return 1, batches[0]
+	}
+	for i, batch := range batches {
+		for _, t := range batch {
+			if t == text {
+				return i + 1, batch
+			}
+		}
+	}
+	return 0, []string{text}
+}
+
+// writePromptPreview writes the prompts a batch would be sent with.
+func writePromptPreview(w io.Writer, systemPrompt, userPrompt string, batchNum, size int) {
+	fmt.Fprintln(w, "=== SYSTEM PROMPT ===")
+	fmt.Fprintln(w, systemPrompt)
+	if batchNum > 0 {
+		fmt.Fprintf(w, "\n=== USER PROMPT (batch %d, %d texts) ===\n", batchNum, size)
+	} else {
+		fmt.Fprintln(w, "\n=== USER PROMPT (standalone text) ===")
+	}
+	fmt.Fprintln(w, userPrompt)
+}
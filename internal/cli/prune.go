@@ -0,0 +1,216 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"rag-translator/internal/cache"
+	"rag-translator/internal/config"
+	"rag-translator/internal/filewalker"
+	"rag-translator/internal/graph"
+	"rag-translator/internal/parser"
+	"rag-translator/internal/rag"
+	"rag-translator/internal/seed"
+	"rag-translator/internal/textutil"
+	"rag-translator/internal/worker"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func pruneCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune <input-dir>",
+		Short: "Delete embeddings, cache entries and text nodes of texts no longer in the input",
+		Long: `Parses the input tree and finds stored embeddings, translation cache entries (of every
+target language) and TextNodes whose source text no longer appears in it. Embeddings of
+seed corpus texts are kept, and so are cached translations of seed texts and of the
+sentences that long texts are translated as (SENTENCE_SPLIT_RUNES). By default only the counts are reported; pass --dry-run=false
+to delete. Refuses to run when any input file fails to parse, since its texts would be
+taken for orphans.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			return runPrune(args[0], dryRun)
+		},
+	}
+
+	cmd.Flags().Bool("dry-run", true, "Only report what would be deleted")
+
+	return cmd
+}
+
+// runPrune handles the `prune` command.
+func runPrune(inputDir string, dryRun bool) error {
+	ctx, cancel := setupContext()
+	defer cancel()
+
+	cfg, err := config.Load(envFile)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	if _, _, err := applyLanguages(cfg); err != nil {
+		return err
+	}
+
+	pgPool, neo4jDriver, err := initDependencies(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pgPool.Close()
+	defer neo4jDriver.Close(ctx)
+
+	live, err := liveTexts(ctx, cfg, inputDir)
+	if err != nil {
+		return err
+	}
+
+	// Seed texts are embedded for retrieval whether or not the game still uses them.
+	seeds, err := seed.NewSeedStore(pgPool).GetAll(ctx)
+	if err != nil {
+		return fmt.Errorf("load seed entries: %w", err)
+	}
+	embedLive := make(map[string]bool, len(live)+len(seeds))
+	for text := range live {
+		embedLive[textutil.Canonical(text)] = true
+	}
+	for _, e := range seeds {
+		embedLive[textutil.Canonical(e.SourceText)] = true
+	}
+	cacheLive := liveCacheTexts(embedLive, live, cfg.SentenceSplitRunes)
+
+	vectorStore := rag.NewVectorStore(pgPool)
+	embeddingSources, err := vectorStore.Sources(ctx)
+	if err != nil {
+		return err
+	}
+	orphanEmbeddings := orphanHashes(embeddingSources, embedLive)
+
+	translationCache := cache.NewTranslationCache(pgPool)
+	cacheSources, err := translationCache.Sources(ctx)
+	if err != nil {
+		return err
+	}
+	orphanCache := orphanHashes(cacheSources, cacheLive)
+
+	graphBuilder := graph.NewGraphBuilder(neo4jDriver)
+	nodeTexts, err := graphBuilder.TextNodeTexts(ctx)
+	if err != nil {
+		return err
+	}
+	var orphanNodes []string
+	for _, text := range nodeTexts {
+		if !live[text] {
+			orphanNodes = append(orphanNodes, text)
+		}
+	}
+
+	if dryRun {
+		log.Info().
+			Int("live_texts", len(live)).
+			Int("embeddings", len(orphanEmbeddings)).
+			Int("cache_entries", len(orphanCache)).
+			Int("text_nodes", len(orphanNodes)).
+			Msg("Dry run: would prune orphaned data (pass --dry-run=false to delete)")
+		return nil
+	}
+
+	deletedEmbeddings, err := vectorStore.Delete(ctx, orphanEmbeddings)
+	if err != nil {
+		return err
+	}
+	deletedCache, err := translationCache.Delete(ctx, orphanCache)
+	if err != nil {
+		return err
+	}
+	deletedNodes, err := graphBuilder.DeleteTextNodes(ctx, orphanNodes)
+	if err != nil {
+		return err
+	}
+
+	log.Info().
+		Int("live_texts", len(live)).
+		Int64("embeddings", deletedEmbeddings).
+		Int64("cache_entries", deletedCache).
+		Int("text_nodes", deletedNodes).
+		Msg("Prune complete")
+
+	return nil
+}
+
+// liveTexts parses every file under inputDir and returns the set of texts found.
+// A parse failure is an error: the texts of the failed file would otherwise look
+// orphaned and be pruned.
+func liveTexts(ctx context.Context, cfg *config.Config, inputDir string) (map[string]bool, error) {
+	w, err := newWalker(cfg)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := w.Walk(inputDir)
+	if err != nil {
+		return nil, fmt.Errorf("walk input directory: %w", err)
+	}
+
+	parsePool := worker.NewPool[filewalker.FileEntry, *parser.ParseResult](cfg.WorkerCount,
+		func(ctx context.Context, entry filewalker.FileEntry) (*parser.ParseResult, error) {
+			return entry.Parser.Parse(entry.Path)
+		},
+	)
+
+	live := make(map[string]bool)
+	for _, pr := range parsePool.Execute(ctx, entries) {
+		if pr.Err != nil {
+			return nil, fmt.Errorf("parse %s: %w", pr.Input.Path, pr.Err)
+		}
+		if pr.Result == nil {
+			continue
+		}
+		for _, et := range pr.Result.Texts {
+			live[et.Text] = true
+		}
+	}
+
+	log.Info().Int("files", len(entries)).Int("unique_texts", len(live)).Msg("Collected live texts")
+	return live, nil
+}
+
+// liveCacheTexts returns the canonical forms of the texts whose translations a
+// run caches: those in keep, plus the sentences that planTranslation queues for
+// the texts of live longer than splitRunes. Variants are cached under their own
+// text, which is live already.
+func liveCacheTexts(keep, live map[string]bool, splitRunes int) map[string]bool {
+	cacheLive := make(map[string]bool, len(keep))
+	for text := range keep {
+		cacheLive[text] = true
+	}
+	for text := range live {
+		split, ok := splitLong(text, splitRunes)
+		if !ok {
+			continue
+		}
+		for _, sentence := range split.Sentences {
+			if textutil.ContainsSource(sentence) {
+				cacheLive[textutil.Canonical(sentence)] = true
+			}
+		}
+	}
+	return cacheLive
+}
+
+// orphanHashes returns, sorted, the hashes in sources whose source text is not
+// live. live holds canonical forms (see textutil.Canonical), as entries are keyed
+// by canonical hash and may have been stored under another variant of a live text.
+func orphanHashes(sources map[string]string, live map[string]bool) []string {
+	var orphans []string
+	for hash, source := range sources {
+		if !live[textutil.Canonical(source)] {
+			orphans = append(orphans, hash)
+		}
+	}
+	sort.Strings(orphans)
+	return orphans
+}
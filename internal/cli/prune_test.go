@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+
+	"rag-translator/internal/textutil"
+)
+
+func TestOrphanHashes(t *testing.T) {
+	long := "这是第一句话，很长很长。这是第二句话，也很长。"
+	nfd := "Cafe\u0301"
+	seed := "种子文本"
+	live := map[string]bool{long: true, "短文本": true, "Caf\u00e9": true}
+	keep := map[string]bool{seed: true}
+	for text := range live {
+		keep[textutil.Canonical(text)] = true
+	}
+	cacheLive := liveCacheTexts(keep, live, 10)
+
+	tests := []struct {
+		name   string
+		source string
+		orphan bool
+	}{
+		{"live text", "短文本", false},
+		{"first sentence of long text", "这是第一句话，很长很长。", false},
+		{"second sentence of long text", "这是第二句话，也很长。", false},
+		{"long text itself", long, false},
+		{"other normalization form", nfd, false},
+		{"seed text", seed, false},
+		{"removed text", "已删除", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := orphanHashes(map[string]string{"h": tt.source}, cacheLive)
+			var want []string
+			if tt.orphan {
+				want = []string{"h"}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("orphanHashes(%q) = %v, want %v", tt.source, got, want)
+			}
+		})
+	}
+}
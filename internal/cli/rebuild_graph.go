@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"fmt"
+
+	"rag-translator/internal/config"
+	"rag-translator/internal/graph"
+	"rag-translator/internal/seed"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func rebuildGraphCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "rebuild-graph",
+		Short: "Repopulate the knowledge graph from the stored seed corpus",
+		Long: `Re-seeds terminology and re-creates SeedTranslation nodes from the seed entries
+already stored in PostgreSQL. Does not read Git or call the embedding API, so it is
+cheap to run after Neo4j is wiped or the graph schema changes. Safe to run repeatedly.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRebuildGraph()
+		},
+	}
+}
+
+// runRebuildGraph handles the `rebuild-graph` command.
+func runRebuildGraph() error {
+	ctx, cancel := setupContext()
+	defer cancel()
+
+	cfg, err := config.Load(envFile)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	if _, _, err := applyLanguages(cfg); err != nil {
+		return err
+	}
+
+	pgPool, neo4jDriver, err := initDependencies(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pgPool.Close()
+	defer neo4jDriver.Close(ctx)
+
+	// Terminology goes first so seed nodes can link to their terms.
+	graphBuilder := graph.NewGraphBuilder(neo4jDriver)
+	graphBuilder.SetMissingEndpointMode(graph.MissingEndpointMode(cfg.MissingTermEndpoints))
+	if err := graphBuilder.EnsureSchema(ctx); err != nil {
+		return fmt.Errorf("ensure graph schema: %w", err)
+	}
+	if err := graphBuilder.SeedTerminology(ctx); err != nil {
+		return fmt.Errorf("seed terminology: %w", err)
+	}
+
+	graphSeeder := seed.NewGraphSeeder(neo4jDriver)
+	if err := graphSeeder.EnsureSchema(ctx); err != nil {
+		return fmt.Errorf("ensure graph seed schema: %w", err)
+	}
+
+	entries, err := seed.NewSeedStore(pgPool).GetAll(ctx)
+	if err != nil {
+		return fmt.Errorf("load seed entries: %w", err)
+	}
+
+	upserted, err := graphSeeder.UpsertSeedNodes(ctx, entries)
+	if err != nil {
+		return fmt.Errorf("upsert seed graph nodes: %w", err)
+	}
+
+	log.Info().
+		Int("seed_entries", len(entries)).
+		Int("seed_nodes", upserted).
+		Int("failed", len(entries)-upserted).
+		Msg("Graph rebuild complete")
+
+	return nil
+}
@@ -0,0 +1,360 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"rag-translator/internal/cache"
+	"rag-translator/internal/config"
+	"rag-translator/internal/filewalker"
+	"rag-translator/internal/language"
+	"rag-translator/internal/parser"
+	"rag-translator/internal/progress"
+	"rag-translator/internal/rag"
+	"rag-translator/internal/textutil"
+	"rag-translator/internal/translation"
+	"rag-translator/internal/worker"
+
+	"github.com/rs/zerolog/log"
+)
+
+// applyOverrides writes manual corrections from a TSV file into the cache. Overrides
+// rank above cache, seeds, and model output: writing them up front means they are
+// never sent to the model and always reconstructed. An empty path is a no-op.
+func applyOverrides(ctx context.Context, translationCache cache.Cache, path string) error {
+	if path == "" {
+		return nil
+	}
+	overrides, err := translation.LoadOverrides(path)
+	if err != nil {
+		return fmt.Errorf("load overrides: %w", err)
+	}
+	if err := translationCache.SetBatch(ctx, overrides); err != nil {
+		return fmt.Errorf("apply overrides: %w", err)
+	}
+	log.Info().Int("count", len(overrides)).Str("path", path).Msg("Applied manual overrides")
+	return nil
+}
+
+// applyExactSeeds caches the seed translation of every text whose source matches a
+// seed entry exactly, so the model is never asked to translate it, and returns the
+// texts that still need translating. A failed lookup leaves the text to the model.
+func applyExactSeeds(ctx context.Context, seedQuerier rag.SeedQuerier, translationCache cache.Cache, texts []string) []string {
+	remaining := texts[:0:0]
+	applied := 0
+	for _, text := range texts {
+		seeds, err := seedQuerier.FindSeedTranslations(ctx, text)
+		if err != nil {
+			log.Warn().Err(err).Str("text", textutil.Truncate(text, 30)).Msg("Seed query failed")
+			remaining = append(remaining, text)
+			continue
+		}
+		translated, ok := seeds[text]
+		if !ok {
+			remaining = append(remaining, text)
+			continue
+		}
+		if err := translationCache.Set(ctx, text, translated); err != nil {
+			log.Warn().Err(err).Msg("Failed to cache translation")
+			remaining = append(remaining, text)
+			continue
+		}
+		applied++
+	}
+	log.Info().Int("count", applied).Msg("Applied exact seed matches")
+	return remaining
+}
+
+// joinSentences caches the translation of every split text whose sentences are
+// all translated: the sentence translations rejoined in the layout of the text.
+// Sentences without source text are kept as they are. A text with an untranslated
+// sentence is left uncached.
+func joinSentences(ctx context.Context, splits map[string]translation.SentenceSplit, translationCache cache.Cache) {
+	joined, incomplete := 0, 0
+	for text, split := range splits {
+		parts := make([]string, len(split.Sentences))
+		complete := true
+		for i, sentence := range split.Sentences {
+			if !textutil.ContainsSource(sentence) {
+				parts[i] = sentence
+				continue
+			}
+			translated, ok := translationCache.Get(ctx, sentence)
+			if !ok {
+				complete = false
+				break
+			}
+			parts[i] = translated
+		}
+		if !complete {
+			incomplete++
+			continue
+		}
+		if err := translationCache.Set(ctx, text, split.Join(parts)); err != nil {
+			log.Warn().Err(err).Str("text", textutil.Truncate(text, 30)).Msg("Failed to cache joined translation")
+			continue
+		}
+		joined++
+	}
+	if len(splits) > 0 {
+		log.Info().Int("joined", joined).Int("incomplete", incomplete).Msg("Joined sentence-split translations")
+	}
+}
+
+// applyVariants caches for every variant the translation of the text it was
+// merged with, given the variant's own padding and full-width or half-width
+// punctuation (see textutil.VariantForms). A variant whose representative is
+// untranslated, or which shares its cache key, is left as it is.
+func applyVariants(ctx context.Context, variants map[string]string, translationCache cache.Cache) {
+	applied := 0
+	for variant, rep := range variants {
+		translated, ok := translationCache.Get(ctx, rep)
+		if !ok {
+			continue
+		}
+		// With HASH_FOLD_WIDTH a variant differing only in width shares the cache
+		// key of rep, and setting it would overwrite rep's translation.
+		if textutil.CanonicalHash(variant) == textutil.CanonicalHash(rep) {
+			continue
+		}
+		forms, _ := textutil.VariantForms(rep, variant)
+		if err := translationCache.Set(ctx, variant, textutil.Repad(variant, forms.Apply(translated))); err != nil {
+			log.Warn().Err(err).Str("text", textutil.Truncate(variant, 30)).Msg("Failed to cache variant translation")
+			continue
+		}
+		applied++
+	}
+	if len(variants) > 0 {
+		log.Info().Int("applied", applied).Int("variants", len(variants)).Msg("Applied translations to merged variants")
+	}
+}
+
+// newWalker creates a file walker with parser settings from cfg.
+func newWalker(cfg *config.Config) (*filewalker.Walker, error) {
+	w := filewalker.NewWalker()
+	w.SetStreamThreshold(int64(cfg.StreamThresholdMB) << 20)
+	w.SetTSVHeaderMode(parser.HeaderMode(cfg.TSVHeaderMode))
+	w.SetSniffContent(cfg.SniffContent)
+	w.SetINICommentPrefixes(cfg.INICommentPrefixes)
+	w.SetLuaMode(parser.LuaMode(cfg.LuaParseMode))
+	w.SetIncludeComments(cfg.IncludeComments)
+	w.SetOutputSuffix(cfg.OutputSuffix)
+	if len(cfg.INIKeySections) > 0 {
+		sections, err := parser.ParseINIKeySections(cfg.INIKeySections)
+		if err != nil {
+			return nil, fmt.Errorf("INI_KEY_SECTIONS: %w", err)
+		}
+		w.SetINIKeySections(sections)
+	}
+	if len(cfg.ParserOverrides) > 0 {
+		rules, err := filewalker.ParseParserRules(cfg.ParserOverrides)
+		if err != nil {
+			return nil, fmt.Errorf("PARSER_OVERRIDES: %w", err)
+		}
+		w.SetParserRules(rules)
+	}
+	if cfg.TSVColumnsFile != "" {
+		rules, err := filewalker.LoadColumnRules(cfg.TSVColumnsFile)
+		if err != nil {
+			return nil, err
+		}
+		w.SetColumnRules(rules)
+		log.Info().Int("rules", len(rules)).Str("path", cfg.TSVColumnsFile).Msg("Loaded TSV column rules")
+	}
+	return w, nil
+}
+
+// prepareOutput makes sure the output location for inputPath exists and returns it.
+// A directory input needs an output directory. A single-file input is written to
+// outputPath itself, or into it when outputPath is an existing directory. An empty
+// outputPath, allowed only with an output suffix, writes next to the input.
+func prepareOutput(inputPath, outputPath, suffix string) (string, error) {
+	info, err := os.Stat(inputPath)
+	if err != nil {
+		return "", fmt.Errorf("stat input: %w", err)
+	}
+	if outputPath == "" {
+		if suffix == "" {
+			return "", fmt.Errorf("an output path is required unless --output-suffix or OUTPUT_SUFFIX is set")
+		}
+		return inputPath, nil
+	}
+
+	if info.IsDir() {
+		if err := os.MkdirAll(outputPath, 0755); err != nil {
+			return "", fmt.Errorf("create output directory: %w", err)
+		}
+		return outputPath, nil
+	}
+
+	if out, err := os.Stat(outputPath); err == nil && out.IsDir() {
+		return filepath.Join(outputPath, filepath.Base(inputPath)), nil
+	}
+	return outputPath, nil
+}
+
+// writeOutputs reconstructs every parsed file with its cached translations and writes
+// it under outputDir, mirroring the input tree, with suffix inserted into each file
+// name (see filewalker.OutputPath). Texts without a cached translation are left in
+// the source language. A file whose output path is one of the input files is not
+// written, so sources are never overwritten. Depending on bilingual, a review file
+// listing each source and translation is written next to or instead of each output
+// file. It returns per-file translation coverage.
+func writeOutputs(ctx context.Context, parseResults []worker.Task[filewalker.FileEntry, *parser.ParseResult], translationCache cache.Cache, inputDir, outputDir, suffix string, bilingual bilingualMode) *coverage {
+	inputAbs, _ := filepath.Abs(inputDir)
+	outputAbs, _ := filepath.Abs(outputDir)
+	inputs := make(map[string]bool, len(parseResults))
+	for _, pr := range parseResults {
+		inputs[pr.Input.Path] = true
+	}
+
+	cov := &coverage{Ratio: 1}
+	bar := progress.New("Writing", len(parseResults))
+	defer bar.Finish()
+	for _, pr := range parseResults {
+		bar.Add(1)
+		if pr.Err != nil || pr.Result == nil {
+			continue
+		}
+
+		// Build translations map for this file.
+		fileTranslations := make(map[string]string)
+		for _, et := range pr.Result.Texts {
+			if translated, ok := translationCache.Get(ctx, et.Text); ok {
+				fileTranslations[et.Text] = translated
+			}
+		}
+
+		// Compute output path.
+		entry := pr.Input
+		relPath, err := filepath.Rel(inputAbs, entry.Path)
+		if err != nil {
+			log.Error().Err(err).Msg("Compute relative path")
+			continue
+		}
+		outPath := filepath.Join(outputAbs, relPath)
+		if suffix != "" {
+			outPath = filewalker.OutputPath(outPath, suffix)
+		}
+		if inputs[outPath] {
+			log.Error().Str("file", entry.Path).Str("path", outPath).Msg("Output path is an input file, not overwriting it")
+			continue
+		}
+
+		// Create parent directories.
+		if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+			log.Error().Err(err).Str("path", outPath).Msg("Create output directory")
+			continue
+		}
+
+		if bilingual == bilingualAlso || bilingual == bilingualOnly {
+			if err := writeBilingual(outPath+bilingualSuffix, pr.Result.Texts, fileTranslations); err != nil {
+				log.Error().Err(err).Str("file", entry.Path).Str("path", outPath+bilingualSuffix).Msg("Write bilingual file")
+			}
+		}
+
+		// Reconstruct and write the translated file. In bilingual-only mode it is
+		// reconstructed for its coverage report and discarded.
+		target := outPath
+		if bilingual == bilingualOnly {
+			target = os.DevNull
+		}
+		report, err := writeReconstructed(entry.Parser, pr.Result, fileTranslations, target)
+		if err != nil {
+			log.Error().Err(err).Str("file", entry.Path).Str("path", outPath).Msg("Write output file")
+			continue
+		}
+
+		cov.add(filepath.ToSlash(relPath), report)
+		if report.SkippedOutOfRange > 0 {
+			log.Warn().
+				Str("file", entry.Path).
+				Int("out_of_range", report.SkippedOutOfRange).
+				Msg("Some texts could not be placed in the output")
+		}
+		log.Info().
+			Str("input", entry.Path).
+			Str("output", outPath).
+			Int("translations", len(fileTranslations)).
+			Int("applied", report.Applied).
+			Int("untranslated", report.SkippedMissing+report.SkippedOutOfRange).
+			Msg("File translated")
+	}
+
+	return cov
+}
+
+// writeReconstructed rebuilds one file, writes it to outPath and returns the
+// parser's report of applied and skipped texts. Streamed results are written
+// straight to the file so large files never sit in memory.
+func writeReconstructed(p parser.Parser, result *parser.ParseResult, translations map[string]string, outPath string) (parser.ReconstructReport, error) {
+	if sr, ok := p.(parser.StreamReconstructor); ok && result.Streamed {
+		f, err := os.Create(outPath)
+		if err != nil {
+			return parser.ReconstructReport{}, fmt.Errorf("create output file: %w", err)
+		}
+		report, err := sr.ReconstructTo(f, result, translations)
+		if err != nil {
+			f.Close()
+			return report, fmt.Errorf("reconstruct: %w", err)
+		}
+		return report, f.Close()
+	}
+
+	reconstructed, report, err := p.Reconstruct(result, translations)
+	if err != nil {
+		return report, fmt.Errorf("reconstruct: %w", err)
+	}
+	return report, os.WriteFile(outPath, reconstructed, 0644)
+}
+
+// runTranslateCached handles `translate --only-cached`: it rebuilds output files from
+// the translation cache alone, without connecting to Neo4j or calling any API.
+func runTranslateCached(ctx context.Context, cfg *config.Config, targetLang language.Language, inputDir, outputDir string, opts translateOptions) error {
+	pgPool, err := initPostgres(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pgPool.Close()
+
+	translationCache := cache.NewTranslationCache(pgPool)
+	translationCache.SetTargetLanguage(targetLang.Code)
+	// The cache is the only source of translations here, so a failed preload is fatal.
+	if err := translationCache.Preload(ctx); err != nil {
+		return fmt.Errorf("preload cache: %w", err)
+	}
+
+	if err := applyOverrides(ctx, translationCache, opts.overridesPath); err != nil {
+		return err
+	}
+
+	outputDir, err = prepareOutput(inputDir, outputDir, cfg.OutputSuffix)
+	if err != nil {
+		return err
+	}
+
+	plan, err := planTranslation(ctx, cfg, inputDir, opts.since, func(text string) bool {
+		_, cached := translationCache.Get(ctx, text)
+		return cached
+	})
+	if err != nil {
+		return err
+	}
+
+	joinSentences(ctx, plan.splits, translationCache)
+	applyVariants(ctx, plan.variants, translationCache)
+	cov := writeOutputs(ctx, plan.parseResults, translationCache, inputDir, outputDir, cfg.OutputSuffix, opts.bilingual)
+
+	log.Info().
+		Int("files", len(plan.entries)).
+		Int("unique_texts", plan.uniqueTexts).
+		Int("missing_unique_texts", len(plan.toTranslate)).
+		Int("untranslated", cov.untranslated()).
+		Float64("coverage", cov.Ratio).
+		Str("output", outputDir).
+		Msg("Reconstruction from cache complete")
+
+	return checkCoverage(cov, opts.coverageReport, opts.minCoverage)
+}
@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"context"
+	"testing"
+
+	"rag-translator/internal/cache"
+	"rag-translator/internal/textutil"
+	"rag-translator/internal/translation"
+)
+
+// canonicalCache is a MemoryCache keyed like TranslationCache, by canonical hash.
+type canonicalCache struct {
+	*cache.MemoryCache
+}
+
+func (c canonicalCache) Get(ctx context.Context, sourceText string) (string, bool) {
+	return c.MemoryCache.Get(ctx, textutil.CanonicalHash(sourceText))
+}
+
+func (c canonicalCache) Set(ctx context.Context, sourceText, translated string) error {
+	return c.MemoryCache.Set(ctx, textutil.CanonicalHash(sourceText), translated)
+}
+
+func TestApplyVariants(t *testing.T) {
+	tests := []struct {
+		name      string
+		foldWidth bool
+		want      map[string]string // text → cached translation; "" for none
+	}{
+		{
+			name: "width kept apart",
+			want: map[string]string{
+				"攻击力:10":  "Tấn công: 10",
+				"攻击力：10":  "Tấn công： 10",
+				"攻击力：10 ": "Tấn công： 10 ",
+				"  确认":    "  OK",
+				"取消 ":     "",
+			},
+		},
+		{
+			name:      "width folded",
+			foldWidth: true,
+			want: map[string]string{
+				// The variant shares the representative's key and is left alone,
+				// rather than overwriting it.
+				"攻击力:10":  "Tấn công: 10",
+				"攻击力：10":  "Tấn công: 10",
+				"攻击力：10 ": "Tấn công： 10 ",
+				"  确认":    "  OK",
+				"取消 ":     "",
+			},
+		},
+	}
+	defer textutil.SetFoldWidth(false)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			textutil.SetFoldWidth(tt.foldWidth)
+			ctx := context.Background()
+			c := canonicalCache{cache.NewMemoryCache()}
+			_ = c.Set(ctx, "攻击力:10", "Tấn công: 10")
+			_ = c.Set(ctx, "确认", "OK")
+
+			applyVariants(ctx, map[string]string{
+				"攻击力：10":  "攻击力:10",
+				"攻击力：10 ": "攻击力:10",
+				"  确认":    "确认",
+				"取消 ":     "取消", // representative untranslated
+			}, c)
+
+			for text, want := range tt.want {
+				got, ok := c.Get(ctx, text)
+				if ok != (want != "") || got != want {
+					t.Errorf("Get(%q) = %q, %v, want %q", text, got, ok, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitJoinSentences(t *testing.T) {
+	sentences := map[string]string{
+		"你好。":      "Xin chào.",
+		"你是谁？":     "Ngươi là ai?",
+		"我是张无忌！":   "Ta là Trương Vô Kỵ!",
+		"“明教教主。”":  "“Giáo chủ Minh Giáo.”",
+		"快走":       "Mau đi",
+		"第一句。":     "Câu thứ nhất.",
+		"第二句！！":    "Câu thứ hai!!",
+		"来者何人？":    "Người đến là ai?",
+		"报上名来……":   "Xưng tên đi…",
+		"{0}获得奖励。": "{0} nhận được phần thưởng.",
+	}
+	tests := []struct {
+		name string
+		text string
+		want string // "" when the text is left uncached
+	}{
+		{"dialog lines", "你好。你是谁？\n我是张无忌！", "Xin chào. Ngươi là ai?\nTa là Trương Vô Kỵ!"},
+		{"closing quote stays with its sentence", "我是张无忌！“明教教主。”", "Ta là Trương Vô Kỵ! “Giáo chủ Minh Giáo.”"},
+		{"escaped line break", `第一句。\n第二句！！`, `Câu thứ nhất.\nCâu thứ hai!!`},
+		{"lead and trailing whitespace", "  来者何人？ 报上名来……\n", "  Người đến là ai? Xưng tên đi…\n"},
+		{"sentence without source text", "你好。123\n你是谁？", "Xin chào. 123\nNgươi là ai?"},
+		{"placeholder", "{0}获得奖励。快走", "{0} nhận được phần thưởng. Mau đi"},
+		{"incomplete", "你好。没有翻译的句子。你是谁？", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			split, ok := splitLong(tt.text, 4)
+			if !ok {
+				t.Fatalf("splitLong(%q) did not split", tt.text)
+			}
+			if got := split.Join(split.Sentences); got != tt.text {
+				t.Errorf("untranslated join = %q, want %q", got, tt.text)
+			}
+
+			ctx := context.Background()
+			c := cache.NewMemoryCache()
+			for src, dst := range sentences {
+				_ = c.Set(ctx, src, dst)
+			}
+			joinSentences(ctx, map[string]translation.SentenceSplit{tt.text: split}, c)
+
+			got, cached := c.Get(ctx, tt.text)
+			if tt.want == "" {
+				if cached {
+					t.Errorf("incomplete text cached as %q", got)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("joined %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, ok := splitLong("你好。你是谁？", 0); ok {
+		t.Error("splitLong split with splitting disabled")
+	}
+	if _, ok := splitLong("你好。你是谁？", 20); ok {
+		t.Error("splitLong split a text within the limit")
+	}
+}
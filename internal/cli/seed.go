@@ -0,0 +1,177 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"rag-translator/internal/config"
+	"rag-translator/internal/seed"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func seedCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "seed",
+		Short: "Inspect the seed translation corpus",
+	}
+
+	cmd.AddCommand(seedSearchCmd())
+	cmd.AddCommand(seedLintCmd())
+
+	return cmd
+}
+
+func seedSearchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "search <query>",
+		Short: "Find seed entries whose source or translation contains a substring",
+		Long: `Searches the stored seed corpus case-insensitively in both source and translated
+text and prints matches in a table. Pass "" as the query to list by --entity-type or
+--quality only.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entityType, _ := cmd.Flags().GetString("entity-type")
+			quality, _ := cmd.Flags().GetString("quality")
+			limit, _ := cmd.Flags().GetInt("limit")
+			if limit < 1 {
+				return fmt.Errorf("--limit must be at least 1")
+			}
+			return runSeedSearch(args[0], entityType, quality, limit)
+		},
+	}
+
+	cmd.Flags().String("entity-type", "", "Only show entries of this entity type (e.g. skill, item)")
+	cmd.Flags().String("quality", "", "Only show entries with this quality label")
+	cmd.Flags().Int("limit", 50, "Maximum number of entries to print")
+
+	return cmd
+}
+
+// runSeedSearch handles the `seed search` command.
+func runSeedSearch(query, entityType, quality string, limit int) error {
+	ctx, cancel := setupContext()
+	defer cancel()
+
+	cfg, err := config.Load(envFile)
+	if err != nil {
+		return err
+	}
+
+	pgPool, err := initPostgres(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pgPool.Close()
+
+	entries, err := seed.NewSeedStore(pgPool).Search(ctx, query, entityType, quality, limit)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SOURCE\tTRANSLATION\tENTITY TYPE\tQUALITY\tFILE")
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", oneLine(e.SourceText), oneLine(e.TranslatedText), e.EntityType, e.Quality, e.File)
+	}
+	tw.Flush()
+
+	log.Info().
+		Str("query", query).
+		Str("entity_type", entityType).
+		Str("quality", quality).
+		Int("matches", len(entries)).
+		Bool("truncated", len(entries) == limit).
+		Msg("Seed search complete")
+
+	return nil
+}
+
+func seedLintCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Report seed entries that look mis-mined",
+		Long: `Checks every stored seed entry and lists the suspicious ones for review: a source
+without Chinese text, a translation still containing Chinese, interpolation variables,
+format specifiers or markup tags that differ between source and translation, and a
+translation more than --max-length-ratio times longer or shorter than its source.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			maxRatio, _ := cmd.Flags().GetFloat64("max-length-ratio")
+			outputPath, _ := cmd.Flags().GetString("output")
+			if maxRatio < 0 {
+				return fmt.Errorf("--max-length-ratio must not be negative")
+			}
+			return runSeedLint(maxRatio, outputPath)
+		},
+	}
+
+	cmd.Flags().Float64("max-length-ratio", 6, "Flag translations this many times longer or shorter than their source, in characters (0 disables)")
+	cmd.Flags().String("output", "", "Also write the suspicious entries with their hashes to this TSV path")
+
+	return cmd
+}
+
+// runSeedLint handles the `seed lint` command.
+func runSeedLint(maxRatio float64, outputPath string) error {
+	ctx, cancel := setupContext()
+	defer cancel()
+
+	cfg, err := config.Load(envFile)
+	if err != nil {
+		return err
+	}
+	if _, _, err := applyLanguages(cfg); err != nil {
+		return err
+	}
+
+	pgPool, err := initPostgres(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pgPool.Close()
+
+	entries, err := seed.NewSeedStore(pgPool).GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	issues := seed.Lint(entries, maxRatio)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SOURCE\tTRANSLATION\tFILE\tPROBLEMS")
+	for _, issue := range issues {
+		e := issue.Entry
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", oneLine(e.SourceText), oneLine(e.TranslatedText), e.File, strings.Join(issue.Problems, "; "))
+	}
+	tw.Flush()
+
+	if outputPath != "" {
+		if err := writeSeedIssues(outputPath, issues); err != nil {
+			return err
+		}
+	}
+
+	log.Info().
+		Int("entries", len(entries)).
+		Int("suspicious", len(issues)).
+		Msg("Seed lint complete")
+
+	return nil
+}
+
+// writeSeedIssues writes issues as TSV: hash, source, translation, file, problems.
+func writeSeedIssues(path string, issues []seed.LintIssue) error {
+	var sb strings.Builder
+	sb.WriteString("# hash\tsource\ttranslation\tfile\tproblems\n")
+	for _, issue := range issues {
+		e := issue.Entry
+		fmt.Fprintf(&sb, "%s\t%s\t%s\t%s\t%s\n", e.Hash, escapeCell(e.SourceText), escapeCell(e.TranslatedText), escapeCell(e.File), escapeCell(strings.Join(issue.Problems, "; ")))
+	}
+	if err := os.WriteFile(path, []byte(sb.String()), 0644); err != nil {
+		return fmt.Errorf("write seed lint report: %w", err)
+	}
+	return nil
+}
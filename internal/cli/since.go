@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"rag-translator/internal/filewalker"
+	"rag-translator/internal/seed"
+
+	"github.com/rs/zerolog/log"
+)
+
+// sinceLayouts are the timestamp formats accepted by --since. Anything else is
+// taken to be a git ref.
+var sinceLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"}
+
+// filterSince keeps the entries changed since since, which is either a timestamp
+// compared against file modification times or a git ref compared against the
+// working tree of root. An empty since keeps every entry.
+func filterSince(ctx context.Context, entries []filewalker.FileEntry, root, since string) ([]filewalker.FileEntry, error) {
+	if since == "" {
+		return entries, nil
+	}
+
+	changed, err := changedSince(ctx, root, since)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := entries[:0:0]
+	for _, entry := range entries {
+		if changed(entry.Path) {
+			kept = append(kept, entry)
+		}
+	}
+	log.Info().Str("since", since).Int("changed", len(kept)).Int("files", len(entries)).Msg("Limited input to changed files")
+	return kept, nil
+}
+
+// changedSince returns a predicate reporting whether an absolute file path changed
+// since since.
+func changedSince(ctx context.Context, root, since string) (func(path string) bool, error) {
+	for _, layout := range sinceLayouts {
+		t, err := time.ParseInLocation(layout, since, time.Local)
+		if err != nil {
+			continue
+		}
+		return func(path string) bool {
+			info, err := os.Stat(path)
+			return err == nil && !info.ModTime().Before(t)
+		}, nil
+	}
+
+	dir, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("resolve input path: %w", err)
+	}
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+	files, err := seed.ChangedFilesSince(ctx, dir, since)
+	if err != nil {
+		return nil, fmt.Errorf("list files changed since %s: %w", since, err)
+	}
+	set := make(map[string]bool, len(files))
+	for _, f := range files {
+		set[filepath.Join(dir, filepath.FromSlash(f))] = true
+	}
+	return func(path string) bool { return set[path] }, nil
+}
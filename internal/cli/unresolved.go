@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"rag-translator/internal/filewalker"
+	"rag-translator/internal/parser"
+	"rag-translator/internal/worker"
+
+	"github.com/rs/zerolog/log"
+)
+
+// unresolvedTexts records why texts could not be translated during a run. Only the
+// last reason per text is kept.
+type unresolvedTexts struct {
+	order   []string
+	reasons map[string]string
+}
+
+func newUnresolvedTexts() *unresolvedTexts {
+	return &unresolvedTexts{reasons: make(map[string]string)}
+}
+
+// add records a failure to translate text.
+func (u *unresolvedTexts) add(text, reason string) {
+	if _, ok := u.reasons[text]; !ok {
+		u.order = append(u.order, text)
+	}
+	u.reasons[text] = reason
+}
+
+// remaining returns the recorded texts for which isDone reports false, in the order
+// they first failed. A text that failed once but was translated later is dropped.
+func (u *unresolvedTexts) remaining(isDone func(text string) bool) []string {
+	var texts []string
+	for _, text := range u.order {
+		if !isDone(text) {
+			texts = append(texts, text)
+		}
+	}
+	return texts
+}
+
+// writeUnresolved writes texts as TSV with an empty target column, ready to be
+// filled in and applied with --overrides: source, target, file, line, reason. The
+// file and line are those of the text's first occurrence in the input.
+func writeUnresolved(path string, texts []string, reasons map[string]string, parseResults []worker.Task[filewalker.FileEntry, *parser.ParseResult], inputDir string) error {
+	locations := make(map[string]parser.ExtractedText, len(texts))
+	for _, text := range texts {
+		locations[text] = parser.ExtractedText{}
+	}
+	for _, pr := range parseResults {
+		if pr.Err != nil || pr.Result == nil {
+			continue
+		}
+		for _, et := range pr.Result.Texts {
+			if loc, ok := locations[et.Text]; ok && loc.File == "" {
+				locations[et.Text] = et
+			}
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create unresolved file: %w", err)
+	}
+	defer f.Close()
+
+	inputAbs, _ := filepath.Abs(inputDir)
+	fmt.Fprintln(f, "# source\ttarget\tfile\tline\treason")
+	for _, text := range texts {
+		loc := locations[text]
+		file := loc.File
+		if rel, err := filepath.Rel(inputAbs, file); err == nil && !strings.HasPrefix(rel, "..") {
+			file = filepath.ToSlash(rel)
+		}
+		fmt.Fprintf(f, "%s\t\t%s\t%d\t%s\n", escapeCell(text), escapeCell(file), loc.Line, escapeCell(reasons[text]))
+	}
+
+	log.Info().Str("path", path).Int("texts", len(texts)).Msg("Wrote unresolved texts")
+	return f.Close()
+}
+
+// escapeCell escapes tabs and line breaks the way LoadOverrides decodes them.
+func escapeCell(s string) string {
+	return strings.NewReplacer("\t", `\t`, "\n", `\n`, "\r", `\r`).Replace(s)
+}
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"fmt"
+
+	"rag-translator/internal/cache"
+	"rag-translator/internal/config"
+	"rag-translator/internal/seed"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func warmCacheCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "warm-cache",
+		Short: "Populate the translation cache from the stored seed corpus",
+		Long: `Writes every seed translation stored in PostgreSQL into the translation cache, so
+seeded texts are reused without calling the model. Does not read Git or call any API,
+so it is cheap to run after the cache is wiped. Safe to run repeatedly.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWarmCache()
+		},
+	}
+}
+
+// runWarmCache handles the `warm-cache` command.
+func runWarmCache() error {
+	ctx, cancel := setupContext()
+	defer cancel()
+
+	cfg, err := config.Load(envFile)
+	if err != nil {
+		return err
+	}
+
+	pgPool, err := initPostgres(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pgPool.Close()
+
+	seeds, err := seed.NewSeedStore(pgPool).BuildTranslationMap(ctx)
+	if err != nil {
+		return fmt.Errorf("load seed translations: %w", err)
+	}
+
+	if err := cache.NewTranslationCache(pgPool).SetBatch(ctx, seeds); err != nil {
+		return fmt.Errorf("cache seed translations: %w", err)
+	}
+
+	log.Info().Int("entries", len(seeds)).Msg("Cache warmed from seed corpus")
+
+	return nil
+}
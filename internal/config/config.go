@@ -1,30 +1,91 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+
+	"rag-translator/internal/httpclient"
+	"rag-translator/internal/language"
+	"rag-translator/internal/segment"
+	"rag-translator/internal/textutil"
 
 	"github.com/joho/godotenv"
 	"github.com/rs/zerolog/log"
 )
 
+// MaxRetrievalTopK caps retrieval breadth so prompts stay a manageable size.
+const MaxRetrievalTopK = 20
+
 type Config struct {
 	GeminiAPIKey          string
 	DatabaseURL           string
 	Neo4jURI              string
 	Neo4jUser             string
 	Neo4jPassword         string
+	Neo4jMaxConcurrent    int // concurrent graph write transactions during ingest
 	WorkerCount           int
 	BatchSize             int
+	BatchSizeMin          int // lower bound when batch size auto-tuning is on
+	BatchSizeMax          int // upper bound for auto-tuning; 0 keeps BatchSize fixed
 	MaxConcurrentAPICalls int
+	APIMaxRetries         int // retries of a failed API request; 0 fails on the first error
 	EmbeddingModel        string
 	EmbeddingDimensions   int
+	EmbeddingTimeout      time.Duration
 	TranslationModel      string
+	TranslationTimeout    time.Duration
+	HTTPProxyURL          string // proxy for API requests; empty uses HTTPS_PROXY from the environment
+	CABundleFile          string // PEM certificates trusted for API requests besides the system roots
+	TLSInsecure           bool   // skip API certificate verification; development only
+	FallbackModel         string // model retried for requests the primary fails; empty disables
+	RetrievalTopK         int
+	FailureThreshold      int    // translation failures in a row that abort a run; 0 disables
+	SeedStrictness        string // "off", "soft" or "hard"; see translation.SeedStrictness
+	BatchContextItems     int    // per-section cap on retrieval context in batch prompts; 0 disables
+	SentenceSplitRunes    int    // texts longer than this are translated sentence by sentence; 0 disables
+	MissingTermEndpoints  string // "report" or "create"; see graph.MissingEndpointMode
+	StreamThresholdMB     int    // .txt files above this size are streamed; 0 disables
+	TSVColumnsFile        string // optional per-file TSV column selection rules
+	NumberUnitsFile       string // optional Chinese numeric unit → rendering table
+	RejectRules           string // comma-separated translation.RejectRule names, or "none"
+	RefusalPatternsFile   string // optional regexes replacing translation.DefaultRefusalPatterns
+	TSVHeaderMode         string // "never", "always" or "auto"; see parser.HeaderMode
+	OutputSuffix          string // write translations next to sources as name<suffix>.ext; see filewalker.OutputPath
+	SniffContent          bool   // pick .lua/.ini/.txt parsers by file content
+	IncludeComments       bool   // extract source text in Lua and INI comments
+	MergeVariants         bool   // texts differing only in padding, NBSP or punctuation width share one translation
+	HashFoldWidth         bool   // cache and dedup keys fold full-width ASCII; see textutil.SetFoldWidth
+	LuaParseMode          string // "line" or "table"; see parser.LuaMode
+	LuaLiteralPercent     bool   // % in Lua files without a format call is literal; see parser.PercentIsLiteral
+	TermMatchMode         string // "substring", "longest" or "segmented"; see segment.Mode
+	TermSegmentWordsFile  string // optional word list, one per line, segmenting texts in "segmented" mode
+	SourceLang            string
+	TargetLang            string
+	SourceScripts         []string // Unicode scripts identifying source text; empty means the source language's default
+	INICommentPrefixes    []string // line prefixes marking INI comments; empty means ";" and "#"
+	INIKeySections        []string // INI sections whose key names are translated, as section[:keys|both]
+	ParserOverrides       []string // "glob=parser" parser choices overriding the extension; see filewalker.ParserRule
+	SeedQualities         []string // seed quality labels used in prompts; empty means every seed
+	EmbeddingWeights      []string // "glob=weight" ranking weights of ingested texts; see rag.WeightRule
+	CandidateFactor       int      // vector search fetches RetrievalTopK × this before filtering
+	MinSimilarity         float64  // similar texts scoring below this are dropped
+	SeedWeight            float64  // ranking weight of seed embeddings; ingested texts default to 1
+	DedupNormalized       bool     // similar texts differing only in padding/punctuation count as duplicates
+	InputPricePerMTok     float64  // USD per million input tokens, used by `estimate`
+	OutputPricePerMTok    float64  // USD per million output tokens, used by `estimate`
 }
 
-func Load() *Config {
-	if err := godotenv.Load(); err != nil {
-		log.Warn().Msg("No .env file found, using environment variables")
+// Load reads settings from env files and the process environment. Files override
+// the process environment, and later files override earlier ones: .env, then
+// .env.<APP_ENV> when APP_ENV is set, then envFile when non-empty.
+func Load(envFile string) (*Config, error) {
+	if err := loadEnvFiles(envFile); err != nil {
+		return nil, err
 	}
 
 	return &Config{
@@ -34,11 +95,202 @@ func Load() *Config {
 		Neo4jUser:             getEnv("NEO4J_USER", "neo4j"),
 		Neo4jPassword:         getEnv("NEO4J_PASSWORD", "password"),
 		WorkerCount:           getEnvInt("WORKER_COUNT", 8),
+		Neo4jMaxConcurrent:    getEnvInt("NEO4J_MAX_CONCURRENT", 4),
 		BatchSize:             getEnvInt("BATCH_SIZE", 10),
+		BatchSizeMin:          getEnvInt("BATCH_SIZE_MIN", 1),
+		BatchSizeMax:          getEnvInt("BATCH_SIZE_MAX", 0),
 		MaxConcurrentAPICalls: getEnvInt("MAX_CONCURRENT_API_CALLS", 5),
+		HTTPProxyURL:          getEnv("API_PROXY_URL", ""),
+		CABundleFile:          getEnv("API_CA_BUNDLE", ""),
+		TLSInsecure:           getEnvBool("API_TLS_INSECURE_SKIP_VERIFY", false),
+		APIMaxRetries:         getEnvInt("API_MAX_RETRIES", 2),
 		EmbeddingModel:        getEnv("EMBEDDING_MODEL", "text-embedding-004"),
 		EmbeddingDimensions:   getEnvInt("EMBEDDING_DIMENSIONS", 768),
+		EmbeddingTimeout:      getEnvDuration("EMBEDDING_TIMEOUT", 60*time.Second),
 		TranslationModel:      getEnv("TRANSLATION_MODEL", "gemini-2.5-flash"),
+		TranslationTimeout:    getEnvDuration("TRANSLATION_TIMEOUT", 120*time.Second),
+		FallbackModel:         getEnv("TRANSLATION_MODEL_FALLBACK", ""),
+		RetrievalTopK:         getEnvInt("RETRIEVAL_TOP_K", 3),
+		CandidateFactor:       getEnvInt("RETRIEVAL_CANDIDATE_FACTOR", 5),
+		MinSimilarity:         getEnvFloat("RETRIEVAL_MIN_SIMILARITY", 0),
+		DedupNormalized:       getEnvBool("RETRIEVAL_DEDUP_NORMALIZED", false),
+		FailureThreshold:      getEnvInt("MAX_CONSECUTIVE_FAILURES", 5),
+		SeedStrictness:        getEnv("SEED_STRICTNESS", "off"),
+		BatchContextItems:     getEnvInt("BATCH_CONTEXT_ITEMS", 10),
+		SentenceSplitRunes:    getEnvInt("SENTENCE_SPLIT_RUNES", 0),
+		MissingTermEndpoints:  getEnv("GRAPH_MISSING_ENDPOINTS", "report"),
+		StreamThresholdMB:     getEnvInt("TXT_STREAM_THRESHOLD_MB", 64),
+		TSVColumnsFile:        getEnv("TSV_COLUMNS_FILE", ""),
+		NumberUnitsFile:       getEnv("NUMBER_UNITS_FILE", ""),
+		RejectRules:           getEnv("REJECT_TRANSLATIONS", "empty,unchanged,refusal"),
+		RefusalPatternsFile:   getEnv("REFUSAL_PATTERNS_FILE", ""),
+		TSVHeaderMode:         getEnv("TSV_HEADER_MODE", "never"),
+		OutputSuffix:          getEnv("OUTPUT_SUFFIX", ""),
+		SniffContent:          getEnvBool("SNIFF_FILE_CONTENT", false),
+		IncludeComments:       getEnvBool("INCLUDE_COMMENTS", false),
+		MergeVariants:         getEnvBool("MERGE_TEXT_VARIANTS", true),
+		HashFoldWidth:         getEnvBool("HASH_FOLD_WIDTH", false),
+		LuaParseMode:          getEnv("LUA_PARSE_MODE", "line"),
+		LuaLiteralPercent:     getEnvBool("LUA_LITERAL_PERCENT", false),
+		TermMatchMode:         getEnv("TERM_MATCH_MODE", string(segment.Substring)),
+		TermSegmentWordsFile:  getEnv("TERM_SEGMENT_WORDS_FILE", ""),
+		SourceLang:            getEnv("SOURCE_LANG", language.DefaultSource),
+		TargetLang:            getEnv("TARGET_LANG", language.DefaultTarget),
+		SourceScripts:         getEnvList("SOURCE_SCRIPTS"),
+		INICommentPrefixes:    getEnvList("INI_COMMENT_PREFIXES"),
+		INIKeySections:        getEnvList("INI_KEY_SECTIONS"),
+		ParserOverrides:       getEnvList("PARSER_OVERRIDES"),
+		SeedQualities:         getEnvList("SEED_QUALITIES"),
+		EmbeddingWeights:      getEnvList("EMBEDDING_WEIGHTS"),
+		SeedWeight:            getEnvFloat("SEED_EMBEDDING_WEIGHT", 1.2),
+		InputPricePerMTok:     getEnvFloat("TRANSLATION_INPUT_PRICE_PER_MTOK", 0.30),
+		OutputPricePerMTok:    getEnvFloat("TRANSLATION_OUTPUT_PRICE_PER_MTOK", 2.50),
+	}, nil
+}
+
+// loadEnvFiles applies env files in increasing order of precedence. A missing
+// .env is not an error, but a named or explicit file must exist.
+func loadEnvFiles(envFile string) error {
+	loaded := 0
+	if err := godotenv.Overload(".env"); err == nil {
+		loaded++
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("load .env: %w", err)
+	}
+
+	// APP_ENV may itself come from .env, so it is read after .env is applied.
+	if appEnv := os.Getenv("APP_ENV"); appEnv != "" {
+		if err := godotenv.Overload(".env." + appEnv); err != nil {
+			return fmt.Errorf("load env file for APP_ENV=%s: %w", appEnv, err)
+		}
+		loaded++
+		log.Info().Str("file", ".env."+appEnv).Msg("Loaded environment file")
+	}
+
+	if envFile != "" {
+		if err := godotenv.Overload(envFile); err != nil {
+			return fmt.Errorf("load env file: %w", err)
+		}
+		loaded++
+		log.Info().Str("file", envFile).Msg("Loaded environment file")
+	}
+
+	if loaded == 0 {
+		log.Warn().Msg("No .env file found, using environment variables")
+	}
+	return nil
+}
+
+// Validate checks settings after env and flag overrides are applied, clamping
+// values that have a safe upper bound.
+func (c *Config) Validate() error {
+	if _, err := language.Lookup(c.SourceLang); err != nil {
+		return fmt.Errorf("SOURCE_LANG: %w", err)
+	}
+	if _, err := language.Lookup(c.TargetLang); err != nil {
+		return fmt.Errorf("TARGET_LANG: %w", err)
+	}
+	if len(c.SourceScripts) > 0 {
+		if _, err := textutil.NewScriptDetector(c.SourceScripts...); err != nil {
+			return fmt.Errorf("SOURCE_SCRIPTS: %w", err)
+		}
+	}
+	if c.Neo4jMaxConcurrent < 1 {
+		return fmt.Errorf("NEO4J_MAX_CONCURRENT must be at least 1, got %d", c.Neo4jMaxConcurrent)
+	}
+	if c.WorkerCount < 1 {
+		return fmt.Errorf("worker count must be at least 1, got %d", c.WorkerCount)
+	}
+	if c.MaxConcurrentAPICalls < 1 {
+		return fmt.Errorf("max concurrent API calls must be at least 1, got %d", c.MaxConcurrentAPICalls)
+	}
+	if _, err := httpclient.NewTransport(c.HTTPOptions()); err != nil {
+		return fmt.Errorf("API transport: %w", err)
+	}
+	if c.TLSInsecure {
+		log.Warn().Msg("API certificate verification disabled by API_TLS_INSECURE_SKIP_VERIFY")
+	}
+	if c.APIMaxRetries < 0 {
+		return fmt.Errorf("API_MAX_RETRIES must not be negative, got %d", c.APIMaxRetries)
+	}
+	if c.TranslationTimeout <= 0 {
+		return fmt.Errorf("TRANSLATION_TIMEOUT must be a positive duration, got %s", c.TranslationTimeout)
+	}
+	if c.EmbeddingTimeout <= 0 {
+		return fmt.Errorf("EMBEDDING_TIMEOUT must be a positive duration, got %s", c.EmbeddingTimeout)
+	}
+	if c.MissingTermEndpoints != "report" && c.MissingTermEndpoints != "create" {
+		return fmt.Errorf("GRAPH_MISSING_ENDPOINTS must be report or create, got %q", c.MissingTermEndpoints)
+	}
+	if c.StreamThresholdMB < 0 {
+		return fmt.Errorf("TXT_STREAM_THRESHOLD_MB must not be negative, got %d", c.StreamThresholdMB)
+	}
+	switch c.TSVHeaderMode {
+	case "never", "always", "auto":
+	default:
+		return fmt.Errorf("TSV_HEADER_MODE must be never, always or auto, got %q", c.TSVHeaderMode)
+	}
+	if strings.ContainsAny(c.OutputSuffix, `/\`) {
+		return fmt.Errorf("OUTPUT_SUFFIX must not contain a path separator, got %q", c.OutputSuffix)
+	}
+	if c.LuaParseMode != "line" && c.LuaParseMode != "table" {
+		return fmt.Errorf("LUA_PARSE_MODE must be line or table, got %q", c.LuaParseMode)
+	}
+	if _, err := segment.ParseMode(c.TermMatchMode); err != nil {
+		return fmt.Errorf("TERM_MATCH_MODE: %w", err)
+	}
+	switch c.SeedStrictness {
+	case "off", "soft", "hard":
+	default:
+		return fmt.Errorf("SEED_STRICTNESS must be off, soft or hard, got %q", c.SeedStrictness)
+	}
+	if c.FailureThreshold < 0 {
+		return fmt.Errorf("MAX_CONSECUTIVE_FAILURES must not be negative, got %d", c.FailureThreshold)
+	}
+	if c.BatchSize < 1 {
+		return fmt.Errorf("BATCH_SIZE must be at least 1, got %d", c.BatchSize)
+	}
+	if c.BatchSizeMax > 0 {
+		if c.BatchSizeMin < 1 {
+			return fmt.Errorf("BATCH_SIZE_MIN must be at least 1, got %d", c.BatchSizeMin)
+		}
+		if c.BatchSize < c.BatchSizeMin || c.BatchSize > c.BatchSizeMax {
+			return fmt.Errorf("BATCH_SIZE %d must lie between BATCH_SIZE_MIN %d and BATCH_SIZE_MAX %d", c.BatchSize, c.BatchSizeMin, c.BatchSizeMax)
+		}
+	} else if c.BatchSizeMax < 0 {
+		return fmt.Errorf("BATCH_SIZE_MAX must not be negative, got %d", c.BatchSizeMax)
+	}
+	if c.CandidateFactor < 1 {
+		return fmt.Errorf("RETRIEVAL_CANDIDATE_FACTOR must be at least 1, got %d", c.CandidateFactor)
+	}
+	if c.MinSimilarity < 0 || c.MinSimilarity > 1 {
+		return fmt.Errorf("RETRIEVAL_MIN_SIMILARITY must be between 0 and 1, got %g", c.MinSimilarity)
+	}
+	if c.SeedWeight <= 0 {
+		return fmt.Errorf("SEED_EMBEDDING_WEIGHT must be positive, got %g", c.SeedWeight)
+	}
+	if c.BatchContextItems < 0 {
+		return fmt.Errorf("BATCH_CONTEXT_ITEMS must not be negative, got %d", c.BatchContextItems)
+	}
+	if c.SentenceSplitRunes < 0 {
+		return fmt.Errorf("SENTENCE_SPLIT_RUNES must not be negative, got %d", c.SentenceSplitRunes)
+	}
+	if c.RetrievalTopK < 1 {
+		return fmt.Errorf("retrieval top-k must be at least 1, got %d", c.RetrievalTopK)
+	}
+	if c.RetrievalTopK > MaxRetrievalTopK {
+		log.Warn().Int("requested", c.RetrievalTopK).Int("max", MaxRetrievalTopK).Msg("Retrieval top-k capped")
+		c.RetrievalTopK = MaxRetrievalTopK
+	}
+	return nil
+}
+
+// HTTPOptions returns the transport settings of API requests.
+func (c *Config) HTTPOptions() httpclient.Options {
+	return httpclient.Options{
+		ProxyURL:           c.HTTPProxyURL,
+		CAFile:             c.CABundleFile,
+		InsecureSkipVerify: c.TLSInsecure,
 	}
 }
 
@@ -49,6 +301,29 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+// getEnvList reads a comma-separated list, dropping empty items.
+func getEnvList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fallback
+	}
+	return b
+}
+
 func getEnvInt(key string, fallback int) int {
 	v := os.Getenv(key)
 	if v == "" {
@@ -60,3 +335,27 @@ func getEnvInt(key string, fallback int) int {
 	}
 	return n
 }
+
+func getEnvFloat(key string, fallback float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return fallback
+	}
+	return f
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return fallback
+	}
+	return d
+}
@@ -9,6 +9,18 @@ import (
 	"context"
 )
 
+const deleteCachedTranslationsByHash = `-- name: DeleteCachedTranslationsByHash :execrows
+DELETE FROM translation_cache WHERE hash = ANY($1::text[])
+`
+
+func (q *Queries) DeleteCachedTranslationsByHash(ctx context.Context, dollar_1 []string) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteCachedTranslationsByHash, dollar_1)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const getCachedTranslation = `-- name: GetCachedTranslation :one
 SELECT translated FROM translation_cache WHERE hash = $1
 `
@@ -49,6 +61,36 @@ func (q *Queries) ListAllCachedTranslations(ctx context.Context) ([]ListAllCache
 	return items, nil
 }
 
+const listCachedTranslationPairs = `-- name: ListCachedTranslationPairs :many
+SELECT hash, source, translated FROM translation_cache
+`
+
+type ListCachedTranslationPairsRow struct {
+	Hash       string `json:"hash"`
+	Source     string `json:"source"`
+	Translated string `json:"translated"`
+}
+
+func (q *Queries) ListCachedTranslationPairs(ctx context.Context) ([]ListCachedTranslationPairsRow, error) {
+	rows, err := q.db.Query(ctx, listCachedTranslationPairs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListCachedTranslationPairsRow{}
+	for rows.Next() {
+		var i ListCachedTranslationPairsRow
+		if err := rows.Scan(&i.Hash, &i.Source, &i.Translated); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const upsertCachedTranslation = `-- name: UpsertCachedTranslation :exec
 INSERT INTO translation_cache (hash, source, translated)
 VALUES ($1, $2, $3)
@@ -12,6 +12,18 @@ import (
 	"github.com/pgvector/pgvector-go"
 )
 
+const deleteEmbeddingsByHash = `-- name: DeleteEmbeddingsByHash :execrows
+DELETE FROM embeddings WHERE hash = ANY($1::text[])
+`
+
+func (q *Queries) DeleteEmbeddingsByHash(ctx context.Context, dollar_1 []string) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteEmbeddingsByHash, dollar_1)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const getEmbeddingByHash = `-- name: GetEmbeddingByHash :one
 SELECT id, hash, source, context, file_path, created_at
 FROM embeddings
@@ -41,36 +53,79 @@ func (q *Queries) GetEmbeddingByHash(ctx context.Context, hash string) (GetEmbed
 	return i, err
 }
 
-const insertEmbeddingWithVector = `-- name: InsertEmbeddingWithVector :exec
-INSERT INTO embeddings (hash, source, context, file_path, embedding)
-VALUES ($1, $2, $3, $4, $5::vector)
-ON CONFLICT (hash) DO NOTHING
+const getEmbeddingVectorByHash = `-- name: GetEmbeddingVectorByHash :one
+SELECT embedding
+FROM embeddings
+WHERE hash = $1 AND embedding IS NOT NULL
 `
 
-type InsertEmbeddingWithVectorParams struct {
-	Hash     string          `json:"hash"`
-	Source   string          `json:"source"`
-	Context  string          `json:"context"`
-	FilePath string          `json:"file_path"`
-	Column5  pgvector.Vector `json:"column_5"`
+func (q *Queries) GetEmbeddingVectorByHash(ctx context.Context, hash string) (pgvector.Vector, error) {
+	row := q.db.QueryRow(ctx, getEmbeddingVectorByHash, hash)
+	var embedding pgvector.Vector
+	err := row.Scan(&embedding)
+	return embedding, err
 }
 
-func (q *Queries) InsertEmbeddingWithVector(ctx context.Context, arg InsertEmbeddingWithVectorParams) error {
-	_, err := q.db.Exec(ctx, insertEmbeddingWithVector,
-		arg.Hash,
-		arg.Source,
-		arg.Context,
-		arg.FilePath,
-		arg.Column5,
-	)
-	return err
+const listEmbeddingSources = `-- name: ListEmbeddingSources :many
+SELECT hash, source FROM embeddings
+`
+
+type ListEmbeddingSourcesRow struct {
+	Hash   string `json:"hash"`
+	Source string `json:"source"`
+}
+
+func (q *Queries) ListEmbeddingSources(ctx context.Context) ([]ListEmbeddingSourcesRow, error) {
+	rows, err := q.db.Query(ctx, listEmbeddingSources)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListEmbeddingSourcesRow{}
+	for rows.Next() {
+		var i ListEmbeddingSourcesRow
+		if err := rows.Scan(&i.Hash, &i.Source); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listExistingEmbeddingHashes = `-- name: ListExistingEmbeddingHashes :many
+SELECT hash
+FROM embeddings
+WHERE hash = ANY($1::text[]) AND embedding IS NOT NULL
+`
+
+func (q *Queries) ListExistingEmbeddingHashes(ctx context.Context, dollar_1 []string) ([]string, error) {
+	rows, err := q.db.Query(ctx, listExistingEmbeddingHashes, dollar_1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		items = append(items, hash)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const searchSimilarEmbeddings = `-- name: SearchSimilarEmbeddings :many
-SELECT source, context, (1 - (embedding <=> $1::vector))::float8 AS similarity
+SELECT source, context, weight, (1 - (embedding <=> $1::vector))::float8 AS similarity
 FROM embeddings
 WHERE embedding IS NOT NULL
-ORDER BY embedding <=> $1::vector
+ORDER BY embedding <=> $1::vector, hash
 LIMIT $2
 `
 
@@ -82,6 +137,7 @@ type SearchSimilarEmbeddingsParams struct {
 type SearchSimilarEmbeddingsRow struct {
 	Source     string  `json:"source"`
 	Context    string  `json:"context"`
+	Weight     float64 `json:"weight"`
 	Similarity float64 `json:"similarity"`
 }
 
@@ -94,7 +150,7 @@ func (q *Queries) SearchSimilarEmbeddings(ctx context.Context, arg SearchSimilar
 	items := []SearchSimilarEmbeddingsRow{}
 	for rows.Next() {
 		var i SearchSimilarEmbeddingsRow
-		if err := rows.Scan(&i.Source, &i.Context, &i.Similarity); err != nil {
+		if err := rows.Scan(&i.Source, &i.Context, &i.Weight, &i.Similarity); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -104,3 +160,34 @@ func (q *Queries) SearchSimilarEmbeddings(ctx context.Context, arg SearchSimilar
 	}
 	return items, nil
 }
+
+const upsertEmbeddingWithVector = `-- name: UpsertEmbeddingWithVector :exec
+INSERT INTO embeddings (hash, source, context, file_path, embedding, weight)
+VALUES ($1, $2, $3, $4, $5::vector, $6)
+ON CONFLICT (hash) DO UPDATE SET
+    context = EXCLUDED.context,
+    file_path = EXCLUDED.file_path,
+    embedding = EXCLUDED.embedding,
+    weight = EXCLUDED.weight
+`
+
+type UpsertEmbeddingWithVectorParams struct {
+	Hash     string          `json:"hash"`
+	Source   string          `json:"source"`
+	Context  string          `json:"context"`
+	FilePath string          `json:"file_path"`
+	Column5  pgvector.Vector `json:"column_5"`
+	Weight   float64         `json:"weight"`
+}
+
+func (q *Queries) UpsertEmbeddingWithVector(ctx context.Context, arg UpsertEmbeddingWithVectorParams) error {
+	_, err := q.db.Exec(ctx, upsertEmbeddingWithVector,
+		arg.Hash,
+		arg.Source,
+		arg.Context,
+		arg.FilePath,
+		arg.Column5,
+		arg.Weight,
+	)
+	return err
+}
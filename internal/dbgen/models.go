@@ -17,6 +17,7 @@ type Embedding struct {
 	FilePath  string             `json:"file_path"`
 	Embedding pgvector_go.Vector `json:"embedding"`
 	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	Weight    float64            `json:"weight"`
 }
 
 type SeedTranslation struct {
@@ -29,6 +30,8 @@ type SeedTranslation struct {
 	IsSeed         bool               `json:"is_seed"`
 	CreatedAt      pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt      pgtype.Timestamptz `json:"updated_at"`
+	Provenance     string             `json:"provenance"`
+	Quality        string             `json:"quality"`
 }
 
 type TranslationCache struct {
@@ -9,10 +9,61 @@ import (
 	"context"
 
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const findSeedTranslationsForText = `-- name: FindSeedTranslationsForText :many
+SELECT source_text, translated_text
+FROM seed_translations
+WHERE is_seed = TRUE
+  AND (strpos($1::text, source_text) > 0 OR source_text ILIKE '%' || $2::text || '%')
+  AND ($3::text[] IS NULL OR quality = ANY($3::text[]))
+ORDER BY length(source_text) DESC, source_text
+LIMIT $4
+`
+
+type FindSeedTranslationsForTextParams struct {
+	Text       string   `json:"text"`
+	Pattern    string   `json:"pattern"`
+	Qualities  []string `json:"qualities"`
+	MaxResults int32    `json:"max_results"`
+}
+
+type FindSeedTranslationsForTextRow struct {
+	SourceText     string `json:"source_text"`
+	TranslatedText string `json:"translated_text"`
+}
+
+// The ILIKE branch can use idx_seed_translations_source_trgm, but the strpos branch
+// tests every seed against the text and always scans the table. Seed corpora are
+// small enough for this; a larger one needs the seeds matched in the application.
+func (q *Queries) FindSeedTranslationsForText(ctx context.Context, arg FindSeedTranslationsForTextParams) ([]FindSeedTranslationsForTextRow, error) {
+	rows, err := q.db.Query(ctx, findSeedTranslationsForText,
+		arg.Text,
+		arg.Pattern,
+		arg.Qualities,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []FindSeedTranslationsForTextRow{}
+	for rows.Next() {
+		var i FindSeedTranslationsForTextRow
+		if err := rows.Scan(&i.SourceText, &i.TranslatedText); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getAllSeedTranslations = `-- name: GetAllSeedTranslations :many
-SELECT hash, source_text, translated_text, file, function_name, entity_type
+SELECT hash, source_text, translated_text, file, function_name, entity_type, provenance, quality
 FROM seed_translations
 WHERE is_seed = TRUE
 ORDER BY created_at
@@ -25,6 +76,8 @@ type GetAllSeedTranslationsRow struct {
 	File           string `json:"file"`
 	FunctionName   string `json:"function_name"`
 	EntityType     string `json:"entity_type"`
+	Provenance     string `json:"provenance"`
+	Quality        string `json:"quality"`
 }
 
 func (q *Queries) GetAllSeedTranslations(ctx context.Context) ([]GetAllSeedTranslationsRow, error) {
@@ -43,6 +96,8 @@ func (q *Queries) GetAllSeedTranslations(ctx context.Context) ([]GetAllSeedTrans
 			&i.File,
 			&i.FunctionName,
 			&i.EntityType,
+			&i.Provenance,
+			&i.Quality,
 		); err != nil {
 			return nil, err
 		}
@@ -55,7 +110,7 @@ func (q *Queries) GetAllSeedTranslations(ctx context.Context) ([]GetAllSeedTrans
 }
 
 const getSeedTranslationsByEntityType = `-- name: GetSeedTranslationsByEntityType :many
-SELECT hash, source_text, translated_text, file, function_name, entity_type
+SELECT hash, source_text, translated_text, file, function_name, entity_type, provenance, quality
 FROM seed_translations
 WHERE is_seed = TRUE AND entity_type = $1
 ORDER BY created_at
@@ -68,6 +123,8 @@ type GetSeedTranslationsByEntityTypeRow struct {
 	File           string `json:"file"`
 	FunctionName   string `json:"function_name"`
 	EntityType     string `json:"entity_type"`
+	Provenance     string `json:"provenance"`
+	Quality        string `json:"quality"`
 }
 
 func (q *Queries) GetSeedTranslationsByEntityType(ctx context.Context, entityType string) ([]GetSeedTranslationsByEntityTypeRow, error) {
@@ -86,6 +143,71 @@ func (q *Queries) GetSeedTranslationsByEntityType(ctx context.Context, entityTyp
 			&i.File,
 			&i.FunctionName,
 			&i.EntityType,
+			&i.Provenance,
+			&i.Quality,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchSeedTranslations = `-- name: SearchSeedTranslations :many
+SELECT hash, source_text, translated_text, file, function_name, entity_type, provenance, quality
+FROM seed_translations
+WHERE is_seed = TRUE
+  AND (source_text ILIKE '%' || $1::text || '%' OR translated_text ILIKE '%' || $1::text || '%')
+  AND ($2::text IS NULL OR entity_type = $2::text)
+  AND ($3::text IS NULL OR quality = $3::text)
+ORDER BY created_at
+LIMIT $4
+`
+
+type SearchSeedTranslationsParams struct {
+	Query      string      `json:"query"`
+	EntityType pgtype.Text `json:"entity_type"`
+	Quality    pgtype.Text `json:"quality"`
+	MaxResults int32       `json:"max_results"`
+}
+
+type SearchSeedTranslationsRow struct {
+	Hash           string `json:"hash"`
+	SourceText     string `json:"source_text"`
+	TranslatedText string `json:"translated_text"`
+	File           string `json:"file"`
+	FunctionName   string `json:"function_name"`
+	EntityType     string `json:"entity_type"`
+	Provenance     string `json:"provenance"`
+	Quality        string `json:"quality"`
+}
+
+func (q *Queries) SearchSeedTranslations(ctx context.Context, arg SearchSeedTranslationsParams) ([]SearchSeedTranslationsRow, error) {
+	rows, err := q.db.Query(ctx, searchSeedTranslations,
+		arg.Query,
+		arg.EntityType,
+		arg.Quality,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SearchSeedTranslationsRow{}
+	for rows.Next() {
+		var i SearchSeedTranslationsRow
+		if err := rows.Scan(
+			&i.Hash,
+			&i.SourceText,
+			&i.TranslatedText,
+			&i.File,
+			&i.FunctionName,
+			&i.EntityType,
+			&i.Provenance,
+			&i.Quality,
 		); err != nil {
 			return nil, err
 		}
@@ -98,13 +220,15 @@ func (q *Queries) GetSeedTranslationsByEntityType(ctx context.Context, entityTyp
 }
 
 const upsertSeedTranslation = `-- name: UpsertSeedTranslation :execresult
-INSERT INTO seed_translations (hash, source_text, translated_text, file, function_name, entity_type)
-VALUES ($1, $2, $3, $4, $5, $6)
+INSERT INTO seed_translations (hash, source_text, translated_text, file, function_name, entity_type, provenance, quality)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 ON CONFLICT (hash) DO UPDATE SET
     translated_text = EXCLUDED.translated_text,
     file = EXCLUDED.file,
     function_name = EXCLUDED.function_name,
     entity_type = EXCLUDED.entity_type,
+    provenance = EXCLUDED.provenance,
+    quality = EXCLUDED.quality,
     updated_at = NOW()
 `
 
@@ -115,6 +239,8 @@ type UpsertSeedTranslationParams struct {
 	File           string `json:"file"`
 	FunctionName   string `json:"function_name"`
 	EntityType     string `json:"entity_type"`
+	Provenance     string `json:"provenance"`
+	Quality        string `json:"quality"`
 }
 
 func (q *Queries) UpsertSeedTranslation(ctx context.Context, arg UpsertSeedTranslationParams) (pgconn.CommandTag, error) {
@@ -125,5 +251,7 @@ func (q *Queries) UpsertSeedTranslation(ctx context.Context, arg UpsertSeedTrans
 		arg.File,
 		arg.FunctionName,
 		arg.EntityType,
+		arg.Provenance,
+		arg.Quality,
 	)
 }
@@ -0,0 +1,85 @@
+package filewalker
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"rag-translator/internal/parser"
+)
+
+// ColumnRule selects the TSV columns to translate in files matching Pattern.
+type ColumnRule struct {
+	// Pattern is a path.Match glob. Patterns containing "/" match the path relative
+	// to the walk root; others match the file name.
+	Pattern string
+	Columns parser.ColumnSelection
+}
+
+// matches reports whether the rule applies to a file at relPath (slash-separated).
+func (r ColumnRule) matches(relPath string) bool {
+	return matchPattern(r.Pattern, relPath)
+}
+
+// LoadColumnRules reads a column selection file with one rule per line:
+//
+//	<glob><TAB>only|skip<TAB><col>,<col>,...
+//
+// Columns are 0-based. Blank lines and lines starting with # are ignored. When
+// several rules match a file, the first one wins.
+func LoadColumnRules(filePath string) ([]ColumnRule, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("open column rules file: %w", err)
+	}
+	defer file.Close()
+
+	var rules []ColumnRule
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("column rules line %d: expected glob<TAB>only|skip<TAB>columns", lineNum)
+		}
+		pattern := strings.TrimSpace(fields[0])
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("column rules line %d: bad pattern %q: %w", lineNum, pattern, err)
+		}
+
+		cols := make(map[int]bool)
+		for _, c := range strings.Split(fields[2], ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(c))
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("column rules line %d: bad column %q", lineNum, c)
+			}
+			cols[n] = true
+		}
+
+		rule := ColumnRule{Pattern: pattern}
+		switch strings.TrimSpace(fields[1]) {
+		case "only":
+			rule.Columns.Only = cols
+		case "skip":
+			rule.Columns.Skip = cols
+		default:
+			return nil, fmt.Errorf("column rules line %d: mode must be only or skip", lineNum)
+		}
+		rules = append(rules, rule)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan column rules file: %w", err)
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,79 @@
+package filewalker
+
+import (
+	"bufio"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GlossaryFileName is the per-directory glossary file. Its terms apply to every
+// file in that directory and below, taking precedence over the global terminology
+// and over glossaries of parent directories.
+const GlossaryFileName = "glossary.tsv"
+
+// Glossary holds the terminology overrides that apply to a file.
+type Glossary struct {
+	// Dir is the nearest directory with a glossary file.
+	Dir string
+	// Terms maps source terms to preferred translations, already layered over
+	// parent directory glossaries.
+	Terms map[string]string
+}
+
+// LoadGlossary reads a glossary file with one source<TAB>target term per line.
+// Blank lines and lines starting with # are ignored.
+func LoadGlossary(filePath string) (map[string]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("open glossary file: %w", err)
+	}
+	defer file.Close()
+
+	terms := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		source, target, found := strings.Cut(line, "\t")
+		source, target = strings.TrimSpace(source), strings.TrimSpace(target)
+		if !found || source == "" || target == "" {
+			return nil, fmt.Errorf("glossary %s line %d: expected source<TAB>target", filePath, lineNum)
+		}
+		terms[source] = target
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan glossary file: %w", err)
+	}
+
+	return terms, nil
+}
+
+// layerGlossary returns the glossary for dir: parent with the terms of dir's own
+// glossary file on top, or parent itself when dir has none.
+func layerGlossary(dir string, parent *Glossary) (*Glossary, error) {
+	glossaryPath := filepath.Join(dir, GlossaryFileName)
+	if _, err := os.Stat(glossaryPath); err != nil {
+		return parent, nil
+	}
+
+	own, err := LoadGlossary(glossaryPath)
+	if err != nil {
+		return nil, err
+	}
+
+	terms := make(map[string]string)
+	if parent != nil {
+		maps.Copy(terms, parent.Terms)
+	}
+	maps.Copy(terms, own)
+	return &Glossary{Dir: dir, Terms: terms}, nil
+}
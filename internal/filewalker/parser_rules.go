@@ -0,0 +1,66 @@
+package filewalker
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// parserExtensions maps the parser names accepted by parser rules to the
+// extension whose parser they select.
+var parserExtensions = map[string]string{
+	"lua": ".lua",
+	"ini": ".ini",
+	"txt": ".txt",
+	"tsv": ".txt",
+	"po":  ".po",
+}
+
+// ParserRule makes files matching Pattern parse with the named parser whatever
+// their extension, e.g. *.dat as tsv or scripts/raw/*.lua as txt.
+type ParserRule struct {
+	// Pattern is a path.Match glob. Patterns containing "/" match the path relative
+	// to the walk root; others match the file name.
+	Pattern string
+	Parser  string
+}
+
+// ParseParserRules parses "glob=parser" items. Parser names are lua, ini, txt,
+// tsv (the txt parser, which splits tabbed lines into columns) and po.
+func ParseParserRules(items []string) ([]ParserRule, error) {
+	rules := make([]ParserRule, 0, len(items))
+	for _, item := range items {
+		pattern, name, ok := strings.Cut(item, "=")
+		pattern = strings.TrimSpace(pattern)
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("parser rule %q: expected glob=parser", item)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("parser rule %q: bad pattern: %w", item, err)
+		}
+		if _, ok := parserExtensions[name]; !ok {
+			names := make([]string, 0, len(parserExtensions))
+			for n := range parserExtensions {
+				names = append(names, n)
+			}
+			slices.Sort(names)
+			return nil, fmt.Errorf("parser rule %q: unknown parser %q, expected one of %s", item, name, strings.Join(names, ", "))
+		}
+		rules = append(rules, ParserRule{Pattern: pattern, Parser: name})
+	}
+	return rules, nil
+}
+
+// matchPattern reports whether a path.Match glob matches a file at relPath
+// (slash-separated): patterns containing "/" match the whole path, others only
+// the file name.
+func matchPattern(pattern, relPath string) bool {
+	target := relPath
+	if !strings.Contains(pattern, "/") {
+		target = path.Base(relPath)
+	}
+	ok, _ := path.Match(pattern, target)
+	return ok
+}
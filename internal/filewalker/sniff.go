@@ -0,0 +1,73 @@
+package filewalker
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// sniffBytes is how much of a file is read to guess its type.
+const sniffBytes = 8 << 10
+
+// sniffableExtensions are the extensions whose files are commonly misnamed as one
+// another, and so may be re-dispatched by content.
+var sniffableExtensions = map[string]bool{
+	".lua": true,
+	".ini": true,
+	".txt": true,
+}
+
+var (
+	luaLinePattern     = regexp.MustCompile(`^(local\s|function\b|return\s*\{|end\b|--|\}|[\w.\[\]"']+\s*=\s*\{|\[[^\]]+\]\s*=)|,$`)
+	iniSectionPattern  = regexp.MustCompile(`^\[[^\]]+\]$`)
+	iniKeyValuePattern = regexp.MustCompile(`^[^=\s"'{}]+\s*=`)
+)
+
+// sniffExt guesses the type of the file at path from its first bytes and returns
+// the extension of the matching parser, or "" when the content is inconclusive.
+// Lua is recognized by its keywords and table syntax, INI by [section] headers
+// followed by key=value lines, and TSV text by a majority of tab-separated lines.
+func sniffExt(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	var lines, lua, sections, keyValues, tabbed int
+	scanner := bufio.NewScanner(io.LimitReader(f, sniffBytes))
+	for scanner.Scan() {
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines++
+		switch {
+		case luaLinePattern.MatchString(line):
+			lua++
+		case iniSectionPattern.MatchString(line):
+			sections++
+		case iniKeyValuePattern.MatchString(line):
+			keyValues++
+		}
+		if strings.Contains(raw, "\t") {
+			tabbed++
+		}
+	}
+	if lines == 0 {
+		return ""
+	}
+
+	switch {
+	case lua*5 >= lines:
+		return ".lua"
+	case sections > 0 && (sections+keyValues)*2 >= lines:
+		return ".ini"
+	case tabbed*2 >= lines:
+		return ".txt"
+	}
+	return ""
+}
@@ -16,11 +16,17 @@ var SupportedExtensions = map[string]bool{
 	".lua": true,
 	".ini": true,
 	".txt": true,
+	".po":  true,
+	".pot": true,
 }
 
 // Walker traverses directories and dispatches files to the correct parser.
 type Walker struct {
-	parsers []parser.Parser
+	parsers      []parser.Parser
+	columnRules  []ColumnRule
+	parserRules  []ParserRule
+	sniffContent bool
+	outputSuffix string
 }
 
 // NewWalker creates a Walker with default parsers.
@@ -30,18 +36,125 @@ func NewWalker() *Walker {
 			parser.NewLuaParser(),
 			parser.NewINIParser(),
 			parser.NewTXTParser(),
+			parser.NewPOParser(),
 		},
 	}
 }
 
+// SetStreamThreshold makes text files larger than n bytes parse and reconstruct
+// line by line. Zero disables streaming.
+func (w *Walker) SetStreamThreshold(n int64) {
+	for _, p := range w.parsers {
+		if txt, ok := p.(*parser.TXTParser); ok {
+			txt.SetStreamThreshold(n)
+		}
+	}
+}
+
+// SetTSVHeaderMode selects how the first row of TSV files is handled.
+func (w *Walker) SetTSVHeaderMode(mode parser.HeaderMode) {
+	for _, p := range w.parsers {
+		if txt, ok := p.(*parser.TXTParser); ok {
+			txt.SetHeaderMode(mode)
+		}
+	}
+}
+
+// SetLuaMode selects how Lua files are parsed.
+func (w *Walker) SetLuaMode(mode parser.LuaMode) {
+	for _, p := range w.parsers {
+		if lua, ok := p.(*parser.LuaParser); ok {
+			lua.SetMode(mode)
+		}
+	}
+}
+
+// SetINICommentPrefixes sets the line prefixes that mark INI comments. An empty
+// list keeps the parser defaults.
+func (w *Walker) SetINICommentPrefixes(prefixes []string) {
+	for _, p := range w.parsers {
+		if ini, ok := p.(*parser.INIParser); ok {
+			ini.SetCommentPrefixes(prefixes)
+		}
+	}
+}
+
+// SetINIKeySections makes the INI parser extract key names in the given sections.
+func (w *Walker) SetINIKeySections(sections map[string]parser.INIKeyMode) {
+	for _, p := range w.parsers {
+		if ini, ok := p.(*parser.INIParser); ok {
+			ini.SetKeySections(sections)
+		}
+	}
+}
+
+// SetIncludeComments makes the Lua and INI parsers extract text in comments.
+func (w *Walker) SetIncludeComments(enabled bool) {
+	for _, p := range w.parsers {
+		switch p := p.(type) {
+		case *parser.LuaParser:
+			p.SetIncludeComments(enabled)
+		case *parser.INIParser:
+			p.SetIncludeComments(enabled)
+		}
+	}
+}
+
+// SetColumnRules selects the TSV columns to translate per file. Matching files get
+// their own copy of the text parser configured with the rule's columns.
+func (w *Walker) SetColumnRules(rules []ColumnRule) {
+	w.columnRules = rules
+}
+
+// SetParserRules overrides the extension-based parser choice for files matching
+// a rule; the first matching rule wins. Matched files are walked even when their
+// extension is not in SupportedExtensions.
+func (w *Walker) SetParserRules(rules []ParserRule) {
+	w.parserRules = rules
+}
+
+// SetSniffContent makes the walker pick the parser for .lua, .ini and .txt files
+// by their content rather than their extension when the content is conclusive.
+// It costs a read of the start of every such file.
+func (w *Walker) SetSniffContent(enabled bool) {
+	w.sniffContent = enabled
+}
+
+// SetOutputSuffix makes directory walks skip files named like a translation
+// written next to its source with suffix, see OutputPath, so that earlier output
+// is not read back as input. An empty suffix skips nothing.
+func (w *Walker) SetOutputSuffix(suffix string) {
+	w.outputSuffix = suffix
+}
+
+// OutputPath returns the path of the translation of path written next to it:
+// suffix is inserted before the extension, so file.lua with ".vi" becomes
+// file.vi.lua.
+func OutputPath(path, suffix string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + suffix + ext
+}
+
+// isOutput reports whether path is named like a translation written with suffix.
+func isOutput(path, suffix string) bool {
+	if suffix == "" {
+		return false
+	}
+	return strings.HasSuffix(strings.TrimSuffix(path, filepath.Ext(path)), suffix)
+}
+
 // FileEntry represents a discovered file ready for processing.
 type FileEntry struct {
 	Path   string
 	Ext    string
 	Parser parser.Parser
+	// Glossary holds the directory glossary overrides for the file, nil if none.
+	Glossary *Glossary
 }
 
-// Walk discovers all supported files under the given root directory.
+// Walk discovers all supported files under the given root directory. A root that
+// is a single supported file yields just that file. Each entry carries the layered
+// glossary of its directory, see GlossaryFileName.
 func (w *Walker) Walk(root string) ([]FileEntry, error) {
 	root, err := filepath.Abs(root)
 	if err != nil {
@@ -53,10 +166,21 @@ func (w *Walker) Walk(root string) ([]FileEntry, error) {
 		return nil, fmt.Errorf("stat root: %w", err)
 	}
 	if !info.IsDir() {
-		return nil, fmt.Errorf("root is not a directory: %s", root)
+		entry, ok := w.entryFor(root, filepath.Base(root))
+		if !ok {
+			return nil, fmt.Errorf("unsupported file type: %s", root)
+		}
+		if entry.Glossary, err = layerGlossary(filepath.Dir(root), nil); err != nil {
+			return nil, err
+		}
+		log.Info().Str("file", root).Msg("Discovered single file")
+		return []FileEntry{entry}, nil
 	}
 
 	var entries []FileEntry
+	// Directories are visited before their contents, so a parent's glossary is
+	// always known when its children are reached.
+	glossaries := make(map[string]*Glossary)
 
 	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -65,23 +189,33 @@ func (w *Walker) Walk(root string) ([]FileEntry, error) {
 		}
 
 		if info.IsDir() {
+			g, err := layerGlossary(path, glossaries[filepath.Dir(path)])
+			if err != nil {
+				return err
+			}
+			if g != nil && g.Dir == path {
+				log.Info().Str("dir", path).Int("terms", len(g.Terms)).Msg("Loaded directory glossary")
+			}
+			glossaries[path] = g
 			return nil
 		}
 
-		ext := strings.ToLower(filepath.Ext(path))
-		if !SupportedExtensions[ext] {
+		// A parser rule such as *.tsv=tsv must not turn glossaries into input.
+		if info.Name() == GlossaryFileName {
+			return nil
+		}
+		if isOutput(path, w.outputSuffix) {
+			log.Debug().Str("path", path).Msg("Skipping translation output")
 			return nil
 		}
 
-		for _, p := range w.parsers {
-			if p.CanParse(ext) {
-				entries = append(entries, FileEntry{
-					Path:   path,
-					Ext:    ext,
-					Parser: p,
-				})
-				break
-			}
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			relPath = path
+		}
+		if entry, ok := w.entryFor(path, filepath.ToSlash(relPath)); ok {
+			entry.Glossary = glossaries[filepath.Dir(path)]
+			entries = append(entries, entry)
 		}
 
 		return nil
@@ -95,6 +229,56 @@ func (w *Walker) Walk(root string) ([]FileEntry, error) {
 	return entries, nil
 }
 
+// entryFor returns the entry for path if a parser rule or its extension selects a
+// parser. relPath is the slash-separated path relative to the walk root, used for
+// parser and column rules.
+func (w *Walker) entryFor(path, relPath string) (FileEntry, bool) {
+	ext := strings.ToLower(filepath.Ext(path))
+	parseAs := ext
+	override, overridden := w.parserRule(relPath)
+	if overridden {
+		parseAs = parserExtensions[override.Parser]
+		log.Debug().Str("file", path).Str("pattern", override.Pattern).Str("parser", override.Parser).Msg("Parser rule matched")
+	} else if !SupportedExtensions[ext] {
+		return FileEntry{}, false
+	} else if w.sniffContent && sniffableExtensions[ext] {
+		if sniffed := sniffExt(path); sniffed != "" && sniffed != ext {
+			log.Info().Str("file", path).Str("parser", strings.TrimPrefix(sniffed, ".")).Msg("Content does not match extension, using sniffed parser")
+			parseAs = sniffed
+		}
+	}
+	for _, p := range w.parsers {
+		if !p.CanParse(parseAs) {
+			continue
+		}
+		if txt, ok := p.(*parser.TXTParser); ok {
+			if overridden && override.Parser == "tsv" {
+				txt = txt.AsTSV()
+				p = txt
+			}
+			for _, rule := range w.columnRules {
+				if rule.matches(relPath) {
+					p = txt.WithColumns(rule.Columns)
+					break
+				}
+			}
+		}
+		log.Debug().Str("file", path).Str("parser", strings.TrimPrefix(parseAs, ".")).Msg("Selected parser")
+		return FileEntry{Path: path, Ext: ext, Parser: p}, true
+	}
+	return FileEntry{}, false
+}
+
+// parserRule returns the first parser rule matching relPath.
+func (w *Walker) parserRule(relPath string) (ParserRule, bool) {
+	for _, rule := range w.parserRules {
+		if matchPattern(rule.Pattern, relPath) {
+			return rule, true
+		}
+	}
+	return ParserRule{}, false
+}
+
 // ParseFile parses a single file using the appropriate parser.
 func (w *Walker) ParseFile(entry FileEntry) (*parser.ParseResult, error) {
 	return entry.Parser.Parse(entry.Path)
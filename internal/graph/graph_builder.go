@@ -2,7 +2,12 @@ package graph
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+
+	"rag-translator/internal/language"
+	"rag-translator/internal/worker"
 
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
 	"github.com/rs/zerolog/log"
@@ -12,7 +17,9 @@ import (
 type WuxiaTerm struct {
 	Chinese    string
 	Vietnamese string
-	Category   string // skill, item, character, location, faction, general
+	Category   string            // skill, item, character, location, faction, general
+	Registers  map[string]string // optional register → rendering, see RegisterFormal
+	Targets    map[string]string // optional target language code → rendering, for targets other than Vietnamese
 }
 
 // Relationship represents a directed edge in the knowledge graph.
@@ -24,27 +31,62 @@ type Relationship struct {
 	ToType      string
 }
 
+// TermProperty returns the Term node property holding the glossary rendering for a
+// target language. Vietnamese keeps the original "vietnamese" property; other
+// languages use "target_<code>", e.g. "target_th".
+func TermProperty(targetLang string) string {
+	base := language.Base(targetLang)
+	if base == "vi" {
+		return "vietnamese"
+	}
+	return "target_" + base
+}
+
+// MissingEndpointMode selects how SeedTerminology handles a relationship whose
+// endpoint term does not exist in the graph.
+type MissingEndpointMode string
+
+const (
+	// MissingEndpointsReport skips such relationships and reports every missing endpoint.
+	MissingEndpointsReport MissingEndpointMode = "report"
+	// MissingEndpointsCreate merges the missing endpoint as a placeholder Term so the edge is always created.
+	MissingEndpointsCreate MissingEndpointMode = "create"
+)
+
+// PlaceholderCategory is the category given to Term nodes created only as relationship endpoints.
+const PlaceholderCategory = "placeholder"
+
 // GraphBuilder seeds and updates the Neo4j knowledge graph.
 type GraphBuilder struct {
-	driver neo4j.DriverWithContext
+	driver           neo4j.DriverWithContext
+	missingEndpoints MissingEndpointMode
+	maxConcurrent    int // concurrent write transactions in AddEntitiesFromTexts
 }
 
 // NewGraphBuilder creates a new graph builder.
 func NewGraphBuilder(driver neo4j.DriverWithContext) *GraphBuilder {
-	return &GraphBuilder{driver: driver}
+	return &GraphBuilder{driver: driver, missingEndpoints: MissingEndpointsReport, maxConcurrent: 1}
+}
+
+// SetMaxConcurrent bounds how many write transactions AddEntitiesFromTexts runs at
+// once. Values below 1 are treated as 1.
+func (gb *GraphBuilder) SetMaxConcurrent(n int) {
+	gb.maxConcurrent = max(n, 1)
+}
+
+// SetMissingEndpointMode selects how relationships with a missing endpoint term are handled.
+func (gb *GraphBuilder) SetMissingEndpointMode(mode MissingEndpointMode) {
+	gb.missingEndpoints = mode
 }
 
 // EnsureSchema creates constraints and indexes on the Neo4j database.
 func (gb *GraphBuilder) EnsureSchema(ctx context.Context) error {
-	session := gb.driver.NewSession(ctx, neo4j.SessionConfig{})
-	defer session.Close(ctx)
-
 	constraints := []string{
 		"CREATE CONSTRAINT IF NOT EXISTS FOR (t:Term) REQUIRE t.chinese IS UNIQUE",
 	}
 
 	for _, c := range constraints {
-		if _, err := session.Run(ctx, c, nil); err != nil {
+		if err := WriteStatement(ctx, gb.driver, c, nil); err != nil {
 			return fmt.Errorf("create constraint: %w", err)
 		}
 	}
@@ -54,6 +96,8 @@ func (gb *GraphBuilder) EnsureSchema(ctx context.Context) error {
 }
 
 // SeedTerminology populates the knowledge graph with wuxia terminology for 剑侠世界2.
+// Terms and their relationships are written in one transaction, so a failure part
+// way through never leaves terms without their relationships.
 func (gb *GraphBuilder) SeedTerminology(ctx context.Context) error {
 	terms := getJianxiaTerminology()
 	relationships := getJianxiaRelationships()
@@ -61,79 +105,320 @@ func (gb *GraphBuilder) SeedTerminology(ctx context.Context) error {
 	session := gb.driver.NewSession(ctx, neo4j.SessionConfig{})
 	defer session.Close(ctx)
 
-	// Upsert terms.
-	for _, t := range terms {
-		_, err := session.Run(ctx, `
-			MERGE (t:Term {chinese: $chinese})
-			SET t.vietnamese = $vietnamese,
-			    t.category = $category
-		`, map[string]any{
-			"chinese":    t.Chinese,
-			"vietnamese": t.Vietnamese,
-			"category":   t.Category,
-		})
-		if err != nil {
-			return fmt.Errorf("upsert term %s: %w", t.Chinese, err)
+	var missing map[string]struct{}
+	var skipped int
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		// Upsert terms.
+		for _, t := range terms {
+			err := RunInTx(ctx, tx, `
+				MERGE (t:Term {chinese: $chinese})
+				SET t.vietnamese = $vietnamese,
+				    t.category = $category,
+				    t += $renderings
+			`, map[string]any{
+				"chinese":    t.Chinese,
+				"vietnamese": t.Vietnamese,
+				"category":   t.Category,
+				"renderings": t.renderingProperties(),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("upsert term %s: %w", t.Chinese, err)
+			}
 		}
-	}
 
-	log.Info().Int("terms", len(terms)).Msg("Seeded terminology nodes")
+		// Create relationships. The function may be retried, so reset what a
+		// previous attempt collected.
+		missing, skipped = make(map[string]struct{}), 0
+		for _, r := range relationships {
+			absent, err := gb.createRelationship(ctx, tx, r)
+			if err != nil {
+				return nil, fmt.Errorf("create relationship %s-[%s]->%s: %w", r.FromChinese, r.RelType, r.ToChinese, err)
+			}
+			if len(absent) > 0 {
+				skipped++
+			}
+			for _, term := range absent {
+				missing[term] = struct{}{}
+			}
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return fmt.Errorf("seed terminology: %w", err)
+	}
 
-	// Create relationships.
-	for _, r := range relationships {
-		_, err := session.Run(ctx, fmt.Sprintf(`
-			MATCH (a:Term {chinese: $from})
-			MATCH (b:Term {chinese: $to})
-			MERGE (a)-[:%s]->(b)
-		`, r.RelType), map[string]any{
-			"from": r.FromChinese,
-			"to":   r.ToChinese,
-		})
-		if err != nil {
-			log.Warn().Err(err).
-				Str("from", r.FromChinese).
-				Str("to", r.ToChinese).
-				Str("rel", r.RelType).
-				Msg("Failed to create relationship")
+	if len(missing) > 0 {
+		missingTerms := make([]string, 0, len(missing))
+		for term := range missing {
+			missingTerms = append(missingTerms, term)
 		}
+		sort.Strings(missingTerms)
+		log.Warn().
+			Strs("terms", missingTerms).
+			Int("skipped_relationships", skipped).
+			Msg("Relationships skipped because endpoint terms are missing")
 	}
 
-	log.Info().Int("relationships", len(relationships)).Msg("Seeded terminology relationships")
+	log.Info().
+		Int("terms", len(terms)).
+		Int("relationships", len(relationships)-skipped).
+		Str("missing_endpoints", string(gb.missingEndpoints)).
+		Msg("Seeded terminology")
 	return nil
 }
 
+// ImportRenderings stores the rendering of each term of renderings (source term →
+// rendering) for targetLang in TermProperty(targetLang), creating missing terms
+// with category "general". This loads the glossary of a target language other
+// than the built-in Vietnamese one. It returns how many terms it wrote.
+func (gb *GraphBuilder) ImportRenderings(ctx context.Context, targetLang string, renderings map[string]string) (int, error) {
+	property := TermProperty(targetLang)
+	rows := make([]map[string]any, 0, len(renderings))
+	for _, term := range SortedTermKeys(renderings) {
+		rows = append(rows, map[string]any{
+			"chinese":    term,
+			"renderings": map[string]any{property: renderings[term]},
+		})
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	err := WriteStatement(ctx, gb.driver, `
+		UNWIND $rows AS row
+		MERGE (t:Term {chinese: row.chinese})
+		ON CREATE SET t.category = 'general'
+		SET t += row.renderings
+	`, map[string]any{"rows": rows})
+	if err != nil {
+		return 0, fmt.Errorf("import %s renderings: %w", property, err)
+	}
+	return len(rows), nil
+}
+
+// createRelationship merges one relationship inside tx. In report mode it returns the
+// endpoint terms that do not exist, and no edge is created when any is missing.
+func (gb *GraphBuilder) createRelationship(ctx context.Context, tx neo4j.ManagedTransaction, r Relationship) ([]string, error) {
+	params := map[string]any{
+		"from": r.FromChinese,
+		"to":   r.ToChinese,
+	}
+
+	if gb.missingEndpoints == MissingEndpointsCreate {
+		params["from_category"] = placeholderCategory(r.FromType)
+		params["to_category"] = placeholderCategory(r.ToType)
+		return nil, RunInTx(ctx, tx, fmt.Sprintf(`
+			MERGE (a:Term {chinese: $from})
+			ON CREATE SET a.category = $from_category, a.placeholder = true
+			MERGE (b:Term {chinese: $to})
+			ON CREATE SET b.category = $to_category, b.placeholder = true
+			MERGE (a)-[:%s]->(b)
+		`, r.RelType), params)
+	}
+
+	result, err := tx.Run(ctx, fmt.Sprintf(`
+		OPTIONAL MATCH (a:Term {chinese: $from})
+		OPTIONAL MATCH (b:Term {chinese: $to})
+		FOREACH (_ IN CASE WHEN a IS NOT NULL AND b IS NOT NULL THEN [1] ELSE [] END |
+			MERGE (a)-[:%s]->(b))
+		RETURN a IS NOT NULL AS has_from, b IS NOT NULL AS has_to
+	`, r.RelType), params)
+	if err != nil {
+		return nil, err
+	}
+	record, err := result.Single(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var absent []string
+	if hasFrom, _ := record.Get("has_from"); hasFrom != true {
+		absent = append(absent, r.FromChinese)
+	}
+	if hasTo, _ := record.Get("has_to"); hasTo != true {
+		absent = append(absent, r.ToChinese)
+	}
+	return absent, nil
+}
+
+// placeholderCategory returns the category for an endpoint created on demand.
+func placeholderCategory(entityType string) string {
+	if entityType != "" {
+		return entityType
+	}
+	return PlaceholderCategory
+}
+
+// TextEntity is a parsed text to store as a TextNode.
+type TextEntity struct {
+	Text    string
+	File    string
+	Context string
+}
+
+// textEntityBatchSize is how many TextNodes one write transaction stores.
+const textEntityBatchSize = 500
+
 // AddEntityFromText extracts and stores game entities found in parsed text.
+// The text node and its term links are written in one transaction.
 func (gb *GraphBuilder) AddEntityFromText(ctx context.Context, text, filePath, context string) error {
+	return gb.addTextEntities(ctx, []TextEntity{{Text: text, File: filePath, Context: context}})
+}
+
+// AddEntitiesFromTexts stores entities as TextNodes linked to the terms they
+// contain. Entities are written in batches, each in one transaction, with at most
+// SetMaxConcurrent batches in flight. A failed batch does not stop the others;
+// the errors of all failed batches are returned joined.
+func (gb *GraphBuilder) AddEntitiesFromTexts(ctx context.Context, entities []TextEntity) error {
+	pool := worker.NewPool[[]TextEntity, struct{}](gb.maxConcurrent,
+		func(ctx context.Context, batch []TextEntity) (struct{}, error) {
+			return struct{}{}, gb.addTextEntities(ctx, batch)
+		},
+	)
+
+	failed := 0
+	var errs []error
+	for _, task := range pool.Execute(ctx, worker.Batch(entities, textEntityBatchSize)) {
+		if task.Err != nil {
+			failed += len(task.Input)
+			errs = append(errs, fmt.Errorf("store %d text nodes: %w", len(task.Input), task.Err))
+		}
+	}
+	log.Info().
+		Int("texts", len(entities)).
+		Int("failed", failed).
+		Int("concurrency", gb.maxConcurrent).
+		Msg("Stored text nodes")
+	return errors.Join(errs...)
+}
+
+// addTextEntities writes entities and their term links in one transaction.
+func (gb *GraphBuilder) addTextEntities(ctx context.Context, entities []TextEntity) error {
+	rows := make([]map[string]any, len(entities))
+	for i, e := range entities {
+		rows[i] = map[string]any{"text": e.Text, "file": e.File, "context": e.Context}
+	}
+
 	session := gb.driver.NewSession(ctx, neo4j.SessionConfig{})
 	defer session.Close(ctx)
 
-	// Store the text as a TextNode for reference.
-	_, err := session.Run(ctx, `
-		MERGE (t:TextNode {text: $text})
-		SET t.file = $file, t.context = $context
-	`, map[string]any{
-		"text":    text,
-		"file":    filePath,
-		"context": context,
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		// Store each text as a TextNode for reference.
+		err := RunInTx(ctx, tx, `
+			UNWIND $rows AS row
+			MERGE (t:TextNode {text: row.text})
+			SET t.file = row.file, t.context = row.context
+		`, map[string]any{"rows": rows})
+		if err != nil {
+			return nil, fmt.Errorf("add text nodes: %w", err)
+		}
+
+		// Link texts to any matching terms.
+		err = RunInTx(ctx, tx, `
+			UNWIND $rows AS row
+			MATCH (term:Term)
+			WHERE row.text CONTAINS term.chinese
+			MATCH (t:TextNode {text: row.text})
+			MERGE (t)-[:CONTAINS_TERM]->(term)
+		`, map[string]any{"rows": rows})
+		if err != nil {
+			return nil, fmt.Errorf("link texts to terms: %w", err)
+		}
+		return nil, nil
 	})
+	return err
+}
+
+// TextNodeTexts returns the text of every TextNode.
+func (gb *GraphBuilder) TextNodeTexts(ctx context.Context) ([]string, error) {
+	records, err := ReadRecords(ctx, gb.driver, `MATCH (t:TextNode) RETURN t.text AS text`, nil)
 	if err != nil {
-		return fmt.Errorf("add text node: %w", err)
+		return nil, fmt.Errorf("list text nodes: %w", err)
+	}
+
+	texts := make([]string, 0, len(records))
+	for _, record := range records {
+		text, _ := record.Get("text")
+		texts = append(texts, fmt.Sprintf("%v", text))
 	}
+	return texts, nil
+}
 
-	// Link text to any matching terms.
-	_, err = session.Run(ctx, `
-		MATCH (term:Term)
-		WHERE $text CONTAINS term.chinese
-		MATCH (t:TextNode {text: $text})
-		MERGE (t)-[:CONTAINS_TERM]->(term)
-	`, map[string]any{
-		"text": text,
+// DeleteTextNodes removes the TextNodes of texts along with their term links and
+// returns how many were deleted.
+func (gb *GraphBuilder) DeleteTextNodes(ctx context.Context, texts []string) (int, error) {
+	if len(texts) == 0 {
+		return 0, nil
+	}
+	session := gb.driver.NewSession(ctx, neo4j.SessionConfig{})
+	defer session.Close(ctx)
+
+	deleted, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		result, err := tx.Run(ctx, `
+			UNWIND $texts AS text
+			MATCH (t:TextNode {text: text})
+			DETACH DELETE t
+			RETURN count(*) AS deleted
+		`, map[string]any{"texts": texts})
+		if err != nil {
+			return nil, err
+		}
+		record, err := result.Single(ctx)
+		if err != nil {
+			return nil, err
+		}
+		n, _ := record.Get("deleted")
+		return n, nil
 	})
 	if err != nil {
-		return fmt.Errorf("link text to terms: %w", err)
+		return 0, fmt.Errorf("delete text nodes: %w", err)
 	}
+	n, _ := deleted.(int64)
+	return int(n), nil
+}
 
-	return nil
+// ReadRecords runs a read query in a managed transaction and returns all of its
+// records. The driver retries managed transactions on transient failures such as a
+// leader election or deadlock, so a cluster hiccup delays the query instead of
+// dropping its results.
+func ReadRecords(ctx context.Context, driver neo4j.DriverWithContext, cypher string, params map[string]any) ([]*neo4j.Record, error) {
+	session := driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	records, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		result, err := tx.Run(ctx, cypher, params)
+		if err != nil {
+			return nil, err
+		}
+		return result.Collect(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records.([]*neo4j.Record), nil
+}
+
+// WriteStatement runs a single write statement in a managed transaction, retried
+// on transient failures like ReadRecords.
+func WriteStatement(ctx context.Context, driver neo4j.DriverWithContext, cypher string, params map[string]any) error {
+	session := driver.NewSession(ctx, neo4j.SessionConfig{})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		return nil, RunInTx(ctx, tx, cypher, params)
+	})
+	return err
+}
+
+// RunInTx runs a statement inside a managed transaction and consumes its result,
+// so a server-side error surfaces at the statement rather than at commit.
+func RunInTx(ctx context.Context, tx neo4j.ManagedTransaction, cypher string, params map[string]any) error {
+	result, err := tx.Run(ctx, cypher, params)
+	if err != nil {
+		return err
+	}
+	_, err = result.Consume(ctx)
+	return err
 }
 
 // getJianxiaTerminology returns the complete terminology for 剑侠世界2.
@@ -160,7 +445,10 @@ func getJianxiaTerminology() []WuxiaTerm {
 		{Chinese: "强化", Vietnamese: "Cường hóa", Category: "gameplay"},
 		{Chinese: "等级", Vietnamese: "Cấp", Category: "gameplay"},
 		{Chinese: "技能", Vietnamese: "Kỹ năng", Category: "skill"},
-		{Chinese: "坐骑", Vietnamese: "Ngựa cưỡi", Category: "item"},
+		{Chinese: "坐骑", Vietnamese: "Ngựa cưỡi", Category: "item", Registers: map[string]string{
+			RegisterFormal:     "Tọa kỵ",
+			RegisterColloquial: "Thú cưỡi",
+		}},
 
 		// Exploration
 		{Chinese: "藏宝图", Vietnamese: "Bản đồ kho báu", Category: "item"},
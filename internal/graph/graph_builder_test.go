@@ -0,0 +1,162 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// flakyDriver fails the first statements it runs with err and then serves
+// records. Its sessions retry managed transactions on retryable errors, as the
+// driver does, so a test sees whether the work passed to them is safe to retry.
+type flakyDriver struct {
+	neo4j.DriverWithContext
+	failures int   // statements failing before the first success
+	err      error // error of a failing statement
+	records  []*neo4j.Record
+	runs     int // statements run
+	mode     neo4j.AccessMode
+}
+
+func (d *flakyDriver) NewSession(ctx context.Context, config neo4j.SessionConfig) neo4j.SessionWithContext {
+	d.mode = config.AccessMode
+	return flakySession{d: d}
+}
+
+type flakySession struct {
+	neo4j.SessionWithContext
+	d *flakyDriver
+}
+
+func (s flakySession) Close(ctx context.Context) error { return nil }
+
+func (s flakySession) ExecuteRead(ctx context.Context, work neo4j.ManagedTransactionWork, _ ...func(*neo4j.TransactionConfig)) (any, error) {
+	return s.execute(work)
+}
+
+func (s flakySession) ExecuteWrite(ctx context.Context, work neo4j.ManagedTransactionWork, _ ...func(*neo4j.TransactionConfig)) (any, error) {
+	return s.execute(work)
+}
+
+func (s flakySession) execute(work neo4j.ManagedTransactionWork) (any, error) {
+	const maxAttempts = 5
+	for attempt := 1; ; attempt++ {
+		result, err := work(flakyTx{d: s.d})
+		if err == nil || !neo4j.IsRetryable(err) || attempt == maxAttempts {
+			return result, err
+		}
+	}
+}
+
+type flakyTx struct {
+	neo4j.ManagedTransaction
+	d *flakyDriver
+}
+
+func (tx flakyTx) Run(ctx context.Context, cypher string, params map[string]any) (neo4j.ResultWithContext, error) {
+	tx.d.runs++
+	if tx.d.runs <= tx.d.failures {
+		return nil, tx.d.err
+	}
+	return flakyResult{records: tx.d.records}, nil
+}
+
+type flakyResult struct {
+	neo4j.ResultWithContext
+	records []*neo4j.Record
+}
+
+func (r flakyResult) Collect(ctx context.Context) ([]*neo4j.Record, error) { return r.records, nil }
+
+func (r flakyResult) Consume(ctx context.Context) (neo4j.ResultSummary, error) { return nil, nil }
+
+func TestTermProperty(t *testing.T) {
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"vi", "vietnamese"},
+		{"vi-VN", "vietnamese"},
+		{"th", "target_th"},
+		{"th-TH", "target_th"},
+	}
+	for _, tt := range tests {
+		if got := TermProperty(tt.target); got != tt.want {
+			t.Errorf("TermProperty(%q) = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestRenderingProperties(t *testing.T) {
+	term := WuxiaTerm{
+		Chinese:    "门派",
+		Vietnamese: "Môn phái",
+		Registers:  map[string]string{RegisterFormal: "Tông môn"},
+		Targets:    map[string]string{"th": "สำนัก", "en-US": "Sect"},
+	}
+	want := map[string]any{
+		"vietnamese_formal": "Tông môn",
+		"target_th":         "สำนัก",
+		"target_en":         "Sect",
+	}
+	if got := term.renderingProperties(); !maps.Equal(got, want) {
+		t.Errorf("renderingProperties() = %v, want %v", got, want)
+	}
+}
+
+func TestManagedTransactionRetry(t *testing.T) {
+	deadlock := &neo4j.Neo4jError{Code: "Neo.TransientError.Transaction.DeadlockDetected", Msg: "deadlock"}
+	syntax := &neo4j.Neo4jError{Code: "Neo.ClientError.Statement.SyntaxError", Msg: "bad query"}
+	tests := []struct {
+		name     string
+		failures int
+		err      error
+		want     []string
+		runs     int
+		wantErr  bool
+	}{
+		{"no failure", 0, nil, []string{"金币", "宝石"}, 1, false},
+		{"transient failures retried", 2, deadlock, []string{"金币", "宝石"}, 3, false},
+		{"retries exhausted", 10, deadlock, nil, 5, true},
+		{"client error not retried", 1, syntax, nil, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := []*neo4j.Record{
+				{Keys: []string{"text"}, Values: []any{"金币"}},
+				{Keys: []string{"text"}, Values: []any{"宝石"}},
+			}
+
+			d := &flakyDriver{failures: tt.failures, err: tt.err, records: records}
+			texts, err := NewGraphBuilder(d).TextNodeTexts(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TextNodeTexts err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("TextNodeTexts err = %v, want it to wrap %v", err, tt.err)
+			}
+			if !tt.wantErr && !slices.Equal(texts, tt.want) {
+				t.Errorf("texts = %q, want %q", texts, tt.want)
+			}
+			if d.runs != tt.runs {
+				t.Errorf("read ran %d times, want %d", d.runs, tt.runs)
+			}
+			if d.mode != neo4j.AccessModeRead {
+				t.Errorf("read session access mode = %v, want read", d.mode)
+			}
+
+			d = &flakyDriver{failures: tt.failures, err: tt.err}
+			err = WriteStatement(context.Background(), d, "CREATE INDEX IF NOT EXISTS FOR (t:Term) ON (t.category)", nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WriteStatement err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if d.runs != tt.runs {
+				t.Errorf("write ran %d times, want %d", d.runs, tt.runs)
+			}
+		})
+	}
+}
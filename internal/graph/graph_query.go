@@ -3,6 +3,11 @@ package graph
 import (
 	"context"
 	"fmt"
+	"sort"
+	"unicode/utf8"
+
+	"rag-translator/internal/language"
+	"rag-translator/internal/segment"
 
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
 	"github.com/rs/zerolog/log"
@@ -30,38 +35,58 @@ type QueryResult struct {
 
 // GraphQuerier queries the Neo4j knowledge graph for translation context.
 type GraphQuerier struct {
-	driver neo4j.DriverWithContext
+	driver       neo4j.DriverWithContext
+	matcher      *segment.Matcher // optional, nil means plain substring matching
+	termProperty string           // Term property holding the target-language rendering
 }
 
 // NewGraphQuerier creates a new graph querier.
 func NewGraphQuerier(driver neo4j.DriverWithContext) *GraphQuerier {
-	return &GraphQuerier{driver: driver}
+	return &GraphQuerier{driver: driver, termProperty: TermProperty(language.DefaultTarget)}
+}
+
+// SetTargetLanguage selects which target-language rendering of each Term is returned.
+func (gq *GraphQuerier) SetTargetLanguage(code string) {
+	gq.termProperty = TermProperty(code)
+}
+
+// SetTermMatcher selects how terms are matched in text; see segment.Mode.
+func (gq *GraphQuerier) SetTermMatcher(m *segment.Matcher) {
+	gq.matcher = m
 }
 
 // FindRelatedTerms finds all terminology and relationships relevant to the given text.
 func (gq *GraphQuerier) FindRelatedTerms(ctx context.Context, text string) (*QueryResult, error) {
-	session := gq.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
-	defer session.Close(ctx)
+	return gq.FindRelatedTermsInRegister(ctx, text, "")
+}
 
+// FindRelatedTermsInRegister is like FindRelatedTerms, but returns the rendering of
+// each term in register where the term has one. An empty register means the
+// default renderings.
+func (gq *GraphQuerier) FindRelatedTermsInRegister(ctx context.Context, text, register string) (*QueryResult, error) {
 	result := &QueryResult{}
 
 	// Find terms whose Chinese text appears in the input.
-	termsResult, err := session.Run(ctx, `
+	termRecords, err := ReadRecords(ctx, gq.driver, `
 		MATCH (t:Term)
-		WHERE $text CONTAINS t.chinese
-		RETURN t.chinese AS chinese, t.vietnamese AS vietnamese, t.category AS category
-		ORDER BY size(t.chinese) DESC
-	`, map[string]any{"text": text})
+		WHERE $text CONTAINS t.chinese AND t[$prop] IS NOT NULL
+		RETURN t.chinese AS chinese, coalesce(t[$register_prop], t[$prop]) AS vietnamese, t.category AS category
+		ORDER BY size(t.chinese) DESC, t.chinese
+	`, map[string]any{"text": text, "prop": gq.termProperty, "register_prop": gq.registerProperty(register)})
 	if err != nil {
 		return nil, fmt.Errorf("query terms: %w", err)
 	}
 
-	for termsResult.Next(ctx) {
-		record := termsResult.Record()
+	for _, record := range termRecords {
 		chinese, _ := record.Get("chinese")
 		vietnamese, _ := record.Get("vietnamese")
 		category, _ := record.Get("category")
 
+		// CONTAINS is only a candidate filter; drop matches the term match mode rejects.
+		if !gq.matcher.ContainsTerm(text, fmt.Sprintf("%v", chinese)) {
+			continue
+		}
+
 		result.Terms = append(result.Terms, TermResult{
 			Chinese:    fmt.Sprintf("%v", chinese),
 			Vietnamese: fmt.Sprintf("%v", vietnamese),
@@ -73,25 +98,29 @@ func (gq *GraphQuerier) FindRelatedTerms(ctx context.Context, text string) (*Que
 		return result, nil
 	}
 
+	matched := make([]string, len(result.Terms))
+	for i, t := range result.Terms {
+		matched[i] = t.Chinese
+	}
+
 	// Find 1-hop relationships for matched terms.
-	relsResult, err := session.Run(ctx, `
+	relRecords, err := ReadRecords(ctx, gq.driver, `
 		MATCH (t:Term)
-		WHERE $text CONTAINS t.chinese
+		WHERE t.chinese IN $terms
 		MATCH (t)-[r]->(neighbor:Term)
 		RETURN t.chinese AS from_node, type(r) AS rel_type, neighbor.chinese AS to_node
 		UNION
 		MATCH (t:Term)
-		WHERE $text CONTAINS t.chinese
+		WHERE t.chinese IN $terms
 		MATCH (neighbor:Term)-[r]->(t)
 		RETURN neighbor.chinese AS from_node, type(r) AS rel_type, t.chinese AS to_node
-	`, map[string]any{"text": text})
+	`, map[string]any{"terms": matched})
 	if err != nil {
 		log.Warn().Err(err).Msg("Failed to query relationships")
 		return result, nil
 	}
 
-	for relsResult.Next(ctx) {
-		record := relsResult.Record()
+	for _, record := range relRecords {
 		from, _ := record.Get("from_node")
 		relType, _ := record.Get("rel_type")
 		to, _ := record.Get("to_node")
@@ -103,6 +132,18 @@ func (gq *GraphQuerier) FindRelatedTerms(ctx context.Context, text string) (*Que
 		})
 	}
 
+	// UNION results have no defined order; sort so prompts are reproducible.
+	sort.Slice(result.Relationships, func(i, j int) bool {
+		a, b := result.Relationships[i], result.Relationships[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.To < b.To
+	})
+
 	log.Debug().
 		Int("terms", len(result.Terms)).
 		Int("relationships", len(result.Relationships)).
@@ -113,20 +154,17 @@ func (gq *GraphQuerier) FindRelatedTerms(ctx context.Context, text string) (*Que
 
 // GetAllTerminology retrieves all terminology from the graph as a lookup map.
 func (gq *GraphQuerier) GetAllTerminology(ctx context.Context) (map[string]string, error) {
-	session := gq.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
-	defer session.Close(ctx)
-
-	result, err := session.Run(ctx, `
+	records, err := ReadRecords(ctx, gq.driver, `
 		MATCH (t:Term)
-		RETURN t.chinese AS chinese, t.vietnamese AS vietnamese
-	`, nil)
+		WHERE t[$prop] IS NOT NULL
+		RETURN t.chinese AS chinese, t[$prop] AS vietnamese
+	`, map[string]any{"prop": gq.termProperty})
 	if err != nil {
 		return nil, fmt.Errorf("get all terminology: %w", err)
 	}
 
 	terms := make(map[string]string)
-	for result.Next(ctx) {
-		record := result.Record()
+	for _, record := range records {
 		chinese, _ := record.Get("chinese")
 		vietnamese, _ := record.Get("vietnamese")
 		terms[fmt.Sprintf("%v", chinese)] = fmt.Sprintf("%v", vietnamese)
@@ -135,3 +173,73 @@ func (gq *GraphQuerier) GetAllTerminology(ctx context.Context) (map[string]strin
 	log.Info().Int("count", len(terms)).Msg("Loaded terminology from graph")
 	return terms, nil
 }
+
+// GetRegisterTerminology retrieves the renderings in register of every term that
+// has one, as a lookup map to layer over GetAllTerminology.
+func (gq *GraphQuerier) GetRegisterTerminology(ctx context.Context, register string) (map[string]string, error) {
+	records, err := ReadRecords(ctx, gq.driver, `
+		MATCH (t:Term)
+		WHERE t[$register_prop] IS NOT NULL
+		RETURN t.chinese AS chinese, t[$register_prop] AS vietnamese
+	`, map[string]any{"register_prop": gq.registerProperty(register)})
+	if err != nil {
+		return nil, fmt.Errorf("get %s terminology: %w", register, err)
+	}
+
+	terms := make(map[string]string)
+	for _, record := range records {
+		chinese, _ := record.Get("chinese")
+		vietnamese, _ := record.Get("vietnamese")
+		terms[fmt.Sprintf("%v", chinese)] = fmt.Sprintf("%v", vietnamese)
+	}
+
+	return terms, nil
+}
+
+// registerProperty returns the Term property for register in the querier's target
+// language, or the default rendering property when register is empty.
+func (gq *GraphQuerier) registerProperty(register string) string {
+	if register == "" {
+		return gq.termProperty
+	}
+	return gq.termProperty + "_" + register
+}
+
+// GetTermsByCategory retrieves the terminology of the given categories as a lookup map.
+func (gq *GraphQuerier) GetTermsByCategory(ctx context.Context, categories []string) (map[string]string, error) {
+	records, err := ReadRecords(ctx, gq.driver, `
+		MATCH (t:Term)
+		WHERE t.category IN $categories AND t[$prop] IS NOT NULL
+		RETURN t.chinese AS chinese, t[$prop] AS vietnamese
+	`, map[string]any{"categories": categories, "prop": gq.termProperty})
+	if err != nil {
+		return nil, fmt.Errorf("get terms by category: %w", err)
+	}
+
+	terms := make(map[string]string)
+	for _, record := range records {
+		chinese, _ := record.Get("chinese")
+		vietnamese, _ := record.Get("vietnamese")
+		terms[fmt.Sprintf("%v", chinese)] = fmt.Sprintf("%v", vietnamese)
+	}
+
+	return terms, nil
+}
+
+// SortedTermKeys returns the source terms of a terminology map in a stable order:
+// longest first, then lexicographically. Map iteration order is random, so callers
+// that emit terms into prompts use this to keep prompts reproducible.
+func SortedTermKeys(terms map[string]string) []string {
+	keys := make([]string, 0, len(terms))
+	for k := range terms {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		li, lj := utf8.RuneCountInString(keys[i]), utf8.RuneCountInString(keys[j])
+		if li != lj {
+			return li > lj
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
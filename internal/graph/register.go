@@ -0,0 +1,71 @@
+package graph
+
+import (
+	"strings"
+
+	"rag-translator/internal/language"
+)
+
+// Registers select between alternative renderings of one term, such as the
+// Sino-Vietnamese form used in lore and the plain form used in chat.
+const (
+	RegisterFormal     = "formal"
+	RegisterColloquial = "colloquial"
+)
+
+// registerKeywords map substrings of a text's context to the register it is
+// written in. The first register with a matching keyword wins.
+var registerKeywords = []struct {
+	register string
+	keywords []string
+}{
+	{RegisterColloquial, []string{"chat", "dialog", "talk", "bubble", "say", "greet", "barrage"}},
+	{RegisterFormal, []string{"lore", "story", "desc", "intro", "book", "legend", "biography"}},
+}
+
+// registerContextKeys are the ExtractedText.Context keys inspected for register
+// keywords. Keys holding code, such as "concat", are left out.
+var registerContextKeys = []string{"file", "section", "key", "msgctxt", "function"}
+
+// RegisterFromContext returns the register of a text from its extraction context:
+// an explicit "register" entry, or else the first register whose keywords occur in
+// the file path, section, key or message context. It returns "" when nothing matches,
+// meaning the default rendering.
+func RegisterFromContext(context map[string]string) string {
+	if r := context["register"]; r != "" {
+		return r
+	}
+	for _, rk := range registerKeywords {
+		for _, key := range registerContextKeys {
+			value := strings.ToLower(context[key])
+			if value == "" {
+				continue
+			}
+			for _, kw := range rk.keywords {
+				if strings.Contains(value, kw) {
+					return rk.register
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// TermRegisterProperty returns the Term node property holding the rendering of a
+// term in register for a target language, e.g. "vietnamese_colloquial".
+func TermRegisterProperty(targetLang, register string) string {
+	return TermProperty(targetLang) + "_" + register
+}
+
+// renderingProperties returns the Term properties for the renderings of t besides
+// Vietnamese: its Vietnamese register renderings and its other target languages.
+func (t WuxiaTerm) renderingProperties() map[string]any {
+	props := make(map[string]any, len(t.Registers)+len(t.Targets))
+	for register, rendering := range t.Registers {
+		props[TermRegisterProperty(language.DefaultTarget, register)] = rendering
+	}
+	for code, rendering := range t.Targets {
+		props[TermProperty(code)] = rendering
+	}
+	return props
+}
@@ -0,0 +1,91 @@
+package graph
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// StaticGraph answers graph queries from fixed terminology and relationships held
+// in memory, standing in for Neo4j in tests. Renderings are those of WuxiaTerm,
+// i.e. the default target language.
+type StaticGraph struct {
+	terms         []WuxiaTerm
+	relationships []Relationship
+}
+
+// NewStaticGraph creates a StaticGraph over terms and relationships.
+func NewStaticGraph(terms []WuxiaTerm, relationships []Relationship) *StaticGraph {
+	return &StaticGraph{terms: terms, relationships: relationships}
+}
+
+// FindRelatedTerms finds the terms contained in text and their relationships.
+func (sg *StaticGraph) FindRelatedTerms(ctx context.Context, text string) (*QueryResult, error) {
+	return sg.FindRelatedTermsInRegister(ctx, text, "")
+}
+
+// FindRelatedTermsInRegister is like FindRelatedTerms, but returns the rendering of
+// each term in register where the term has one. Results are ordered as
+// GraphQuerier orders them.
+func (sg *StaticGraph) FindRelatedTermsInRegister(ctx context.Context, text, register string) (*QueryResult, error) {
+	result := &QueryResult{}
+	matched := make(map[string]bool)
+	for _, t := range sg.terms {
+		if t.Vietnamese == "" || !strings.Contains(text, t.Chinese) {
+			continue
+		}
+		rendering := t.Vietnamese
+		if r := t.Registers[register]; register != "" && r != "" {
+			rendering = r
+		}
+		result.Terms = append(result.Terms, TermResult{Chinese: t.Chinese, Vietnamese: rendering, Category: t.Category})
+		matched[t.Chinese] = true
+	}
+	sort.Slice(result.Terms, func(i, j int) bool {
+		a, b := result.Terms[i].Chinese, result.Terms[j].Chinese
+		if la, lb := utf8.RuneCountInString(a), utf8.RuneCountInString(b); la != lb {
+			return la > lb
+		}
+		return a < b
+	})
+
+	for _, r := range sg.relationships {
+		if matched[r.FromChinese] || matched[r.ToChinese] {
+			result.Relationships = append(result.Relationships, RelationshipResult{From: r.FromChinese, Type: r.RelType, To: r.ToChinese})
+		}
+	}
+	sort.Slice(result.Relationships, func(i, j int) bool {
+		a, b := result.Relationships[i], result.Relationships[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.To < b.To
+	})
+	return result, nil
+}
+
+// GetAllTerminology returns all terminology as a lookup map.
+func (sg *StaticGraph) GetAllTerminology(ctx context.Context) (map[string]string, error) {
+	terms := make(map[string]string, len(sg.terms))
+	for _, t := range sg.terms {
+		if t.Vietnamese != "" {
+			terms[t.Chinese] = t.Vietnamese
+		}
+	}
+	return terms, nil
+}
+
+// GetRegisterTerminology returns the renderings in register of every term that has one.
+func (sg *StaticGraph) GetRegisterTerminology(ctx context.Context, register string) (map[string]string, error) {
+	terms := make(map[string]string)
+	for _, t := range sg.terms {
+		if r := t.Registers[register]; r != "" {
+			terms[t.Chinese] = r
+		}
+	}
+	return terms, nil
+}
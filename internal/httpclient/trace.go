@@ -0,0 +1,169 @@
+package httpclient
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// redacted replaces secrets in trace files.
+const redacted = "REDACTED"
+
+// secretParams are query parameters holding credentials.
+var secretParams = []string{"key", "api_key", "access_token"}
+
+// secretHeaders are headers holding credentials, in canonical form.
+var secretHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"X-Goog-Api-Key":      true,
+}
+
+// traceTransport writes every request and response it carries to a file.
+type traceTransport struct {
+	next http.RoundTripper
+	dir  string
+	seq  atomic.Int64
+}
+
+// Trace wraps next so that each request and its response are written to a file
+// in dir, numbered in the order the requests are sent. API keys are redacted from
+// URLs, headers and error messages. A nil next means http.DefaultTransport.
+func Trace(next http.RoundTripper, dir string) (http.RoundTripper, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create trace directory: %w", err)
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &traceTransport{next: next, dir: dir}, nil
+}
+
+// RoundTrip sends req through the wrapped transport and traces the exchange.
+// Failing to write the trace never fails the request.
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	n := t.seq.Add(1)
+	secrets := urlSecrets(req.URL)
+
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "=== REQUEST %s ===\n%s %s\n", time.Now().Format(time.RFC3339Nano), req.Method, redactURL(req.URL))
+	writeHeaders(&sb, req.Header)
+	sb.WriteString("\n")
+	sb.Write(reqBody)
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		fmt.Fprintf(&sb, "\n\n=== ERROR after %s ===\n%s\n", time.Since(start), redactSecrets(err.Error(), secrets))
+		t.write(n, req, sb.String())
+		return nil, err
+	}
+
+	respBody, readErr := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	fmt.Fprintf(&sb, "\n\n=== RESPONSE after %s ===\n%s\n", time.Since(start), resp.Status)
+	writeHeaders(&sb, resp.Header)
+	sb.WriteString("\n")
+	sb.Write(respBody)
+	if readErr != nil {
+		fmt.Fprintf(&sb, "\n\n=== BODY READ ERROR ===\n%s\n", redactSecrets(readErr.Error(), secrets))
+	}
+	t.write(n, req, sb.String())
+
+	if readErr != nil {
+		return nil, readErr
+	}
+	return resp, nil
+}
+
+// write stores the trace of request n, e.g. 000012-POST-gemini-2.5-flash_generateContent.txt.
+func (t *traceTransport) write(n int64, req *http.Request, trace string) {
+	name := fmt.Sprintf("%06d-%s-%s.txt", n, req.Method, fileSafe(path.Base(req.URL.Path)))
+	// Tracing is a debugging aid; a failed write is not worth failing the request.
+	_ = os.WriteFile(filepath.Join(t.dir, name), []byte(trace), 0644)
+}
+
+// urlSecrets returns the credential values in u's query.
+func urlSecrets(u *url.URL) []string {
+	var secrets []string
+	q := u.Query()
+	for _, p := range secretParams {
+		for _, v := range q[p] {
+			if v != "" {
+				secrets = append(secrets, v)
+			}
+		}
+	}
+	return secrets
+}
+
+// redactURL returns u with credential query parameters redacted.
+func redactURL(u *url.URL) string {
+	q := u.Query()
+	for _, p := range secretParams {
+		if q.Has(p) {
+			q.Set(p, redacted)
+		}
+	}
+	c := *u
+	c.RawQuery = q.Encode()
+	return c.String()
+}
+
+// redactSecrets replaces each of secrets in s, which may quote the request URL.
+func redactSecrets(s string, secrets []string) string {
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+		s = strings.ReplaceAll(s, url.QueryEscape(secret), redacted)
+	}
+	return s
+}
+
+// writeHeaders writes h sorted by name, with credential headers redacted.
+func writeHeaders(sb *strings.Builder, h http.Header) {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range h[name] {
+			if secretHeaders[http.CanonicalHeaderKey(name)] {
+				v = redacted
+			}
+			fmt.Fprintf(sb, "%s: %s\n", name, v)
+		}
+	}
+}
+
+// fileSafe replaces characters that are awkward in file names.
+func fileSafe(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,74 @@
+// Package httpclient builds the HTTP transport shared by the API clients, for
+// networks that reach the API through a proxy or inspect TLS with their own CA.
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// Options configures the API transport. The zero value behaves like
+// http.DefaultTransport, which honours HTTPS_PROXY, HTTP_PROXY and NO_PROXY.
+type Options struct {
+	// ProxyURL routes every request through this proxy instead of the one from
+	// the environment.
+	ProxyURL string
+	// CAFile is a PEM bundle trusted in addition to the system roots.
+	CAFile string
+	// InsecureSkipVerify disables certificate verification. For development only.
+	InsecureSkipVerify bool
+}
+
+// NewTransport returns a transport applying opts. It fails when the proxy URL
+// does not parse or the CA file holds no certificate.
+func NewTransport(opts Options) (*http.Transport, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+
+	if opts.ProxyURL != "" {
+		u, err := url.Parse(opts.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("parse proxy URL: %w", err)
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("proxy URL %q needs a scheme and host", opts.ProxyURL)
+		}
+		t.Proxy = http.ProxyURL(u)
+	}
+
+	if opts.CAFile != "" || opts.InsecureSkipVerify {
+		tlsConfig := &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: opts.InsecureSkipVerify,
+		}
+		if opts.CAFile != "" {
+			pool, err := loadCAFile(opts.CAFile)
+			if err != nil {
+				return nil, err
+			}
+			tlsConfig.RootCAs = pool
+		}
+		t.TLSClientConfig = tlsConfig
+	}
+
+	return t, nil
+}
+
+// loadCAFile returns the system roots plus the certificates of a PEM bundle.
+func loadCAFile(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read CA file: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("CA file %s holds no PEM certificate", path)
+	}
+	return pool, nil
+}
@@ -0,0 +1,35 @@
+package interpolation
+
+import (
+	"regexp"
+	"strings"
+)
+
+// numericVarPattern matches the variables that stand for a number: positional
+// {N} arguments, numeric format specifiers, named or not, and numeric literals.
+var numericVarPattern = regexp.MustCompile(`^(?:\{[0-9]+\}|%(?:\([a-zA-Z_][a-zA-Z0-9_]*\))?[-+0-9]*\.?[0-9]*[difgeu]|[-+]?[0-9]+(?:[.,][0-9]+)*)$`)
+
+// measureWords are Chinese classifiers and units that follow a count, as 个 in
+// 获得{0}个物品. Longer words come first so a prefix does not shadow them.
+var measureWords = []string{
+	"小时", "分钟",
+	"个", "件", "只", "名", "位", "次", "颗", "张", "把", "枚", "条", "块", "份", "本",
+	"瓶", "根", "头", "匹", "座", "层", "支", "套", "组", "株", "朵", "粒", "滴", "种",
+	"项", "轮", "回", "场", "波", "格", "点", "倍", "人", "天", "秒", "两", "斤", "万",
+	"千", "百",
+}
+
+// isCount reports whether value, found in text between before and rest, is a
+// number directly followed by a measure word. An ordinal, as in 第{0}个, is not a
+// count.
+func isCount(value, before, rest string) bool {
+	if !numericVarPattern.MatchString(value) || strings.HasSuffix(before, "第") {
+		return false
+	}
+	for _, w := range measureWords {
+		if strings.HasPrefix(rest, w) {
+			return true
+		}
+	}
+	return false
+}
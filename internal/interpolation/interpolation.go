@@ -11,6 +11,9 @@ type Mapping struct {
 	Original    string
 	Placeholder string
 	Index       int
+	// Count reports a number directly followed by a Chinese measure word, as {0}
+	// in 获得{0}个物品, whose translation should read right for any value.
+	Count bool
 }
 
 // varMatch stores a detected interpolation variable position.
@@ -19,21 +22,87 @@ type varMatch struct {
 	value      string
 }
 
+// namedBracePattern matches a named argument in braces, {playerName}. Doubled
+// braces, as in {{var_1}} or a {{mustache}} tag, are not named arguments.
+var namedBracePattern = regexp.MustCompile(`\{[a-zA-Z_][a-zA-Z0-9_]*\}`)
+
 // patterns to detect interpolation variables in game strings.
 var patterns = []*regexp.Regexp{
-	regexp.MustCompile(`\$\{[a-zA-Z_][a-zA-Z0-9_]*\}`),         // ${value}
-	regexp.MustCompile(`\{[0-9]+\}`),                           // {0}, {1}
-	regexp.MustCompile(`%[-+0-9]*\.?[0-9]*[dsfieEgGxXoubcpq]`), // %d, %s, %f, %2d, etc.
-	regexp.MustCompile(`%%`),                                   // escaped percent literal
+	regexp.MustCompile(`\$\{[a-zA-Z_][a-zA-Z0-9_]*\}`), // ${value}
+	regexp.MustCompile(`\{[0-9]+\}`),                   // {0}, {1}
+	namedBracePattern,                                  // {playerName}
+}
+
+// formatSpecPattern matches printf-style specifiers: %d, %s, %f, %2d, etc.
+var formatSpecPattern = regexp.MustCompile(`%[-+0-9]*\.?[0-9]*[dsfieEgGxXoubcpq]`)
+
+// namedSpecPattern matches Python-style named specifiers: %(count)d, %(name)s.
+var namedSpecPattern = regexp.MustCompile(`%\([a-zA-Z_][a-zA-Z0-9_]*\)[-+ #0-9]*\.?[0-9]*[dsfieEgGxXoubcr]`)
+
+// percentPatterns detect format specifiers and the escaped percent literal %%.
+var percentPatterns = []*regexp.Regexp{
+	namedSpecPattern,
+	formatSpecPattern,
+	regexp.MustCompile(`%%`),
+}
+
+// tagPattern matches inline markup tags: opening tags with optional attributes or
+// an =value (<b>, <color=#ff0000>, <font size="2">), closing tags (</b>) and
+// self-closing tags (<br/>).
+var tagPattern = regexp.MustCompile(`</?[a-zA-Z][a-zA-Z0-9_-]*(?:[\s=][^<>]*)?/?>`)
+
+// numberPattern matches a standalone numeric literal with an optional sign,
+// decimal or grouping separators, and an attached % or ASCII unit (50%, +1,000,
+// 3.5, 120px). Numbers written out in words, like 五十, are not matched.
+var numberPattern = regexp.MustCompile(`[-+]?\b[0-9]+(?:[.,][0-9]+)*(?:%|[a-zA-Z]+\b)?`)
+
+// Options enables protection beyond the interpolation variable patterns.
+type Options struct {
+	// Tags protects each inline markup tag, leaving the text between tags to be
+	// translated. Nesting needs no special handling since every tag is its own
+	// placeholder.
+	Tags bool
+	// Numbers protects numeric literals and their units so the model cannot
+	// reformat or localize them. Leave it off when numbers should be translated.
+	Numbers bool
+	// LiteralPercent marks text in which % is never a format specifier, such as a
+	// Lua string that is not passed to string.format, so percent patterns are skipped.
+	LiteralPercent bool
 }
 
 // Protect replaces all interpolation variables with safe {{var_N}} placeholders.
 // Returns the safe string and a mapping to restore originals after translation.
 func Protect(text string) (string, []Mapping) {
+	return ProtectWithOptions(text, Options{})
+}
+
+// ProtectWithOptions is Protect with the extra protection selected by opts.
+func ProtectWithOptions(text string, opts Options) (string, []Mapping) {
+	var active []*regexp.Regexp
+	if opts.Tags {
+		active = append(active, tagPattern)
+	}
+	active = append(active, patterns...)
+	if !opts.LiteralPercent {
+		active = append(active, percentPatterns...)
+	}
+	if opts.Numbers {
+		active = append(active, numberPattern)
+	}
+
 	var allMatches []varMatch
-	for _, p := range patterns {
+	for _, p := range active {
 		locs := p.FindAllStringIndex(text, -1)
 		for _, loc := range locs {
+			// A specifier wedged between ASCII letters or digits is part of a word,
+			// as in "100%percent", not a format specifier.
+			if p == formatSpecPattern && loc[0] > 0 && loc[1] < len(text) &&
+				isASCIIAlnum(text[loc[0]-1]) && isASCIIAlnum(text[loc[1]]) {
+				continue
+			}
+			if p == namedBracePattern && (loc[0] > 0 && text[loc[0]-1] == '{' || loc[1] < len(text) && text[loc[1]] == '}') {
+				continue
+			}
 			allMatches = append(allMatches, varMatch{
 				start: loc[0],
 				end:   loc[1],
@@ -69,6 +138,7 @@ func Protect(text string) (string, []Mapping) {
 			Original:    m.value,
 			Placeholder: placeholder,
 			Index:       i + 1,
+			Count:       isCount(m.value, text[:m.start], text[m.end:]),
 		}}, mappings...)
 		result = result[:m.start] + placeholder + result[m.end:]
 	}
@@ -85,6 +155,10 @@ func Restore(translated string, mappings []Mapping) string {
 	return result
 }
 
+func isASCIIAlnum(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
+
 // sortVarMatches sorts by start position, then by length (descending) for overlaps.
 func sortVarMatches(matches []varMatch) {
 	for i := 1; i < len(matches); i++ {
@@ -0,0 +1,219 @@
+package interpolation
+
+import (
+	"slices"
+	"testing"
+)
+
+func originals(mappings []Mapping) []string {
+	var out []string
+	for _, m := range mappings {
+		out = append(out, m.Original)
+	}
+	return out
+}
+
+func TestProtectPercent(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		literal bool
+		want    []string
+	}{
+		{"format specifiers", "获得%d金币，消耗%s", false, []string{"%d", "%s"}},
+		{"width and precision", "伤害%5.1f点", false, []string{"%5.1f"}},
+		{"escaped percent", "暴击率+10%%", false, []string{"%%"}},
+		{"percent before a non-verb", "提升50%攻击力", false, nil},
+		{"percent inside a word", "100%percent", false, nil},
+		{"literal text keeps specifiers", "获得%d金币", true, nil},
+		{"literal text keeps other variables", "{0}获得50%", true, []string{"{0}"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			protected, mappings := ProtectWithOptions(tt.text, Options{LiteralPercent: tt.literal})
+			if got := originals(mappings); !slices.Equal(got, tt.want) {
+				t.Errorf("protected %v, want %v", got, tt.want)
+			}
+			if restored := Restore(protected, mappings); restored != tt.text {
+				t.Errorf("Restore = %q, want %q", restored, tt.text)
+			}
+		})
+	}
+}
+
+func TestProtectNumbers(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		numbers bool
+		want    []string
+	}{
+		{"percent value", "攻击力 +50%", true, []string{"+50%"}},
+		{"number in words", "攻击力提升五十", true, nil},
+		{"digits and words", "50%或五十", true, []string{"50%"}},
+		{"grouping and decimals", "需要1,000金币和3.5秒", true, []string{"1,000", "3.5"}},
+		{"attached unit", "宽度120px", true, []string{"120px"}},
+		{"negative value", "温度-5度", true, []string{"-5"}},
+		{"off by default", "攻击力 +50%", false, nil},
+		{"placeholder digits stay one variable", "第{0}名获得100金币", true, []string{"{0}", "100"}},
+		{"specifier wins over number", "造成%d点伤害，暴击50%", true, []string{"%d", "50%"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			protected, mappings := ProtectWithOptions(tt.text, Options{Numbers: tt.numbers})
+			if got := originals(mappings); !slices.Equal(got, tt.want) {
+				t.Errorf("protected %v, want %v", got, tt.want)
+			}
+			if restored := Restore(protected, mappings); restored != tt.text {
+				t.Errorf("Restore = %q, want %q", restored, tt.text)
+			}
+		})
+	}
+}
+
+func TestProtectCount(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []bool
+	}{
+		{"positional before a measure word", "获得{0}个物品", []bool{true}},
+		{"two-character measure word", "冷却{0}分钟", []bool{true}},
+		{"specifier before a measure word", "获得%d件装备", []bool{true}},
+		{"named specifier before a measure word", "获得%(n)d个物品", []bool{true}},
+		{"ordinal", "第{0}个", []bool{false}},
+		{"no measure word", "获得{0}", []bool{false}},
+		{"string variable", "获得{name}个物品", []bool{false}},
+		{"count and name", "{name}获得{0}颗宝石", []bool{false, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, mappings := Protect(tt.text)
+			var got []bool
+			for _, m := range mappings {
+				got = append(got, m.Count)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Count = %v, want %v (mappings %v)", got, tt.want, originals(mappings))
+			}
+		})
+	}
+}
+
+func TestProtectNamedAndPositional(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		want       []string
+		protected  string
+		translated string // translation of protected, with placeholders reordered
+		restored   string
+	}{
+		{
+			name:       "name before index",
+			text:       "{name}获得{0}个{item}",
+			want:       []string{"{name}", "{0}", "{item}"},
+			protected:  "{{var_1}}获得{{var_2}}个{{var_3}}",
+			translated: "{{var_1}} nhận được {{var_2}} {{var_3}}",
+			restored:   "{name} nhận được {0} {item}",
+		},
+		{
+			name:       "index before name, reordered",
+			text:       "第{0}名：{playerName}",
+			want:       []string{"{0}", "{playerName}"},
+			protected:  "第{{var_1}}名：{{var_2}}",
+			translated: "{{var_2}} hạng {{var_1}}",
+			restored:   "{playerName} hạng {0}",
+		},
+		{
+			name:       "with dollar variable",
+			text:       "${value}与{1}和{target}",
+			want:       []string{"${value}", "{1}", "{target}"},
+			protected:  "{{var_1}}与{{var_2}}和{{var_3}}",
+			translated: "{{var_3}}, {{var_2}} và {{var_1}}",
+			restored:   "{target}, {1} và ${value}",
+		},
+		{
+			name:       "doubled braces are not variables",
+			text:       "{{title}}：{name}获得{0}",
+			want:       []string{"{name}", "{0}"},
+			protected:  "{{title}}：{{var_1}}获得{{var_2}}",
+			translated: "{{title}}: {{var_1}} nhận {{var_2}}",
+			restored:   "{{title}}: {name} nhận {0}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			protected, mappings := Protect(tt.text)
+			if got := originals(mappings); !slices.Equal(got, tt.want) {
+				t.Errorf("protected %v, want %v", got, tt.want)
+			}
+			if protected != tt.protected {
+				t.Errorf("Protect = %q, want %q", protected, tt.protected)
+			}
+			if restored := Restore(protected, mappings); restored != tt.text {
+				t.Errorf("Restore = %q, want %q", restored, tt.text)
+			}
+			if restored := Restore(tt.translated, mappings); restored != tt.restored {
+				t.Errorf("Restore(translation) = %q, want %q", restored, tt.restored)
+			}
+		})
+	}
+}
+
+func TestProtectTags(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		want       []string
+		translated string // protected translation with placeholders moved
+		restored   string
+	}{
+		{
+			name:       "nested bold in color",
+			text:       "<color=#ff0000><b>警告</b></color>：生命值过低",
+			want:       []string{"<color=#ff0000>", "<b>", "</b>", "</color>"},
+			translated: "{{var_1}}{{var_2}}Cảnh báo{{var_3}}{{var_4}}: Sinh lực quá thấp",
+			restored:   "<color=#ff0000><b>Cảnh báo</b></color>: Sinh lực quá thấp",
+		},
+		{
+			name:       "attributes, variables and a line break",
+			text:       `{name}的<font size="2" color="#00ff00">攻击力</font>提升<b>%d</b>点<br/>`,
+			want:       []string{"{name}", `<font size="2" color="#00ff00">`, "</font>", "<b>", "%d", "</b>", "<br/>"},
+			translated: "{{var_2}}Tấn công{{var_3}} của {{var_1}} tăng {{var_4}}{{var_5}}{{var_6}} điểm{{var_7}}",
+			restored:   `<font size="2" color="#00ff00">Tấn công</font> của {name} tăng <b>%d</b> điểm<br/>`,
+		},
+		{
+			name:       "link around an item name",
+			text:       `获得<a href="item:1001"><color=orange>[屠龙刀]</color></a>`,
+			want:       []string{`<a href="item:1001">`, "<color=orange>", "</color>", "</a>"},
+			translated: "Nhận được {{var_1}}{{var_2}}[Đồ Long Đao]{{var_3}}{{var_4}}",
+			restored:   `Nhận được <a href="item:1001"><color=orange>[Đồ Long Đao]</color></a>`,
+		},
+		{
+			name:       "comparisons are not tags",
+			text:       "<b>生命<50%</b>时 a < b",
+			want:       []string{"<b>", "</b>"},
+			translated: "Khi {{var_1}}sinh lực<50%{{var_2}} a < b",
+			restored:   "Khi <b>sinh lực<50%</b> a < b",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			protected, mappings := ProtectWithOptions(tt.text, Options{Tags: true})
+			if got := originals(mappings); !slices.Equal(got, tt.want) {
+				t.Errorf("protected %v, want %v", got, tt.want)
+			}
+			if restored := Restore(protected, mappings); restored != tt.text {
+				t.Errorf("Restore = %q, want %q", restored, tt.text)
+			}
+			if restored := Restore(tt.translated, mappings); restored != tt.restored {
+				t.Errorf("Restore(translation) = %q, want %q", restored, tt.restored)
+			}
+		})
+	}
+
+	if _, mappings := Protect("<color=red>警告</color>"); len(mappings) != 0 {
+		t.Errorf("tags protected without Tags: %v", originals(mappings))
+	}
+}
@@ -0,0 +1,52 @@
+package language
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Language describes a source or target locale of the localization pipeline.
+type Language struct {
+	// Code is the configured BCP 47 code, e.g. "zh-CN".
+	Code string
+	// Name is the English name used in prompts.
+	Name string
+	// Scripts are the Unicode script names whose characters identify text in this language.
+	Scripts []string
+}
+
+// Default language pair of the tool.
+const (
+	DefaultSource = "zh-CN"
+	DefaultTarget = "vi-VN"
+)
+
+// known maps a primary language subtag to its description.
+var known = map[string]Language{
+	"zh": {Name: "Simplified Chinese", Scripts: []string{"Han"}},
+	"ja": {Name: "Japanese", Scripts: []string{"Han", "Hiragana", "Katakana"}},
+	"ko": {Name: "Korean", Scripts: []string{"Hangul"}},
+	"vi": {Name: "Vietnamese", Scripts: []string{"Latin"}},
+	"th": {Name: "Thai", Scripts: []string{"Thai"}},
+	"en": {Name: "English", Scripts: []string{"Latin"}},
+	"id": {Name: "Indonesian", Scripts: []string{"Latin"}},
+}
+
+// Lookup resolves a language code such as "vi-VN" or "th" by its primary subtag.
+func Lookup(code string) (Language, error) {
+	lang, ok := known[Base(code)]
+	if !ok {
+		return Language{}, fmt.Errorf("unsupported language %q", code)
+	}
+	lang.Code = code
+	if Base(code) == "zh" && strings.Contains(strings.ToUpper(code), "TW") {
+		lang.Name = "Traditional Chinese"
+	}
+	return lang, nil
+}
+
+// Base returns the lowercase primary subtag of a language code ("zh-CN" → "zh").
+func Base(code string) string {
+	base, _, _ := strings.Cut(strings.ReplaceAll(code, "_", "-"), "-")
+	return strings.ToLower(base)
+}
@@ -9,10 +9,117 @@ import (
 	"rag-translator/internal/textutil"
 )
 
-// INIParser extracts translatable strings from INI/config files.
-type INIParser struct{}
+// INIParser extracts translatable strings from INI/config files. Each text's
+// context records its section and key, plus the dotted path joining the two, so a
+// value under [ui] with key skill.fire.name has the path ui.skill.fire.name, and
+// "part": whether the text is the key, the value or a comment.
+type INIParser struct {
+	commentPrefixes []string
+	keySections     map[string]INIKeyMode // lower-cased section name → what to extract
+	includeComments bool
+}
+
+// INIKeyMode selects what is extracted from the lines of an INI section.
+type INIKeyMode string
+
+const (
+	// INIKeysValues extracts values only, the default for every section.
+	INIKeysValues INIKeyMode = "values"
+	// INIKeysOnly extracts key names only, for sections whose keys are displayed.
+	INIKeysOnly INIKeyMode = "keys"
+	// INIKeysBoth extracts both key names and values.
+	INIKeysBoth INIKeyMode = "both"
+)
+
+// ParseINIKeySections parses entries of the form "section" or "section:mode",
+// where mode is keys (the default) or both, into a table for SetKeySections.
+func ParseINIKeySections(entries []string) (map[string]INIKeyMode, error) {
+	sections := make(map[string]INIKeyMode, len(entries))
+	for _, entry := range entries {
+		name, mode, found := strings.Cut(entry, ":")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("empty INI section name in %q", entry)
+		}
+		m := INIKeysOnly
+		if found {
+			m = INIKeyMode(strings.TrimSpace(mode))
+		}
+		if m != INIKeysOnly && m != INIKeysBoth {
+			return nil, fmt.Errorf("INI section %q: mode must be keys or both, got %q", name, m)
+		}
+		sections[name] = m
+	}
+	return sections, nil
+}
+
+// DefaultINICommentPrefixes are the line prefixes that mark INI comments unless
+// configured otherwise.
+var DefaultINICommentPrefixes = []string{";", "#"}
+
+func NewINIParser() *INIParser {
+	return &INIParser{commentPrefixes: DefaultINICommentPrefixes}
+}
+
+// SetCommentPrefixes sets the prefixes that mark a line as a comment, such as
+// "//". Comment lines are never extracted and are written back unchanged. An
+// empty list keeps the defaults.
+func (p *INIParser) SetCommentPrefixes(prefixes []string) {
+	if len(prefixes) == 0 {
+		prefixes = DefaultINICommentPrefixes
+	}
+	p.commentPrefixes = prefixes
+}
+
+// SetIncludeComments makes the parser extract source text in comment lines too.
+// Their context has "part": "comment" and "comment": "line".
+func (p *INIParser) SetIncludeComments(enabled bool) {
+	p.includeComments = enabled
+}
 
-func NewINIParser() *INIParser { return &INIParser{} }
+// SetKeySections makes the parser extract key names in the given sections, as
+// from ParseINIKeySections. Section names match case-insensitively. In these
+// sections a line without "=" is taken as a bare key.
+func (p *INIParser) SetKeySections(sections map[string]INIKeyMode) {
+	p.keySections = make(map[string]INIKeyMode, len(sections))
+	for name, mode := range sections {
+		p.keySections[strings.ToLower(name)] = mode
+	}
+}
+
+// keyMode returns what is extracted from the lines of section.
+func (p *INIParser) keyMode(section string) INIKeyMode {
+	if mode, ok := p.keySections[strings.ToLower(section)]; ok {
+		return mode
+	}
+	return INIKeysValues
+}
+
+// isComment reports whether a trimmed line starts with a comment prefix.
+func (p *INIParser) isComment(trimmed string) bool {
+	for _, prefix := range p.commentPrefixes {
+		if strings.HasPrefix(trimmed, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// commentText returns the text of a comment line without its prefixes, repeated
+// ones such as ";;" included, and padding.
+func (p *INIParser) commentText(line string) string {
+	text := strings.TrimSpace(line)
+	for trimmed := true; trimmed; {
+		trimmed = false
+		for _, prefix := range p.commentPrefixes {
+			if prefix != "" && strings.HasPrefix(text, prefix) {
+				text = strings.TrimSpace(text[len(prefix):])
+				trimmed = true
+			}
+		}
+	}
+	return text
+}
 
 func (p *INIParser) CanParse(ext string) bool {
 	return ext == ".ini"
@@ -44,7 +151,15 @@ func (p *INIParser) Parse(filePath string) (*ParseResult, error) {
 		trimmed := strings.TrimSpace(line)
 
 		// Skip empty lines and comments.
-		if trimmed == "" || strings.HasPrefix(trimmed, ";") || strings.HasPrefix(trimmed, "#") {
+		if trimmed == "" {
+			continue
+		}
+		if p.isComment(trimmed) {
+			if text := p.commentText(trimmed); p.includeComments && textutil.ContainsSource(text) {
+				et := iniText(filePath, currentSection, "", text, "comment", lineNum)
+				et.Context["comment"] = "line"
+				result.Texts = append(result.Texts, et)
+			}
 			continue
 		}
 
@@ -54,32 +169,21 @@ func (p *INIParser) Parse(filePath string) (*ParseResult, error) {
 			continue
 		}
 
-		// Key=Value pair.
-		eqIdx := strings.Index(trimmed, "=")
-		if eqIdx < 0 {
+		// Key=Value pair, or a bare key in a key section.
+		mode := p.keyMode(currentSection)
+		key, value, hasValue := strings.Cut(trimmed, "=")
+		if !hasValue && mode == INIKeysValues {
 			continue
 		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
 
-		value := strings.TrimSpace(trimmed[eqIdx+1:])
-		if value == "" || !textutil.ContainsChinese(value) {
-			continue
+		if mode != INIKeysValues && key != "" && textutil.ContainsSource(key) {
+			result.Texts = append(result.Texts, iniText(filePath, currentSection, key, key, "key", lineNum))
 		}
-
-		key := strings.TrimSpace(trimmed[:eqIdx])
-
-		ctx := map[string]string{
-			"file":    filePath,
-			"section": currentSection,
-			"key":     key,
+		if mode != INIKeysOnly && value != "" && textutil.ContainsSource(value) {
+			result.Texts = append(result.Texts, iniText(filePath, currentSection, key, value, "value", lineNum))
 		}
-
-		result.Texts = append(result.Texts, ExtractedText{
-			Text:    value,
-			File:    filePath,
-			Line:    lineNum,
-			Column:  -1,
-			Context: ctx,
-		})
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -89,40 +193,116 @@ func (p *INIParser) Parse(filePath string) (*ParseResult, error) {
 	return result, nil
 }
 
-func (p *INIParser) Reconstruct(result *ParseResult, translations map[string]string) ([]byte, error) {
+// iniText builds the extracted text of one key or value (part) of an INI line.
+func iniText(filePath, section, key, text, part string, lineNum int) ExtractedText {
+	return ExtractedText{
+		Text:   text,
+		File:   filePath,
+		Line:   lineNum,
+		Column: -1,
+		Context: map[string]string{
+			"file":    filePath,
+			"section": section,
+			"key":     key,
+			"path":    iniPath(section, key),
+			"part":    part,
+		},
+	}
+}
+
+// iniPath joins a section name and key into one dotted path. Both may themselves
+// be dotted ([skill.fire], fire.name); empty segments are dropped.
+func iniPath(section, key string) string {
+	var segments []string
+	for _, part := range []string{section, key} {
+		for _, seg := range strings.Split(part, ".") {
+			if seg = strings.TrimSpace(seg); seg != "" {
+				segments = append(segments, seg)
+			}
+		}
+	}
+	return strings.Join(segments, ".")
+}
+
+func (p *INIParser) Reconstruct(result *ParseResult, translations map[string]string) ([]byte, ReconstructReport, error) {
 	lines := make([]string, len(result.RawLines))
 	copy(lines, result.RawLines)
 
+	var report ReconstructReport
 	for _, et := range result.Texts {
 		idx := et.Line - 1
 		if idx < 0 || idx >= len(lines) {
+			report.outOfRange(et.Text)
 			continue
 		}
 
 		translated, ok := translations[et.Text]
 		if !ok {
+			report.missing(et.Text)
 			continue
 		}
+		translated = singleLine(translated)
 
 		line := lines[idx]
 		eqIdx := strings.Index(line, "=")
-		if eqIdx < 0 {
+
+		if et.Context["part"] == "comment" {
+			at := strings.LastIndex(line, et.Text)
+			if at < 0 {
+				report.outOfRange(et.Text)
+				continue
+			}
+			lines[idx] = line[:at] + translated + line[at+len(et.Text):]
+			report.applied()
 			continue
 		}
 
-		// Preserve leading whitespace after =.
-		afterEq := line[eqIdx+1:]
-		leadingSpaces := ""
-		for _, ch := range afterEq {
-			if ch == ' ' || ch == '\t' {
-				leadingSpaces += string(ch)
-			} else {
-				break
+		if et.Context["part"] == "key" {
+			// A translated key must not add a "=" that would move the value.
+			if strings.Contains(translated, "=") {
+				report.outOfRange(et.Text)
+				continue
+			}
+			keyEnd := len(line)
+			if eqIdx >= 0 {
+				keyEnd = eqIdx
 			}
+			lead, _, trail := textutil.SplitPadding(line[:keyEnd])
+			lines[idx] = lead + translated + trail + line[keyEnd:]
+			report.applied()
+			continue
 		}
 
-		lines[idx] = line[:eqIdx+1] + leadingSpaces + translated
+		if eqIdx < 0 {
+			report.outOfRange(et.Text)
+			continue
+		}
+
+		// Preserve the whitespace around the value.
+		lead, _, trail := textutil.SplitPadding(line[eqIdx+1:])
+		lines[idx] = line[:eqIdx+1] + lead + translated + trail
+		report.applied()
 	}
 
-	return []byte(strings.Join(lines, "\n") + "\n"), nil
+	return []byte(strings.Join(lines, "\n") + "\n"), report, nil
+}
+
+func (p *INIParser) ExtractTranslations(result *ParseResult, translatedLines []string) []string {
+	values := make([]string, len(result.Texts))
+	for i, et := range result.Texts {
+		idx := et.Line - 1
+		if idx < 0 || idx >= len(translatedLines) {
+			continue
+		}
+		key, value, found := strings.Cut(translatedLines[idx], "=")
+		switch {
+		case et.Context["part"] == "comment":
+			values[i] = p.commentText(translatedLines[idx])
+		case et.Context["part"] == "key":
+			values[i] = strings.TrimSpace(key)
+		case found:
+			values[i] = strings.TrimSpace(value)
+		}
+	}
+	return values
 }
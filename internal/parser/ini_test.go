@@ -0,0 +1,53 @@
+package parser
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestINICommentPrefixes(t *testing.T) {
+	const content = "; 分号注释=不翻译\n# 井号注释=不翻译\n// 斜线注释=不翻译\n[ui]\nok = 确认\n  // 缩进注释 = 不翻译\ncancel = 取消\n"
+	tests := []struct {
+		name     string
+		prefixes []string
+		comments bool
+		want     []string
+	}{
+		{"defaults take // for a key", nil, false, []string{"不翻译", "确认", "不翻译", "取消"}},
+		{"// added", []string{";", "#", "//"}, false, []string{"确认", "取消"}},
+		{"only //", []string{"//"}, false, []string{"不翻译", "不翻译", "确认", "取消"}},
+		{"comments included", []string{";", "#", "//"}, true, []string{"分号注释=不翻译", "井号注释=不翻译", "斜线注释=不翻译", "确认", "缩进注释 = 不翻译", "取消"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewINIParser()
+			p.SetCommentPrefixes(tt.prefixes)
+			p.SetIncludeComments(tt.comments)
+			path := writeTemp(t, "ui.ini", content)
+			result, err := p.Parse(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, et := range result.Texts {
+				got = append(got, et.Text)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("texts = %q, want %q", got, tt.want)
+			}
+
+			// Comment lines are written back unchanged.
+			if !slices.Contains(tt.prefixes, "//") {
+				return
+			}
+			out, _, err := p.Reconstruct(result, map[string]string{"确认": "OK", "取消": "Cancel"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := "; 分号注释=不翻译\n# 井号注释=不翻译\n// 斜线注释=不翻译\n[ui]\nok = OK\n  // 缩进注释 = 不翻译\ncancel = Cancel\n"
+			if string(out) != want {
+				t.Errorf("reconstructed %q, want %q", out, want)
+			}
+		})
+	}
+}
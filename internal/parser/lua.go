@@ -11,9 +11,24 @@ import (
 )
 
 // LuaParser extracts translatable strings from Lua source files.
-type LuaParser struct{}
+type LuaParser struct {
+	mode            LuaMode
+	includeComments bool
+}
+
+func NewLuaParser() *LuaParser { return &LuaParser{mode: LuaModeLine} }
 
-func NewLuaParser() *LuaParser { return &LuaParser{} }
+// SetMode selects line-based or table-walking parsing, see LuaMode.
+func (p *LuaParser) SetMode(mode LuaMode) {
+	p.mode = mode
+}
+
+// SetIncludeComments makes the parser extract source text in comments too, one
+// text per comment line. Their context records the comment kind: "line" for --
+// comments, "block" for --[[ ]] ones.
+func (p *LuaParser) SetIncludeComments(enabled bool) {
+	p.includeComments = enabled
+}
 
 func (p *LuaParser) CanParse(ext string) bool {
 	return ext == ".lua"
@@ -25,11 +40,18 @@ var luaStringPattern = regexp.MustCompile(`"([^"\\]*(?:\\.[^"\\]*)*)"|'([^'\\]*(
 // luaFuncPattern captures the function name before a parenthesized argument.
 var luaFuncPattern = regexp.MustCompile(`([a-zA-Z_][a-zA-Z0-9_.:]*)s*\(\s*$`)
 
+// luaFormatCallPattern matches a string.format or :format call.
+var luaFormatCallPattern = regexp.MustCompile(`\bformat\s*\(`)
+
 // luaMultilineOpen matches the opening of --[[ or --[=[ blocks.
 var luaMultilineCommentOpen = regexp.MustCompile(`--\[=*\[`)
 var luaMultilineCommentClose = regexp.MustCompile(`\]=*\]`)
 
 func (p *LuaParser) Parse(filePath string) (*ParseResult, error) {
+	if p.mode == LuaModeTable {
+		return p.parseTable(filePath)
+	}
+
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("open lua file: %w", err)
@@ -46,6 +68,7 @@ func (p *LuaParser) Parse(filePath string) (*ParseResult, error) {
 
 	lineNum := 0
 	inMultilineComment := false
+	usesFormat := false
 
 	for scanner.Scan() {
 		lineNum++
@@ -57,6 +80,7 @@ func (p *LuaParser) Parse(filePath string) (*ParseResult, error) {
 			if luaMultilineCommentClose.MatchString(line) {
 				inMultilineComment = false
 			}
+			p.addComment(result, filePath, lineNum, line, "block")
 			continue
 		}
 
@@ -64,20 +88,59 @@ func (p *LuaParser) Parse(filePath string) (*ParseResult, error) {
 			if !luaMultilineCommentClose.MatchString(line) {
 				inMultilineComment = true
 			}
+			p.addComment(result, filePath, lineNum, line, "block")
 			continue
 		}
 
 		// Skip single-line comments.
 		codePart := line
+		hasComment := false
 		if idx := strings.Index(line, "--"); idx >= 0 {
 			if !isInsideString(line, idx) {
 				codePart = line[:idx]
+				hasComment = true
+			}
+		}
+		if luaFormatCallPattern.MatchString(codePart) {
+			usesFormat = true
+		}
+
+		// Concatenation chains mixing Chinese literals and expressions are extracted
+		// as one template so the message is translated as a whole.
+		var covered [][2]int
+		for _, chain := range findConcatChains(codePart) {
+			if !chain.translatable() {
+				continue
+			}
+			covered = append(covered, [2]int{chain.start, chain.end})
+
+			ctx := map[string]string{
+				"file":   filePath,
+				"concat": codePart[chain.start:chain.end],
 			}
+			if funcMatch := luaFuncPattern.FindStringSubmatch(codePart[:chain.start]); funcMatch != nil {
+				ctx["function"] = funcMatch[1]
+			}
+
+			result.Texts = append(result.Texts, ExtractedText{
+				Text:    chain.template(),
+				File:    filePath,
+				Line:    lineNum,
+				Column:  -1,
+				Context: ctx,
+			})
 		}
 
 		// Find all string literals.
 		matches := luaStringPattern.FindAllStringSubmatchIndex(codePart, -1)
+	literals:
 		for _, loc := range matches {
+			for _, span := range covered {
+				if loc[0] >= span[0] && loc[1] <= span[1] {
+					continue literals
+				}
+			}
+
 			var text string
 			if loc[2] >= 0 {
 				text = codePart[loc[2]:loc[3]] // double quoted
@@ -85,7 +148,7 @@ func (p *LuaParser) Parse(filePath string) (*ParseResult, error) {
 				text = codePart[loc[4]:loc[5]] // single quoted
 			}
 
-			if text == "" || !textutil.ContainsChinese(text) {
+			if text == "" || !textutil.ContainsSource(text) {
 				continue
 			}
 
@@ -105,40 +168,194 @@ func (p *LuaParser) Parse(filePath string) (*ParseResult, error) {
 				Context: ctx,
 			})
 		}
+
+		if hasComment {
+			p.addComment(result, filePath, lineNum, line, "line")
+		}
 	}
 
 	if err := scanner.Err(); err != nil {
 		return nil, fmt.Errorf("scan lua file: %w", err)
 	}
 
+	// A format string may be stored in a variable and formatted elsewhere in the
+	// file, so any format call marks every text of the file but comments.
+	if usesFormat {
+		for _, et := range result.Texts {
+			if et.Context["comment"] == "" {
+				et.Context["string_format"] = "true"
+			}
+		}
+	}
+
 	return result, nil
 }
 
-func (p *LuaParser) Reconstruct(result *ParseResult, translations map[string]string) ([]byte, error) {
+func (p *LuaParser) Reconstruct(result *ParseResult, translations map[string]string) ([]byte, ReconstructReport, error) {
+	if p.mode == LuaModeTable {
+		return p.reconstructTable(result, translations)
+	}
+
 	lines := make([]string, len(result.RawLines))
 	copy(lines, result.RawLines)
 
-	// Group by line number and process.
-	lineReplacements := make(map[int][]ExtractedText)
+	// Texts are in line order, and texts sharing a line are replaced in turn.
+	var report ReconstructReport
 	for _, et := range result.Texts {
-		lineReplacements[et.Line] = append(lineReplacements[et.Line], et)
-	}
-
-	for lineNum, texts := range lineReplacements {
-		idx := lineNum - 1
+		idx := et.Line - 1
 		if idx < 0 || idx >= len(lines) {
+			report.outOfRange(et.Text)
+			continue
+		}
+		translated, ok := translations[et.Text]
+		if !ok {
+			report.missing(et.Text)
 			continue
 		}
-		line := lines[idx]
-		for _, et := range texts {
-			if translated, ok := translations[et.Text]; ok {
-				line = strings.Replace(line, et.Text, translated, 1)
+
+		var line string
+		if kind := et.Context["comment"]; kind != "" {
+			// The comment ends its line, so the text is its last occurrence. A
+			// translation must not break the comment out of its syntax.
+			translated = singleLine(translated)
+			at := strings.LastIndex(lines[idx], et.Text)
+			if at < 0 || (kind == "block" && luaMultilineCommentClose.MatchString(translated)) {
+				report.outOfRange(et.Text)
+				continue
 			}
+			line = lines[idx][:at] + translated + lines[idx][at+len(et.Text):]
+		} else if chainCode := et.Context["concat"]; chainCode != "" {
+			line = replaceConcat(lines[idx], chainCode, translated)
+		} else {
+			line = strings.Replace(lines[idx], et.Text, translated, 1)
+		}
+		if line == lines[idx] && translated != et.Text {
+			report.outOfRange(et.Text)
+			continue
 		}
 		lines[idx] = line
+		report.applied()
+	}
+
+	return []byte(strings.Join(lines, "\n") + "\n"), report, nil
+}
+
+func (p *LuaParser) ExtractTranslations(result *ParseResult, translatedLines []string) []string {
+	if p.mode == LuaModeTable {
+		return p.extractTableTranslations(result, translatedLines)
+	}
+
+	values := make([]string, len(result.Texts))
+	// seen counts how many texts with the same value were already located per line,
+	// so repeated literals on one line map to successive occurrences.
+	seen := make(map[int]map[string]int)
+
+	for i, et := range result.Texts {
+		idx := et.Line - 1
+		if idx < 0 || idx >= len(result.RawLines) || idx >= len(translatedLines) {
+			continue
+		}
+		if kind := et.Context["comment"]; kind != "" {
+			values[i] = luaCommentText(translatedLines[idx], kind)
+			continue
+		}
+		if chainCode := et.Context["concat"]; chainCode != "" {
+			values[i] = concatTranslation(result.RawLines[idx], translatedLines[idx], chainCode)
+			continue
+		}
+		if seen[idx] == nil {
+			seen[idx] = make(map[string]int)
+		}
+		occurrence := seen[idx][et.Text]
+		seen[idx][et.Text]++
+
+		// Find the position of this literal among all literals on the source line.
+		srcLiterals := luaLiterals(result.RawLines[idx])
+		pos := -1
+		for k, lit := range srcLiterals {
+			if lit != et.Text {
+				continue
+			}
+			if occurrence == 0 {
+				pos = k
+				break
+			}
+			occurrence--
+		}
+
+		dstLiterals := luaLiterals(translatedLines[idx])
+		if pos >= 0 && pos < len(dstLiterals) {
+			values[i] = dstLiterals[pos]
+		}
+	}
+	return values
+}
+
+// addComment appends the source text of the comment on line, of the given kind,
+// when comments are included.
+func (p *LuaParser) addComment(result *ParseResult, filePath string, lineNum int, line, kind string) {
+	if !p.includeComments {
+		return
+	}
+	text := luaCommentText(line, kind)
+	if text == "" || !textutil.ContainsSource(text) {
+		return
+	}
+	result.Texts = append(result.Texts, ExtractedText{
+		Text:    text,
+		File:    filePath,
+		Line:    lineNum,
+		Column:  -1,
+		Context: map[string]string{"file": filePath, "comment": kind},
+	})
+}
+
+// luaCommentText returns the text of the comment on line without its delimiters
+// and padding. A line comment starts at the first -- outside a string; a line of
+// a block comment is cut at the block's opening and closing brackets.
+func luaCommentText(line, kind string) string {
+	body := line
+	if kind == "line" {
+		idx := strings.Index(line, "--")
+		for idx >= 0 && isInsideString(line, idx) {
+			next := strings.Index(line[idx+2:], "--")
+			if next < 0 {
+				return ""
+			}
+			idx += 2 + next
+		}
+		if idx < 0 {
+			return ""
+		}
+		body = line[idx+2:]
+	} else {
+		if loc := luaMultilineCommentOpen.FindStringIndex(body); loc != nil {
+			body = body[loc[1]:]
+		}
+		if loc := luaMultilineCommentClose.FindStringIndex(body); loc != nil {
+			body = body[:loc[0]]
+		}
 	}
+	return commentText(body)
+}
+
+// commentText trims the leading dashes of a comment body such as "--- note" and
+// the surrounding whitespace.
+func commentText(body string) string {
+	return strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(body), "-"))
+}
 
-	return []byte(strings.Join(lines, "\n") + "\n"), nil
+// luaLiterals returns the contents of every quoted string literal on a line.
+func luaLiterals(line string) []string {
+	var literals []string
+	for _, m := range luaStringPattern.FindAllStringSubmatch(line, -1) {
+		if m[1] != "" || !strings.HasPrefix(m[0], "'") {
+			literals = append(literals, m[1])
+		} else {
+			literals = append(literals, m[2])
+		}
+	}
+	return literals
 }
 
 // isInsideString checks if position idx is inside a string literal.
@@ -0,0 +1,338 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"rag-translator/internal/textutil"
+)
+
+// concatOperand is one operand of a Lua `..` concatenation chain.
+type concatOperand struct {
+	start, end int  // byte span in the code, end exclusive
+	literal    bool // string literal (true) or expression (false)
+	value      string
+	quote      byte // quote character for literals
+}
+
+// concatChain is a run of operands joined by `..` on a single line.
+type concatChain struct {
+	start, end int
+	operands   []concatOperand
+}
+
+// concatPlaceholderPattern matches the {N} slots standing in for chain expressions.
+var concatPlaceholderPattern = regexp.MustCompile(`\{([0-9]+)\}`)
+
+// template joins the chain into one translatable string, with {0}, {1}, ... in place
+// of the non-literal expressions.
+func (c concatChain) template() string {
+	var sb strings.Builder
+	expr := 0
+	for _, op := range c.operands {
+		if op.literal {
+			sb.WriteString(op.value)
+			continue
+		}
+		fmt.Fprintf(&sb, "{%d}", expr)
+		expr++
+	}
+	return sb.String()
+}
+
+// translatable reports whether the chain mixes Chinese literals with expressions,
+// the case where translating fragments in isolation produces broken word order.
+func (c concatChain) translatable() bool {
+	hasChinese, hasExpr := false, false
+	for _, op := range c.operands {
+		if op.literal && textutil.ContainsSource(op.value) {
+			hasChinese = true
+		}
+		if !op.literal {
+			hasExpr = true
+		}
+	}
+	return hasChinese && hasExpr
+}
+
+// rebuild splices a translated template back around the chain's original expressions,
+// escaping the literal pieces for the chain's quote character.
+// It returns false if the translation lost or duplicated an expression placeholder.
+func (c concatChain) rebuild(translated string) (string, bool) {
+	var exprs []string
+	quote := byte('"')
+	quoteSet := false
+	for _, op := range c.operands {
+		if op.literal && !quoteSet {
+			quote = op.quote
+			quoteSet = true
+		}
+		if !op.literal {
+			exprs = append(exprs, op.value)
+		}
+	}
+
+	var pieces []string
+	literal := func(lit string) string {
+		escaped, _ := escapeLuaContent(lit, string(quote))
+		return string(quote) + escaped + string(quote)
+	}
+	used := make([]bool, len(exprs))
+	last := 0
+	for _, loc := range concatPlaceholderPattern.FindAllStringSubmatchIndex(translated, -1) {
+		n, err := strconv.Atoi(translated[loc[2]:loc[3]])
+		if err != nil || n >= len(exprs) || used[n] {
+			return "", false
+		}
+		used[n] = true
+
+		if lit := translated[last:loc[0]]; lit != "" {
+			pieces = append(pieces, literal(lit))
+		}
+		pieces = append(pieces, exprs[n])
+		last = loc[1]
+	}
+	if lit := translated[last:]; lit != "" {
+		pieces = append(pieces, literal(lit))
+	}
+
+	for _, u := range used {
+		if !u {
+			return "", false
+		}
+	}
+	return strings.Join(pieces, " .. "), true
+}
+
+// replaceConcat rewrites the chain chainCode within line using the translated template.
+// The line is left unchanged if the translation dropped an expression placeholder.
+func replaceConcat(line, chainCode, translated string) string {
+	chains := findConcatChains(chainCode)
+	if len(chains) != 1 {
+		return line
+	}
+	rebuilt, ok := chains[0].rebuild(translated)
+	if !ok {
+		return line
+	}
+	return strings.Replace(line, chainCode, rebuilt, 1)
+}
+
+// concatTranslation reads back the template written for chainCode from a translated
+// line, pairing chains by their position on the source line.
+func concatTranslation(sourceLine, translatedLine, chainCode string) string {
+	pos := -1
+	for i, chain := range findConcatChains(sourceLine) {
+		if sourceLine[chain.start:chain.end] == chainCode {
+			pos = i
+			break
+		}
+	}
+	translated := findConcatChains(translatedLine)
+	if pos < 0 || pos >= len(translated) {
+		return ""
+	}
+	return translated[pos].template()
+}
+
+// findConcatChains locates simple `..` chains in a line of Lua code. Operands must be
+// string literals or simple expressions (names, field access, calls, indexing);
+// chains containing anything more complex are ignored.
+func findConcatChains(code string) []concatChain {
+	literals := luaStringPattern.FindAllStringSubmatchIndex(code, -1)
+	literalAt := func(pos int) []int {
+		for _, loc := range literals {
+			if pos >= loc[0] && pos < loc[1] {
+				return loc
+			}
+		}
+		return nil
+	}
+
+	// Find `..` operators outside string literals (excluding `...` varargs).
+	var ops []int
+	for i := 0; i+1 < len(code); i++ {
+		if loc := literalAt(i); loc != nil {
+			i = loc[1] - 1
+			continue
+		}
+		if code[i] == '.' && code[i+1] == '.' {
+			if (i+2 < len(code) && code[i+2] == '.') || (i > 0 && code[i-1] == '.') {
+				i += 2
+				continue
+			}
+			ops = append(ops, i)
+			i++
+		}
+	}
+
+	var chains []concatChain
+	extending := false // whether the last chain may continue with the next operator
+
+	for _, op := range ops {
+		left, okL := concatOperandBefore(code, op, literalAt)
+		right, okR := concatOperandAfter(code, op+2, literalAt)
+		if !okL || !okR {
+			extending = false
+			continue
+		}
+
+		if extending {
+			last := &chains[len(chains)-1]
+			if last.operands[len(last.operands)-1].start == left.start {
+				last.operands = append(last.operands, right)
+				last.end = right.end
+				continue
+			}
+		}
+
+		chains = append(chains, concatChain{
+			start:    left.start,
+			end:      right.end,
+			operands: []concatOperand{left, right},
+		})
+		extending = true
+	}
+
+	return chains
+}
+
+// concatOperandBefore reads the operand ending just before position pos.
+func concatOperandBefore(code string, pos int, literalAt func(int) []int) (concatOperand, bool) {
+	j := pos - 1
+	for j >= 0 && (code[j] == ' ' || code[j] == '\t') {
+		j--
+	}
+	if j < 0 {
+		return concatOperand{}, false
+	}
+
+	if loc := literalAt(j); loc != nil && loc[1]-1 == j {
+		return literalOperand(code, loc), true
+	}
+
+	end := j + 1
+scan:
+	for j >= 0 {
+		c := code[j]
+		switch {
+		case c == ')' || c == ']':
+			open := matchBackward(code, j, literalAt)
+			if open < 0 {
+				return concatOperand{}, false
+			}
+			j = open - 1
+		case c == '.' && j > 0 && code[j-1] == '.':
+			break scan
+		case isLuaNameChar(c) || c == '.' || c == ':':
+			j--
+		default:
+			break scan
+		}
+	}
+	start := j + 1
+	if start >= end {
+		return concatOperand{}, false
+	}
+	return concatOperand{start: start, end: end, value: code[start:end]}, true
+}
+
+// concatOperandAfter reads the operand starting at or after position pos.
+func concatOperandAfter(code string, pos int, literalAt func(int) []int) (concatOperand, bool) {
+	i := pos
+	for i < len(code) && (code[i] == ' ' || code[i] == '\t') {
+		i++
+	}
+	if i >= len(code) {
+		return concatOperand{}, false
+	}
+
+	if loc := literalAt(i); loc != nil && loc[0] == i {
+		return literalOperand(code, loc), true
+	}
+
+	start := i
+scan:
+	for i < len(code) {
+		c := code[i]
+		switch {
+		case c == '(' || c == '[':
+			if i == start {
+				return concatOperand{}, false
+			}
+			closeIdx := matchForward(code, i, literalAt)
+			if closeIdx < 0 {
+				return concatOperand{}, false
+			}
+			i = closeIdx + 1
+		case c == '.' && i+1 < len(code) && code[i+1] == '.':
+			break scan
+		case isLuaNameChar(c) || c == '.' || c == ':':
+			i++
+		default:
+			break scan
+		}
+	}
+	if i <= start {
+		return concatOperand{}, false
+	}
+	return concatOperand{start: start, end: i, value: code[start:i]}, true
+}
+
+func literalOperand(code string, loc []int) concatOperand {
+	op := concatOperand{start: loc[0], end: loc[1], literal: true, quote: code[loc[0]]}
+	if loc[2] >= 0 {
+		op.value = code[loc[2]:loc[3]]
+	} else {
+		op.value = code[loc[4]:loc[5]]
+	}
+	return op
+}
+
+// matchBackward finds the opening bracket matching the closing bracket at pos.
+func matchBackward(code string, pos int, literalAt func(int) []int) int {
+	depth := 0
+	for j := pos; j >= 0; j-- {
+		if loc := literalAt(j); loc != nil {
+			j = loc[0]
+			continue
+		}
+		switch code[j] {
+		case ')', ']':
+			depth++
+		case '(', '[':
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+// matchForward finds the closing bracket matching the opening bracket at pos.
+func matchForward(code string, pos int, literalAt func(int) []int) int {
+	depth := 0
+	for i := pos; i < len(code); i++ {
+		if loc := literalAt(i); loc != nil {
+			i = loc[1] - 1
+			continue
+		}
+		switch code[i] {
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isLuaNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
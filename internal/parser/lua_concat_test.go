@@ -0,0 +1,120 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLuaConcatRoundTrip(t *testing.T) {
+	tests := []struct {
+		name        string
+		line        string
+		template    string
+		translation string
+		want        string
+	}{
+		{
+			name:        "two parts",
+			line:        `msg = "获得" .. count`,
+			template:    "获得{0}",
+			translation: "Gained {0}",
+			want:        `msg = "Gained " .. count`,
+		},
+		{
+			name:        "three parts",
+			line:        `msg = "获得" .. count .. "经验"`,
+			template:    "获得{0}经验",
+			translation: "Gained {0} XP",
+			want:        `msg = "Gained " .. count .. " XP"`,
+		},
+		{
+			name:        "reordered expressions",
+			line:        `msg = player.name .. "对" .. target .. "造成伤害"`,
+			template:    "{0}对{1}造成伤害",
+			translation: "{1} took damage from {0}",
+			want:        `msg = target .. " took damage from " .. player.name`,
+		},
+		{
+			name:        "single quotes and calls",
+			line:        `show('等级' .. GetLevel(unit))`,
+			template:    "等级{0}",
+			translation: "Level {0}",
+			want:        `show('Level ' .. GetLevel(unit))`,
+		},
+		{
+			name:        "quote inside the translation",
+			line:        `msg = "获得" .. item .. "奖励"`,
+			template:    "获得{0}奖励",
+			translation: `Received the "{0}" reward`,
+			want:        `msg = "Received the \"" .. item .. "\" reward"`,
+		},
+		{
+			name:        "apostrophe in single quotes",
+			line:        `show('获得' .. name)`,
+			template:    "获得{0}",
+			translation: "You've got {0}",
+			want:        `show('You\'ve got ' .. name)`,
+		},
+		{
+			name:        "dropped placeholder keeps source",
+			line:        `msg = "获得" .. count .. "经验"`,
+			template:    "获得{0}经验",
+			translation: "Gained XP",
+			want:        `msg = "获得" .. count .. "经验"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTemp(t, "concat.lua", tt.line+"\n")
+			p := NewLuaParser()
+			result, err := p.Parse(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Texts) != 1 {
+				t.Fatalf("extracted %d texts, want 1: %+v", len(result.Texts), result.Texts)
+			}
+			et := result.Texts[0]
+			if et.Text != tt.template {
+				t.Errorf("template = %q, want %q", et.Text, tt.template)
+			}
+			if et.Context["concat"] == "" {
+				t.Errorf("context has no concat structure: %v", et.Context)
+			}
+
+			out, _, err := p.Reconstruct(result, map[string]string{tt.template: tt.translation})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.TrimRight(string(out), "\n"); got != tt.want {
+				t.Errorf("reconstructed %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLuaConcatIgnoresPlainChains(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want []string
+	}{
+		{"literals only", `msg = "获得" .. "经验"`, []string{"获得", "经验"}},
+		{"no Chinese", `msg = "Level " .. level`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewLuaParser().Parse(writeTemp(t, "plain.lua", tt.line+"\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, et := range result.Texts {
+				got = append(got, et.Text)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("texts = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
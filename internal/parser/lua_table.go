@@ -0,0 +1,566 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"rag-translator/internal/textutil"
+)
+
+// LuaMode selects how Lua files are parsed.
+type LuaMode string
+
+const (
+	// LuaModeLine scans each line for string literals and concatenation chains.
+	LuaModeLine LuaMode = "line"
+	// LuaModeTable tokenizes the whole file and walks its table constructors, so
+	// literals sharing a line or spanning lines are placed exactly and carry their
+	// table path. Concatenation chains are not merged into templates.
+	LuaModeTable LuaMode = "table"
+)
+
+// luaTokenKind classifies a Lua token.
+type luaTokenKind int
+
+const (
+	luaName luaTokenKind = iota
+	luaString
+	luaNumber
+	luaSymbol
+	luaLineComment  // -- comment, only returned by lexLua
+	luaBlockComment // --[[ ]] comment, only returned by lexLua
+)
+
+// luaToken is one Lua token. For strings, value is the raw content between the
+// delimiters and start is the offset of that content.
+type luaToken struct {
+	kind  luaTokenKind
+	value string
+	start int
+}
+
+// luaTableFrame is a table constructor being walked.
+type luaTableFrame struct {
+	path       string
+	index      int    // last positional index used
+	key        string // key of the field being read
+	fieldStart bool   // the next token starts a field
+	nest       int    // open parentheses and brackets within the field
+}
+
+// parseTable is Parse in LuaModeTable. Texts record the line and byte column of
+// their content, and Context["path"] holds the dotted table path of table fields,
+// e.g. skills.3.name.
+func (p *LuaParser) parseTable(filePath string) (*ParseResult, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("open lua file: %w", err)
+	}
+
+	result := &ParseResult{
+		FilePath: filePath,
+		FileType: "lua",
+		RawLines: splitRawLines(string(data)),
+	}
+	content := strings.Join(result.RawLines, "\n")
+	tokens, comments, err := lexLua(content)
+	if err != nil {
+		return nil, fmt.Errorf("tokenize lua file: %w", err)
+	}
+	lineStarts := lineOffsets(result.RawLines)
+
+	var stack []*luaTableFrame
+	usesFormat := false
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		var frame *luaTableFrame
+		if len(stack) > 0 {
+			frame = stack[len(stack)-1]
+		}
+
+		if frame != nil && frame.fieldStart && frame.nest == 0 {
+			if tok.kind == luaSymbol && tok.value == "}" {
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			frame.fieldStart = false
+			switch {
+			case tok.kind == luaName && tokenIs(tokens, i+1, "="):
+				frame.key = tok.value
+				i++
+				continue
+			case tok.kind == luaSymbol && tok.value == "[" && i+3 < len(tokens) &&
+				(tokens[i+1].kind == luaString || tokens[i+1].kind == luaNumber) &&
+				tokenIs(tokens, i+2, "]") && tokenIs(tokens, i+3, "="):
+				// A literal key is never translated: code looks the field up by it.
+				frame.key = tokens[i+1].value
+				i += 3
+				continue
+			case tok.kind == luaSymbol && tok.value == "[":
+				frame.key = "?"
+			default:
+				frame.index++
+				frame.key = strconv.Itoa(frame.index)
+			}
+		}
+
+		switch tok.kind {
+		case luaName:
+			if tok.value == "format" && tokenIs(tokens, i+1, "(") {
+				usesFormat = true
+			}
+		case luaString:
+			if tok.value == "" || !textutil.ContainsSource(tok.value) {
+				continue
+			}
+			line := sort.SearchInts(lineStarts, tok.start+1) - 1
+			ctx := map[string]string{"file": filePath}
+			if frame != nil {
+				ctx["path"] = joinPath(frame.path, frame.key)
+			}
+			if tokenIs(tokens, i-1, "(") {
+				if name := luaNameBefore(tokens, i-1); name != "" {
+					ctx["function"] = name
+				}
+			}
+			result.Texts = append(result.Texts, ExtractedText{
+				Text:    tok.value,
+				File:    filePath,
+				Line:    line + 1,
+				Column:  tok.start - lineStarts[line],
+				Context: ctx,
+			})
+		case luaSymbol:
+			switch tok.value {
+			case "{":
+				path := luaNameBefore(tokens, i)
+				if tokenIs(tokens, i-1, "=") {
+					path = luaNameBefore(tokens, i-1)
+				}
+				if frame != nil {
+					path = joinPath(frame.path, frame.key)
+				}
+				stack = append(stack, &luaTableFrame{path: path, fieldStart: true})
+			case "}":
+				if frame != nil {
+					stack = stack[:len(stack)-1]
+				}
+			case "(", "[":
+				if frame != nil {
+					frame.nest++
+				}
+			case ")", "]":
+				if frame != nil && frame.nest > 0 {
+					frame.nest--
+				}
+			case ",", ";":
+				if frame != nil && frame.nest == 0 {
+					frame.fieldStart = true
+					frame.key = ""
+				}
+			}
+		}
+	}
+
+	if usesFormat {
+		for _, et := range result.Texts {
+			et.Context["string_format"] = "true"
+		}
+	}
+
+	if p.includeComments {
+		for _, c := range luaCommentLines(comments) {
+			if !textutil.ContainsSource(c.value) {
+				continue
+			}
+			line := sort.SearchInts(lineStarts, c.start+1) - 1
+			result.Texts = append(result.Texts, ExtractedText{
+				Text:    c.value,
+				File:    filePath,
+				Line:    line + 1,
+				Column:  c.start - lineStarts[line],
+				Context: map[string]string{"file": filePath, "comment": luaCommentKind(c)},
+			})
+		}
+		// Reconstruction rewrites the texts in file order.
+		sort.SliceStable(result.Texts, func(i, j int) bool {
+			a, b := result.Texts[i], result.Texts[j]
+			if a.Line != b.Line {
+				return a.Line < b.Line
+			}
+			return a.Column < b.Column
+		})
+	}
+	return result, nil
+}
+
+// luaCommentLines splits comments into one token per line holding the trimmed
+// text of that line, as luaCommentText does in line mode. Blank lines are dropped.
+func luaCommentLines(comments []luaToken) []luaToken {
+	var lines []luaToken
+	for _, c := range comments {
+		offset := 0
+		for _, piece := range strings.Split(c.value, "\n") {
+			if text := commentText(piece); text != "" {
+				start := c.start + offset + strings.Index(piece, text)
+				lines = append(lines, luaToken{kind: c.kind, value: text, start: start})
+			}
+			offset += len(piece) + 1
+		}
+	}
+	return lines
+}
+
+// luaCommentKind returns the Context["comment"] value of a comment token.
+func luaCommentKind(c luaToken) string {
+	if c.kind == luaBlockComment {
+		return "block"
+	}
+	return "line"
+}
+
+// reconstructTable is Reconstruct in LuaModeTable: it rewrites the content of each
+// literal at the position recorded by parseTable.
+func (p *LuaParser) reconstructTable(result *ParseResult, translations map[string]string) ([]byte, ReconstructReport, error) {
+	content := strings.Join(result.RawLines, "\n")
+	lineStarts := lineOffsets(result.RawLines)
+
+	type edit struct {
+		start, end int
+		text       string
+	}
+	var edits []edit
+	var report ReconstructReport
+	for _, et := range result.Texts {
+		start, ok := textOffset(content, lineStarts, et)
+		if !ok {
+			report.outOfRange(et.Text)
+			continue
+		}
+		translated, ok := translations[et.Text]
+		if !ok {
+			report.missing(et.Text)
+			continue
+		}
+		var escaped string
+		if kind := et.Context["comment"]; kind != "" {
+			// A comment translation must stay on its line and inside the comment.
+			escaped = singleLine(translated)
+			ok = kind == "line" || !luaMultilineCommentClose.MatchString(escaped)
+		} else {
+			escaped, ok = escapeLuaContent(translated, content[:start])
+		}
+		if !ok {
+			report.outOfRange(et.Text)
+			continue
+		}
+		edits = append(edits, edit{start, start + len(et.Text), escaped})
+		report.applied()
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, e := range edits {
+		sb.WriteString(content[last:e.start])
+		sb.WriteString(e.text)
+		last = e.end
+	}
+	sb.WriteString(content[last:])
+	sb.WriteString("\n")
+	return []byte(sb.String()), report, nil
+}
+
+// extractTableTranslations is ExtractTranslations in LuaModeTable. The n-th string
+// literal of the source is matched with the n-th literal of the translated file.
+func (p *LuaParser) extractTableTranslations(result *ParseResult, translatedLines []string) []string {
+	values := make([]string, len(result.Texts))
+	content := strings.Join(result.RawLines, "\n")
+	srcTokens, srcComments, err := lexLua(content)
+	if err != nil {
+		return values
+	}
+	dstTokens, dstComments, err := lexLua(strings.Join(translatedLines, "\n"))
+	if err != nil {
+		return values
+	}
+	dstLiterals := luaStringValues(dstTokens)
+	dstCommentLines := luaCommentLines(dstComments)
+
+	ordinal := make(map[int]int) // content offset → index among string literals
+	for _, tok := range srcTokens {
+		if tok.kind == luaString {
+			ordinal[tok.start] = len(ordinal)
+		}
+	}
+	commentOrdinal := make(map[int]int) // content offset → index among comment lines
+	for _, c := range luaCommentLines(srcComments) {
+		commentOrdinal[c.start] = len(commentOrdinal)
+	}
+	lineStarts := lineOffsets(result.RawLines)
+	for i, et := range result.Texts {
+		start, ok := textOffset(content, lineStarts, et)
+		if !ok {
+			continue
+		}
+		if et.Context["comment"] != "" {
+			if n, ok := commentOrdinal[start]; ok && n < len(dstCommentLines) {
+				values[i] = dstCommentLines[n].value
+			}
+			continue
+		}
+		if n, ok := ordinal[start]; ok && n < len(dstLiterals) {
+			values[i] = dstLiterals[n]
+		}
+	}
+	return values
+}
+
+// tokenizeLua splits Lua source into tokens, dropping whitespace and comments.
+func tokenizeLua(src string) ([]luaToken, error) {
+	tokens, _, err := lexLua(src)
+	return tokens, err
+}
+
+// lexLua is tokenizeLua that also returns the comments, each as a token whose
+// value is its content between the delimiters.
+func lexLua(src string) (tokens, comments []luaToken, err error) {
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == '\v':
+			i++
+		case strings.HasPrefix(src[i:], "--"):
+			if level, ok := longBracketLevel(src, i+2); ok {
+				end, ok := longBracketEnd(src, i+2, level)
+				if !ok {
+					return nil, nil, fmt.Errorf("unterminated long comment at offset %d", i)
+				}
+				open := i + level + 4
+				comments = append(comments, luaToken{kind: luaBlockComment, value: src[open : end-level-2], start: open})
+				i = end
+				continue
+			}
+			end := len(src)
+			if nl := strings.IndexByte(src[i:], '\n'); nl >= 0 {
+				end = i + nl
+			}
+			comments = append(comments, luaToken{kind: luaLineComment, value: src[i+2 : end], start: i + 2})
+			i = end
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(src) && src[j] != c {
+				if src[j] == '\\' {
+					j++
+				} else if src[j] == '\n' {
+					return nil, nil, fmt.Errorf("unterminated string at offset %d", i)
+				}
+				j++
+			}
+			if j >= len(src) {
+				return nil, nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			tokens = append(tokens, luaToken{kind: luaString, value: src[i+1 : j], start: i + 1})
+			i = j + 1
+		case c == '[':
+			level, ok := longBracketLevel(src, i)
+			if !ok {
+				tokens = append(tokens, luaToken{kind: luaSymbol, value: "[", start: i})
+				i++
+				continue
+			}
+			end, ok := longBracketEnd(src, i, level)
+			if !ok {
+				return nil, nil, fmt.Errorf("unterminated long string at offset %d", i)
+			}
+			open := i + level + 2
+			tokens = append(tokens, luaToken{kind: luaString, value: src[open : end-level-2], start: open})
+			i = end
+		case isLuaNameStart(c):
+			j := i + 1
+			for j < len(src) && (isLuaNameStart(src[j]) || isDigit(src[j])) {
+				j++
+			}
+			tokens = append(tokens, luaToken{kind: luaName, value: src[i:j], start: i})
+			i = j
+		case isDigit(c) || (c == '.' && i+1 < len(src) && isDigit(src[i+1])):
+			j := i + 1
+			for j < len(src) && (isLuaNameStart(src[j]) || isDigit(src[j]) || src[j] == '.' ||
+				((src[j] == '-' || src[j] == '+') && strings.ContainsRune("eEpP", rune(src[j-1])))) {
+				j++
+			}
+			tokens = append(tokens, luaToken{kind: luaNumber, value: src[i:j], start: i})
+			i = j
+		default:
+			n := 1
+			for _, op := range []string{"...", "..", "==", "~=", "<=", ">=", "::", "//", "<<", ">>"} {
+				if strings.HasPrefix(src[i:], op) {
+					n = len(op)
+					break
+				}
+			}
+			tokens = append(tokens, luaToken{kind: luaSymbol, value: src[i : i+n], start: i})
+			i += n
+		}
+	}
+	return tokens, comments, nil
+}
+
+// longBracketLevel reports whether a long bracket [[, [=[, ... opens at src[i] and
+// returns its number of equals signs.
+func longBracketLevel(src string, i int) (int, bool) {
+	if i >= len(src) || src[i] != '[' {
+		return 0, false
+	}
+	j := i + 1
+	for j < len(src) && src[j] == '=' {
+		j++
+	}
+	if j < len(src) && src[j] == '[' {
+		return j - i - 1, true
+	}
+	return 0, false
+}
+
+// longBracketEnd returns the offset just past the long bracket of level opened at
+// src[i].
+func longBracketEnd(src string, i, level int) (int, bool) {
+	closing := "]" + strings.Repeat("=", level) + "]"
+	idx := strings.Index(src[i+level+2:], closing)
+	if idx < 0 {
+		return 0, false
+	}
+	return i + level + 2 + idx + len(closing), true
+}
+
+// escapeLuaContent prepares translated to replace the content of the literal whose
+// opening delimiter ends before: quotes and newlines are escaped in quoted strings.
+// It returns false when translated cannot be placed inside a long bracket.
+func escapeLuaContent(translated, before string) (string, bool) {
+	if before == "" {
+		return translated, true
+	}
+	switch quote := before[len(before)-1]; quote {
+	case '"', '\'':
+		var sb strings.Builder
+		escaped := false
+		for i := 0; i < len(translated); i++ {
+			c := translated[i]
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == quote:
+				sb.WriteByte('\\')
+			case c == '\n':
+				sb.WriteString(`\n`)
+				continue
+			}
+			sb.WriteByte(c)
+		}
+		return sb.String(), true
+	default:
+		// Long bracket: the content may not contain its closing bracket.
+		rest := strings.TrimSuffix(before, "[")
+		level := len(rest) - len(strings.TrimRight(rest, "="))
+		closing := "]" + strings.Repeat("=", level) + "]"
+		return translated, !strings.Contains(translated, closing)
+	}
+}
+
+// luaNameBefore returns the dotted name, such as ui.ShowTip, ending just before
+// tokens[i], or "" when none does.
+func luaNameBefore(tokens []luaToken, i int) string {
+	var parts []string
+	j := i - 1
+	for j >= 0 && tokens[j].kind == luaName && !luaKeywords[tokens[j].value] {
+		parts = append([]string{tokens[j].value}, parts...)
+		if j > 0 && tokens[j-1].kind == luaSymbol && (tokens[j-1].value == "." || tokens[j-1].value == ":") {
+			parts = append([]string{tokens[j-1].value}, parts...)
+			j -= 2
+			continue
+		}
+		break
+	}
+	return strings.Join(parts, "")
+}
+
+// luaKeywords are the reserved words of Lua, which never name a table or function.
+var luaKeywords = map[string]bool{
+	"and": true, "break": true, "do": true, "else": true, "elseif": true, "end": true,
+	"false": true, "for": true, "function": true, "goto": true, "if": true, "in": true,
+	"local": true, "nil": true, "not": true, "or": true, "repeat": true, "return": true,
+	"then": true, "true": true, "until": true, "while": true,
+}
+
+// luaStringValues returns the content of every string literal in tokens.
+func luaStringValues(tokens []luaToken) []string {
+	var values []string
+	for _, tok := range tokens {
+		if tok.kind == luaString {
+			values = append(values, tok.value)
+		}
+	}
+	return values
+}
+
+// textOffset returns the content offset of et and whether et.Text is still there.
+func textOffset(content string, lineStarts []int, et ExtractedText) (int, bool) {
+	idx := et.Line - 1
+	if idx < 0 || idx >= len(lineStarts) || et.Column < 0 {
+		return 0, false
+	}
+	start := lineStarts[idx] + et.Column
+	return start, strings.HasPrefix(content[min(start, len(content)):], et.Text)
+}
+
+// splitRawLines splits file content into lines the way bufio.ScanLines does.
+func splitRawLines(data string) []string {
+	data = strings.TrimSuffix(data, "\n")
+	if data == "" {
+		return nil
+	}
+	lines := strings.Split(data, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSuffix(l, "\r")
+	}
+	return lines
+}
+
+// lineOffsets returns the offset at which each line starts in the lines joined by "\n".
+func lineOffsets(lines []string) []int {
+	offsets := make([]int, len(lines))
+	pos := 0
+	for i, l := range lines {
+		offsets[i] = pos
+		pos += len(l) + 1
+	}
+	return offsets
+}
+
+// joinPath appends key to a dotted path.
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	if key == "" {
+		return path
+	}
+	return path + "." + key
+}
+
+func tokenIs(tokens []luaToken, i int, symbol string) bool {
+	return i >= 0 && i < len(tokens) && tokens[i].kind == luaSymbol && tokens[i].value == symbol
+}
+
+func isLuaNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,50 @@
+package parser
+
+import (
+	"testing"
+
+	"rag-translator/internal/textutil"
+)
+
+func TestPaddingRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		parser  Parser
+		content string
+		want    string
+	}{
+		{"lua", "ui.lua", NewLuaParser(), "label = \"  确认  \"\n", "label = \"  Confirm  \"\n"},
+		{"ini", "ui.ini", NewINIParser(), "[ui]\nlabel =   确认  \n", "[ui]\nlabel =   Confirm  \n"},
+		{"txt", "ui.txt", NewTXTParser(), "  确认  \n", "  Confirm  \n"},
+		{"tsv", "ui.txt", NewTXTParser().AsTSV(), "1\t  确认  \n", "1\t  Confirm  \n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.parser.Parse(writeTemp(t, tt.file, tt.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Texts) != 1 {
+				t.Fatalf("extracted %d texts, want 1", len(result.Texts))
+			}
+			text := result.Texts[0].Text
+			if _, core, _ := textutil.SplitPadding(text); core != "确认" {
+				t.Errorf("extracted %q, want core text 确认", text)
+			}
+
+			// The translate command translates the core and repads it to the source.
+			translations := map[string]string{text: textutil.Repad(text, "Confirm")}
+			out, report, err := tt.parser.Reconstruct(result, translations)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Applied != 1 {
+				t.Errorf("report = %+v, want 1 applied", report)
+			}
+			if got := string(out); got != tt.want {
+				t.Errorf("reconstructed %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
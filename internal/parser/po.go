@@ -0,0 +1,369 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"rag-translator/internal/textutil"
+)
+
+// POParser extracts translatable strings from gettext .po/.pot catalogs.
+// Chinese msgid values are extracted; translations are written into msgstr.
+type POParser struct{}
+
+func NewPOParser() *POParser { return &POParser{} }
+
+func (p *POParser) CanParse(ext string) bool {
+	return ext == ".po" || ext == ".pot"
+}
+
+// poMsgstr locates one msgstr block (keyword line plus continuation lines).
+type poMsgstr struct {
+	start, end int // 0-based, inclusive line range
+	index      int // plural index for msgstr[n], -1 for a plain msgstr
+}
+
+// poEntry is one catalog message with the line positions needed to rewrite it.
+type poEntry struct {
+	msgctxt    string
+	msgid      string
+	msgidLine  int // 0-based
+	plural     string
+	pluralLine int // 0-based, -1 if no msgid_plural
+	msgstrs    []poMsgstr
+}
+
+func (p *POParser) Parse(filePath string) (*ParseResult, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("open po file: %w", err)
+	}
+	defer file.Close()
+
+	result := &ParseResult{
+		FilePath: filePath,
+		FileType: "po",
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		result.RawLines = append(result.RawLines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan po file: %w", err)
+	}
+
+	for _, e := range scanPOEntries(result.RawLines) {
+		if e.msgid == "" || !textutil.ContainsSource(e.msgid) {
+			continue
+		}
+
+		ctx := map[string]string{
+			"file":   filePath,
+			"format": "po",
+		}
+		if e.msgctxt != "" {
+			ctx["msgctxt"] = e.msgctxt
+		}
+
+		result.Texts = append(result.Texts, ExtractedText{
+			Text:    e.msgid,
+			File:    filePath,
+			Line:    e.msgidLine + 1,
+			Column:  -1,
+			Context: ctx,
+		})
+
+		if e.pluralLine >= 0 && textutil.ContainsSource(e.plural) {
+			pluralCtx := make(map[string]string, len(ctx)+1)
+			for k, v := range ctx {
+				pluralCtx[k] = v
+			}
+			pluralCtx["plural"] = "true"
+
+			result.Texts = append(result.Texts, ExtractedText{
+				Text:    e.plural,
+				File:    filePath,
+				Line:    e.pluralLine + 1,
+				Column:  -1,
+				Context: pluralCtx,
+			})
+		}
+	}
+
+	return result, nil
+}
+
+func (p *POParser) Reconstruct(result *ParseResult, translations map[string]string) ([]byte, ReconstructReport, error) {
+	// replacements maps a msgstr block's first line to its rewritten lines.
+	type replacement struct {
+		end   int
+		lines []string
+	}
+	replacements := make(map[int]replacement)
+	// located holds the msgid and msgid_plural values found in the catalog, and
+	// written those whose msgstr blocks were rewritten.
+	located := make(map[string]bool)
+	written := make(map[string]bool)
+
+	for _, e := range scanPOEntries(result.RawLines) {
+		located[e.msgid] = true
+		if e.plural != "" {
+			located[e.plural] = true
+		}
+
+		singular, hasSingular := translations[e.msgid]
+		if e.msgid == "" || !hasSingular {
+			continue
+		}
+		plural, hasPlural := translations[e.plural]
+		if !hasPlural {
+			plural = singular
+		}
+		written[e.msgid] = true
+		if e.plural != "" {
+			written[e.plural] = true
+		}
+
+		for _, ms := range e.msgstrs {
+			keyword := "msgstr"
+			text := singular
+			if ms.index >= 0 {
+				keyword = fmt.Sprintf("msgstr[%d]", ms.index)
+				if ms.index > 0 {
+					text = plural
+				}
+			}
+			replacements[ms.start] = replacement{end: ms.end, lines: formatPOString(keyword, text)}
+		}
+	}
+
+	var report ReconstructReport
+	for _, et := range result.Texts {
+		switch {
+		case !located[et.Text]:
+			report.outOfRange(et.Text)
+		case written[et.Text]:
+			report.applied()
+		default:
+			report.missing(et.Text)
+		}
+	}
+
+	var out []string
+	for i := 0; i < len(result.RawLines); i++ {
+		if r, ok := replacements[i]; ok {
+			out = append(out, r.lines...)
+			i = r.end
+			continue
+		}
+		out = append(out, result.RawLines[i])
+	}
+
+	return []byte(strings.Join(out, "\n") + "\n"), report, nil
+}
+
+func (p *POParser) ExtractTranslations(result *ParseResult, translatedLines []string) []string {
+	// msgid and msgid_plural are never rewritten, so messages are located by key.
+	singular := make(map[string]string)
+	plural := make(map[string]string)
+	for _, e := range scanPOEntries(translatedLines) {
+		for _, ms := range e.msgstrs {
+			value := poBlockValue(translatedLines, ms)
+			switch {
+			case ms.index <= 0:
+				singular[e.msgid] = value
+			case ms.index == 1 && e.plural != "":
+				plural[e.plural] = value
+			}
+		}
+	}
+
+	values := make([]string, len(result.Texts))
+	for i, et := range result.Texts {
+		if et.Context["plural"] == "true" {
+			values[i] = plural[et.Text]
+		} else {
+			values[i] = singular[et.Text]
+		}
+	}
+	return values
+}
+
+// poBlockValue decodes the string value of a msgstr block.
+func poBlockValue(lines []string, ms poMsgstr) string {
+	var sb strings.Builder
+	for i := ms.start; i <= ms.end && i < len(lines); i++ {
+		literal := strings.TrimSpace(lines[i])
+		if i == ms.start {
+			_, literal, _ = strings.Cut(literal, " ")
+			literal = strings.TrimSpace(literal)
+		}
+		if s, ok := unquotePO(literal); ok {
+			sb.WriteString(s)
+		}
+	}
+	return sb.String()
+}
+
+// scanPOEntries walks catalog lines and returns every message with its positions.
+// Comment lines (including obsolete "#~" entries) are left untouched.
+func scanPOEntries(lines []string) []poEntry {
+	var entries []poEntry
+	var cur *poEntry
+	// target receives continuation strings for the keyword currently being read.
+	var target *string
+	var curMsgstr *poMsgstr
+
+	flush := func() {
+		if cur != nil && cur.msgidLine >= 0 {
+			entries = append(entries, *cur)
+		}
+		cur = nil
+		target = nil
+		curMsgstr = nil
+	}
+	ensure := func() {
+		if cur == nil {
+			cur = &poEntry{msgidLine: -1, pluralLine: -1}
+		}
+	}
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			flush()
+
+		case strings.HasPrefix(trimmed, "#"):
+			// A comment after a msgstr starts a new entry.
+			if cur != nil && len(cur.msgstrs) > 0 {
+				flush()
+			}
+			target = nil
+			curMsgstr = nil
+
+		case strings.HasPrefix(trimmed, `"`):
+			s, ok := unquotePO(trimmed)
+			if !ok {
+				continue
+			}
+			if target != nil {
+				*target += s
+			}
+			if curMsgstr != nil {
+				curMsgstr.end = i
+				cur.msgstrs[len(cur.msgstrs)-1] = *curMsgstr
+			}
+
+		default:
+			keyword, rest, found := strings.Cut(trimmed, " ")
+			if !found {
+				continue
+			}
+			value, ok := unquotePO(strings.TrimSpace(rest))
+			if !ok {
+				continue
+			}
+
+			switch {
+			case keyword == "msgctxt":
+				if cur != nil && len(cur.msgstrs) > 0 {
+					flush()
+				}
+				ensure()
+				cur.msgctxt = value
+				target = &cur.msgctxt
+				curMsgstr = nil
+			case keyword == "msgid":
+				if cur != nil && len(cur.msgstrs) > 0 {
+					flush()
+				}
+				ensure()
+				cur.msgid = value
+				cur.msgidLine = i
+				target = &cur.msgid
+				curMsgstr = nil
+			case keyword == "msgid_plural":
+				ensure()
+				cur.plural = value
+				cur.pluralLine = i
+				target = &cur.plural
+				curMsgstr = nil
+			case strings.HasPrefix(keyword, "msgstr"):
+				ensure()
+				ms := poMsgstr{start: i, end: i, index: -1}
+				if idx, ok := strings.CutPrefix(keyword, "msgstr["); ok {
+					n, err := strconv.Atoi(strings.TrimSuffix(idx, "]"))
+					if err != nil {
+						continue
+					}
+					ms.index = n
+				}
+				cur.msgstrs = append(cur.msgstrs, ms)
+				curMsgstr = &ms
+				target = nil
+			}
+		}
+	}
+	flush()
+
+	return entries
+}
+
+// unquotePO decodes a double-quoted PO string literal.
+func unquotePO(s string) (string, bool) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", false
+	}
+	body := s[1 : len(s)-1]
+
+	var sb strings.Builder
+	for i := 0; i < len(body); i++ {
+		ch := body[i]
+		if ch != '\\' || i+1 >= len(body) {
+			sb.WriteByte(ch)
+			continue
+		}
+		i++
+		switch body[i] {
+		case 'n':
+			sb.WriteByte('\n')
+		case 't':
+			sb.WriteByte('\t')
+		case 'r':
+			sb.WriteByte('\r')
+		default:
+			sb.WriteByte(body[i])
+		}
+	}
+	return sb.String(), true
+}
+
+// escapePO encodes a string for use inside a double-quoted PO literal.
+func escapePO(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+	return r.Replace(s)
+}
+
+// formatPOString renders keyword and value as PO lines, using the multi-line
+// `keyword ""` continuation form when the value contains newlines.
+func formatPOString(keyword, value string) []string {
+	if !strings.Contains(strings.TrimSuffix(value, "\n"), "\n") {
+		return []string{fmt.Sprintf(`%s "%s"`, keyword, escapePO(value))}
+	}
+
+	lines := []string{keyword + ` ""`}
+	for _, part := range strings.SplitAfter(value, "\n") {
+		if part == "" {
+			continue
+		}
+		lines = append(lines, `"`+escapePO(part)+`"`)
+	}
+	return lines
+}
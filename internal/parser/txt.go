@@ -2,8 +2,11 @@ package parser
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
 	"strings"
 	"unicode/utf8"
 
@@ -11,9 +14,85 @@ import (
 )
 
 // TXTParser handles both plain text and tab-separated game data files.
-type TXTParser struct{}
+type TXTParser struct {
+	streamThreshold int64            // files larger than this many bytes are streamed; 0 disables
+	columns         *ColumnSelection // optional TSV column filter, nil means every column
+	headerMode      HeaderMode
+	forceTSV        bool // treat every file as TSV instead of detecting it
+}
+
+// HeaderMode selects whether the first row of a TSV file is a header that must
+// be kept verbatim instead of translated.
+type HeaderMode string
+
+const (
+	// HeaderNever translates the first row like any other.
+	HeaderNever HeaderMode = "never"
+	// HeaderAlways treats the first row as a header.
+	HeaderAlways HeaderMode = "always"
+	// HeaderAuto treats the first row as a header when detectHeader says so.
+	HeaderAuto HeaderMode = "auto"
+)
 
-func NewTXTParser() *TXTParser { return &TXTParser{} }
+// ColumnSelection restricts which 0-based TSV columns are translated. When Only is
+// non-empty just those columns are considered; columns in Skip are never translated.
+type ColumnSelection struct {
+	Only map[int]bool
+	Skip map[int]bool
+}
+
+// allows reports whether column col may be translated.
+func (s *ColumnSelection) allows(col int) bool {
+	if s == nil {
+		return true
+	}
+	if len(s.Only) > 0 && !s.Only[col] {
+		return false
+	}
+	return !s.Skip[col]
+}
+
+func NewTXTParser() *TXTParser { return &TXTParser{headerMode: HeaderNever} }
+
+// SetHeaderMode selects how the first row of TSV files is handled.
+func (p *TXTParser) SetHeaderMode(mode HeaderMode) {
+	p.headerMode = mode
+}
+
+// SetStreamThreshold makes files larger than n bytes parse and reconstruct line by
+// line without holding their content in memory. Zero disables streaming.
+func (p *TXTParser) SetStreamThreshold(n int64) {
+	p.streamThreshold = n
+}
+
+// WithColumns returns a copy of the parser that only translates the TSV columns
+// allowed by sel. Plain-text files are unaffected.
+func (p *TXTParser) WithColumns(sel ColumnSelection) *TXTParser {
+	c := *p
+	c.columns = &sel
+	return &c
+}
+
+// AsTSV returns a copy of the parser that treats every file as tab-separated,
+// for files too short or irregular for detection to recognise.
+func (p *TXTParser) AsTSV() *TXTParser {
+	c := *p
+	c.forceTSV = true
+	return &c
+}
+
+// maxLineSize bounds a single line read from a txt file.
+const maxLineSize = 4 * 1024 * 1024
+
+// tsvSampleLines is how many leading lines detectTSV inspects.
+const tsvSampleLines = 20
+
+// newLineScanner returns a scanner that accepts lines up to maxLineSize.
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, maxLineSize), maxLineSize)
+	return scanner
+}
 
 func (p *TXTParser) CanParse(ext string) bool {
 	return ext == ".txt"
@@ -26,9 +105,18 @@ func (p *TXTParser) Parse(filePath string) (*ParseResult, error) {
 	}
 	defer file.Close()
 
+	if p.streamThreshold > 0 {
+		info, err := file.Stat()
+		if err != nil {
+			return nil, fmt.Errorf("stat txt file: %w", err)
+		}
+		if info.Size() > p.streamThreshold {
+			return p.parseStreaming(file, filePath)
+		}
+	}
+
 	var rawLines []string
-	scanner := bufio.NewScanner(file)
-	scanner.Buffer(make([]byte, 0, 4*1024*1024), 4*1024*1024)
+	scanner := newLineScanner(file)
 	for scanner.Scan() {
 		rawLines = append(rawLines, scanner.Text())
 	}
@@ -37,7 +125,7 @@ func (p *TXTParser) Parse(filePath string) (*ParseResult, error) {
 	}
 
 	// Detect whether this is a tab-separated file.
-	isTSV := detectTSV(rawLines)
+	isTSV := p.forceTSV || detectTSV(rawLines)
 
 	result := &ParseResult{
 		FilePath: filePath,
@@ -46,6 +134,7 @@ func (p *TXTParser) Parse(filePath string) (*ParseResult, error) {
 
 	if isTSV {
 		result.FileType = "tsv"
+		result.HasHeader = p.hasHeader(rawLines)
 		p.parseTSV(result, filePath)
 	} else {
 		result.FileType = "txt"
@@ -62,7 +151,7 @@ func detectTSV(lines []string) bool {
 	}
 
 	tabCounts := make(map[int]int)
-	sampleSize := min(len(lines), 20)
+	sampleSize := min(len(lines), tsvSampleLines)
 	nonEmptyLines := 0
 
 	for i := 0; i < sampleSize; i++ {
@@ -93,63 +182,185 @@ func detectTSV(lines []string) bool {
 	return float64(maxCount)/float64(nonEmptyLines) > 0.6
 }
 
-func (p *TXTParser) parseTSV(result *ParseResult, filePath string) {
-	for lineNum, line := range result.RawLines {
+// hasHeader applies the header mode to the leading lines of a TSV file.
+func (p *TXTParser) hasHeader(lines []string) bool {
+	switch p.headerMode {
+	case HeaderAlways:
+		return len(lines) > 0 && strings.TrimSpace(lines[0]) != ""
+	case HeaderAuto:
+		return detectHeader(lines)
+	default:
+		return false
+	}
+}
+
+// detectHeader guesses whether the first line of a TSV file is a header: game data
+// tables key rows by a numeric ID in the first column, so a first row whose first
+// column is not numeric above mostly-numeric IDs is taken to be column names.
+func detectHeader(lines []string) bool {
+	if len(lines) < 2 || strings.TrimSpace(lines[0]) == "" {
+		return false
+	}
+	if isNumericCell(firstColumn(lines[0])) {
+		return false
+	}
+
+	numeric, rows := 0, 0
+	for _, line := range lines[1:min(len(lines), tsvSampleLines)] {
 		if strings.TrimSpace(line) == "" {
 			continue
 		}
+		rows++
+		if isNumericCell(firstColumn(line)) {
+			numeric++
+		}
+	}
+	return rows > 0 && float64(numeric)/float64(rows) >= 0.8
+}
 
-		cols := strings.Split(line, "\t")
-		for colIdx, col := range cols {
-			if !isTranslatableColumn(col) {
-				continue
-			}
+// firstColumn returns the text before the first tab.
+func firstColumn(line string) string {
+	col, _, _ := strings.Cut(line, "\t")
+	return strings.TrimSpace(col)
+}
 
-			ctx := map[string]string{
-				"file":   filePath,
-				"format": "tsv",
-			}
-			if len(cols) > 0 && colIdx > 0 {
-				ctx["id"] = cols[0]
-			}
+// isNumericCell reports whether a cell is an integer ID.
+func isNumericCell(s string) bool {
+	_, err := strconv.ParseInt(s, 10, 64)
+	return err == nil
+}
 
-			result.Texts = append(result.Texts, ExtractedText{
-				Text:    col,
-				File:    filePath,
-				Line:    lineNum + 1,
-				Column:  colIdx,
-				Context: ctx,
-			})
+func (p *TXTParser) parseTSV(result *ParseResult, filePath string) {
+	for lineNum, line := range result.RawLines {
+		if lineNum == 0 && result.HasHeader {
+			continue
 		}
+		result.Texts = append(result.Texts, p.tsvLineTexts(line, lineNum+1, filePath)...)
 	}
 }
 
 func (p *TXTParser) parsePlainText(result *ParseResult, filePath string) {
 	for lineNum, line := range result.RawLines {
-		trimmed := strings.TrimSpace(line)
-		if trimmed == "" || !textutil.ContainsChinese(trimmed) {
+		if et, ok := plainLineText(line, lineNum+1, filePath); ok {
+			result.Texts = append(result.Texts, et)
+		}
+	}
+}
+
+// tsvLineTexts extracts the translatable columns of one TSV line.
+func (p *TXTParser) tsvLineTexts(line string, lineNum int, filePath string) []ExtractedText {
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+
+	var texts []ExtractedText
+	cols := strings.Split(line, "\t")
+	for colIdx, col := range cols {
+		if !p.columns.allows(colIdx) || !isTranslatableColumn(col) {
 			continue
 		}
 
 		ctx := map[string]string{
 			"file":   filePath,
-			"format": "txt",
+			"format": "tsv",
+		}
+		if len(cols) > 0 && colIdx > 0 {
+			ctx["id"] = cols[0]
 		}
 
-		result.Texts = append(result.Texts, ExtractedText{
-			Text:    trimmed,
+		texts = append(texts, ExtractedText{
+			Text:    col,
 			File:    filePath,
-			Line:    lineNum + 1,
-			Column:  -1,
+			Line:    lineNum,
+			Column:  colIdx,
 			Context: ctx,
 		})
 	}
+	return texts
+}
+
+// plainLineText extracts one plain-text line if it contains source text.
+func plainLineText(line string, lineNum int, filePath string) (ExtractedText, bool) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || !textutil.ContainsSource(trimmed) {
+		return ExtractedText{}, false
+	}
+
+	ctx := map[string]string{
+		"file":   filePath,
+		"format": "txt",
+	}
+
+	return ExtractedText{
+		Text:    trimmed,
+		File:    filePath,
+		Line:    lineNum,
+		Column:  -1,
+		Context: ctx,
+	}, true
+}
+
+// parseStreaming extracts texts from a large file in one pass without keeping its
+// lines. Only the first occurrence of each text is recorded, since streamed
+// reconstruction translates by value rather than by position.
+func (p *TXTParser) parseStreaming(file *os.File, filePath string) (*ParseResult, error) {
+	scanner := newLineScanner(file)
+
+	// Sample the head of the file for TSV detection.
+	var head []string
+	for len(head) < tsvSampleLines && scanner.Scan() {
+		head = append(head, scanner.Text())
+	}
+	isTSV := p.forceTSV || detectTSV(head)
+
+	result := &ParseResult{
+		FilePath: filePath,
+		FileType: "txt",
+		Streamed: true,
+	}
+	if isTSV {
+		result.FileType = "tsv"
+		result.HasHeader = p.hasHeader(head)
+	}
+
+	seen := make(map[string]bool)
+	lineNum := 0
+	extract := func(line string) {
+		lineNum++
+		var texts []ExtractedText
+		if isTSV && lineNum == 1 && result.HasHeader {
+			return
+		}
+		if isTSV {
+			texts = p.tsvLineTexts(line, lineNum, filePath)
+		} else if et, ok := plainLineText(line, lineNum, filePath); ok {
+			texts = []ExtractedText{et}
+		}
+		for _, et := range texts {
+			if !seen[et.Text] {
+				seen[et.Text] = true
+				result.Texts = append(result.Texts, et)
+			}
+		}
+	}
+
+	for _, line := range head {
+		extract(line)
+	}
+	for scanner.Scan() {
+		extract(scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan txt file: %w", err)
+	}
+
+	return result, nil
 }
 
 // isTranslatableColumn determines if a TSV column contains human-readable text
 // that should be translated.
 func isTranslatableColumn(col string) bool {
-	if col == "" || !textutil.ContainsChinese(col) {
+	if col == "" || !textutil.ContainsSource(col) {
 		return false
 	}
 
@@ -169,7 +380,16 @@ func isTranslatableColumn(col string) bool {
 	return utf8.RuneCountInString(col) >= 2
 }
 
-func (p *TXTParser) Reconstruct(result *ParseResult, translations map[string]string) ([]byte, error) {
+func (p *TXTParser) Reconstruct(result *ParseResult, translations map[string]string) ([]byte, ReconstructReport, error) {
+	if result.Streamed {
+		var buf bytes.Buffer
+		report, err := p.ReconstructTo(&buf, result, translations)
+		if err != nil {
+			return nil, report, err
+		}
+		return buf.Bytes(), report, nil
+	}
+
 	lines := make([]string, len(result.RawLines))
 	copy(lines, result.RawLines)
 
@@ -179,43 +399,127 @@ func (p *TXTParser) Reconstruct(result *ParseResult, translations map[string]str
 	return p.reconstructPlainText(lines, result, translations)
 }
 
-func (p *TXTParser) reconstructTSV(lines []string, result *ParseResult, translations map[string]string) ([]byte, error) {
+func (p *TXTParser) reconstructTSV(lines []string, result *ParseResult, translations map[string]string) ([]byte, ReconstructReport, error) {
+	var report ReconstructReport
 	for _, et := range result.Texts {
 		idx := et.Line - 1
 		if idx < 0 || idx >= len(lines) {
+			report.outOfRange(et.Text)
 			continue
 		}
 		translated, ok := translations[et.Text]
 		if !ok {
+			report.missing(et.Text)
 			continue
 		}
 
 		cols := strings.Split(lines[idx], "\t")
-		if et.Column >= 0 && et.Column < len(cols) {
-			cols[et.Column] = translated
+		if et.Column < 0 || et.Column >= len(cols) {
+			report.outOfRange(et.Text)
+			continue
 		}
+		cols[et.Column] = tsvCell(translated)
 		lines[idx] = strings.Join(cols, "\t")
+		report.applied()
 	}
 
-	return []byte(strings.Join(lines, "\n") + "\n"), nil
+	return []byte(strings.Join(lines, "\n") + "\n"), report, nil
 }
 
-func (p *TXTParser) reconstructPlainText(lines []string, result *ParseResult, translations map[string]string) ([]byte, error) {
+func (p *TXTParser) reconstructPlainText(lines []string, result *ParseResult, translations map[string]string) ([]byte, ReconstructReport, error) {
+	var report ReconstructReport
 	for _, et := range result.Texts {
 		idx := et.Line - 1
 		if idx < 0 || idx >= len(lines) {
+			report.outOfRange(et.Text)
 			continue
 		}
 		translated, ok := translations[et.Text]
 		if !ok {
+			report.missing(et.Text)
 			continue
 		}
 		original := lines[idx]
 		trimmed := strings.TrimSpace(original)
-		lines[idx] = strings.Replace(original, trimmed, translated, 1)
+		lines[idx] = strings.Replace(original, trimmed, singleLine(translated), 1)
+		report.applied()
+	}
+
+	return []byte(strings.Join(lines, "\n") + "\n"), report, nil
+}
+
+// ReconstructTo re-reads the source file of a streamed result and writes each line
+// to w with its translatable texts replaced, so memory stays bounded by one line.
+func (p *TXTParser) ReconstructTo(w io.Writer, result *ParseResult, translations map[string]string) (ReconstructReport, error) {
+	var report ReconstructReport
+	file, err := os.Open(result.FilePath)
+	if err != nil {
+		return report, fmt.Errorf("open txt file: %w", err)
+	}
+	defer file.Close()
+
+	bw := bufio.NewWriter(w)
+	scanner := newLineScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+
+		// A header row is written back verbatim.
+		isHeader := result.HasHeader && lineNum == 1
+		switch {
+		case isHeader:
+		case result.FileType == "tsv":
+			if texts := p.tsvLineTexts(line, lineNum, result.FilePath); len(texts) > 0 {
+				cols := strings.Split(line, "\t")
+				for _, et := range texts {
+					if translated, ok := translations[et.Text]; ok {
+						cols[et.Column] = tsvCell(translated)
+						report.applied()
+					} else {
+						report.missing(et.Text)
+					}
+				}
+				line = strings.Join(cols, "\t")
+			}
+		default:
+			if et, ok := plainLineText(line, lineNum, result.FilePath); ok {
+				if translated, ok := translations[et.Text]; ok {
+					line = strings.Replace(line, et.Text, singleLine(translated), 1)
+					report.applied()
+				} else {
+					report.missing(et.Text)
+				}
+			}
+		}
+
+		if _, err := bw.WriteString(line + "\n"); err != nil {
+			return report, fmt.Errorf("write txt line: %w", err)
+		}
 	}
+	if err := scanner.Err(); err != nil {
+		return report, fmt.Errorf("scan txt file: %w", err)
+	}
+	return report, bw.Flush()
+}
 
-	return []byte(strings.Join(lines, "\n") + "\n"), nil
+func (p *TXTParser) ExtractTranslations(result *ParseResult, translatedLines []string) []string {
+	values := make([]string, len(result.Texts))
+	for i, et := range result.Texts {
+		idx := et.Line - 1
+		if idx < 0 || idx >= len(translatedLines) {
+			continue
+		}
+		if result.FileType == "tsv" {
+			cols := strings.Split(translatedLines[idx], "\t")
+			if et.Column >= 0 && et.Column < len(cols) {
+				values[i] = cols[et.Column]
+			}
+			continue
+		}
+		values[i] = strings.TrimSpace(translatedLines[idx])
+	}
+	return values
 }
 
 func min(a, b int) int {
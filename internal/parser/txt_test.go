@@ -0,0 +1,144 @@
+package parser
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTXTReconstructToKeepsHeader(t *testing.T) {
+	content := "名称\t描述\n1\t金币\t获得金币\n2\t宝石\t获得宝石\n"
+	want := "名称\t描述\n1\tGold\tGain gold\n2\tGem\tGain gems\n"
+	translations := map[string]string{
+		"金币": "Gold", "获得金币": "Gain gold",
+		"宝石": "Gem", "获得宝石": "Gain gems",
+	}
+
+	path := writeTemp(t, "data.txt", content)
+	p := NewTXTParser().AsTSV()
+	p.SetHeaderMode(HeaderAlways)
+	p.SetStreamThreshold(1)
+	result, err := p.Parse(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Streamed || !result.HasHeader {
+		t.Fatalf("Streamed = %v, HasHeader = %v, want both", result.Streamed, result.HasHeader)
+	}
+
+	var buf bytes.Buffer
+	report, err := p.ReconstructTo(&buf, result, translations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+	if report.Applied != 4 || report.SkippedMissing != 0 || report.SkippedOutOfRange != 0 {
+		t.Errorf("report = %+v, want 4 applied and none skipped", report)
+	}
+}
+
+func TestTXTHeaderRow(t *testing.T) {
+	const withHeader = "编号\t名称\t描述\n1\t金币\t获得金币\n2\t宝石\t获得宝石\n"
+	const withoutHeader = "1\t金币\t获得金币\n2\t宝石\t获得宝石\n"
+	tests := []struct {
+		name      string
+		content   string
+		mode      HeaderMode
+		hasHeader bool
+		texts     int
+	}{
+		{"never", withHeader, HeaderNever, false, 7},
+		{"always", withHeader, HeaderAlways, true, 4},
+		{"auto detects Chinese column names", withHeader, HeaderAuto, true, 4},
+		{"auto keeps a data first row", withoutHeader, HeaderAuto, false, 4},
+	}
+	translations := map[string]string{
+		"金币": "Gold", "获得金币": "Gain gold",
+		"宝石": "Gem", "获得宝石": "Gain gems",
+	}
+	for _, tt := range tests {
+		for _, streamed := range []bool{false, true} {
+			name := tt.name
+			if streamed {
+				name += "/streamed"
+			}
+			t.Run(name, func(t *testing.T) {
+				p := NewTXTParser().AsTSV()
+				p.SetHeaderMode(tt.mode)
+				if streamed {
+					p.SetStreamThreshold(1)
+				}
+				result, err := p.Parse(writeTemp(t, "data.txt", tt.content))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.HasHeader != tt.hasHeader {
+					t.Errorf("HasHeader = %v, want %v", result.HasHeader, tt.hasHeader)
+				}
+				if len(result.Texts) != tt.texts {
+					t.Errorf("extracted %d texts, want %d", len(result.Texts), tt.texts)
+				}
+				if !tt.hasHeader {
+					return
+				}
+
+				var out string
+				if streamed {
+					var buf bytes.Buffer
+					if _, err := p.ReconstructTo(&buf, result, translations); err != nil {
+						t.Fatal(err)
+					}
+					out = buf.String()
+				} else {
+					data, _, err := p.Reconstruct(result, translations)
+					if err != nil {
+						t.Fatal(err)
+					}
+					out = string(data)
+				}
+				if header, _, _ := strings.Cut(out, "\n"); header != "编号\t名称\t描述" {
+					t.Errorf("header reconstructed as %q", header)
+				}
+			})
+		}
+	}
+}
+
+func TestTXTTranslationWithTab(t *testing.T) {
+	content := "1\t金币\t获得金币\n2\t宝石\t获得宝石\n"
+	translations := map[string]string{
+		"金币": "Gold\tcoin", "获得金币": "Gain\tgold\nnow",
+		"宝石": "Gem", "获得宝石": "Gain gems",
+	}
+	want := "1\tGold coin\tGain gold\\nnow\n2\tGem\tGain gems\n"
+	for _, streamed := range []bool{false, true} {
+		path := writeTemp(t, "data.txt", content)
+		p := NewTXTParser().AsTSV()
+		p.SetHeaderMode(HeaderNever)
+		if streamed {
+			p.SetStreamThreshold(1)
+		}
+		result, err := p.Parse(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Streamed != streamed {
+			t.Fatalf("Streamed = %v, want %v", result.Streamed, streamed)
+		}
+
+		var buf bytes.Buffer
+		if _, err := p.ReconstructTo(&buf, result, translations); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != want {
+			t.Errorf("streamed %v: output = %q, want %q", streamed, buf.String(), want)
+		}
+		for i, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+			if cols := strings.Count(line, "\t") + 1; cols != 3 {
+				t.Errorf("streamed %v: line %d has %d columns, want 3", streamed, i+1, cols)
+			}
+		}
+	}
+}
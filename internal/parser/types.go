@@ -1,5 +1,10 @@
 package parser
 
+import (
+	"io"
+	"strings"
+)
+
 // ExtractedText represents a translatable string extracted from a game file.
 type ExtractedText struct {
 	// Text is the original translatable string.
@@ -8,12 +13,22 @@ type ExtractedText struct {
 	File string
 	// Line is the 1-based line number in the source file.
 	Line int
-	// Column is the 0-based column for tab-separated files (-1 if not applicable).
+	// Column is the 0-based column for tab-separated files, or the 0-based byte
+	// column of the literal's content in table-mode Lua (-1 if not applicable).
 	Column int
 	// Context holds additional context (function name, section, etc.)
 	Context map[string]string
 }
 
+// PercentIsLiteral reports whether % in et is ordinary text rather than part of a
+// format specifier. Lua only interprets % in strings passed to string.format, so the
+// Lua parser marks texts of files that call it with Context["string_format"]; other file
+// types give no such signal and are assumed to hold format strings. String tables are
+// often formatted from another file, so callers only rely on this when asked to.
+func PercentIsLiteral(fileType string, et ExtractedText) bool {
+	return fileType == "lua" && et.Context["string_format"] != "true"
+}
+
 // ParseResult holds parsing output for a single file.
 type ParseResult struct {
 	// FilePath is the absolute path to the parsed file.
@@ -24,6 +39,52 @@ type ParseResult struct {
 	Texts []ExtractedText
 	// RawLines preserves the original file content for reconstruction.
 	RawLines []string
+	// HasHeader records that the first row of a TSV file was treated as a header
+	// and excluded from extraction; it is reconstructed verbatim.
+	HasHeader bool
+	// Streamed marks a large file parsed without RawLines; it is reconstructed by
+	// re-reading FilePath, see StreamReconstructor.
+	Streamed bool
+}
+
+// ReconstructReport records what Reconstruct did with each extracted text.
+type ReconstructReport struct {
+	// Applied counts texts written translated.
+	Applied int
+	// SkippedMissing counts texts left as source because no translation was given.
+	SkippedMissing int
+	// SkippedOutOfRange counts texts that could not be placed: their line is out of
+	// range or no longer holds the text. Nonzero counts point at a parser bug.
+	SkippedOutOfRange int
+	// Skipped lists the source of every skipped text, in file order.
+	Skipped []string
+}
+
+func (r *ReconstructReport) applied() { r.Applied++ }
+
+func (r *ReconstructReport) missing(text string) {
+	r.SkippedMissing++
+	r.Skipped = append(r.Skipped, text)
+}
+
+func (r *ReconstructReport) outOfRange(text string) {
+	r.SkippedOutOfRange++
+	r.Skipped = append(r.Skipped, text)
+}
+
+// lineBreaks writes line breaks as the two-character escape \n.
+var lineBreaks = strings.NewReplacer("\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// singleLine keeps a translation on one line for line-based formats, where a line
+// break added by the model would split the line and shift every later one.
+func singleLine(translated string) string {
+	return lineBreaks.Replace(translated)
+}
+
+// tsvCell keeps a translation within one TSV cell: line breaks are escaped as in
+// singleLine, and tabs, which would shift the following columns, become spaces.
+func tsvCell(translated string) string {
+	return strings.ReplaceAll(singleLine(translated), "\t", " ")
 }
 
 // Parser is the interface for all file format parsers.
@@ -32,6 +93,21 @@ type Parser interface {
 	CanParse(ext string) bool
 	// Parse extracts translatable strings from a file.
 	Parse(filePath string) (*ParseResult, error)
-	// Reconstruct rebuilds the file with translated strings.
-	Reconstruct(result *ParseResult, translations map[string]string) ([]byte, error)
+	// Reconstruct rebuilds the file with translated strings and reports which
+	// extracted texts were replaced.
+	Reconstruct(result *ParseResult, translations map[string]string) ([]byte, ReconstructReport, error)
+}
+
+// TranslationExtractor is implemented by parsers that can read back, from a
+// reconstructed file, the value written in place of each extracted text.
+type TranslationExtractor interface {
+	// ExtractTranslations returns one value per result.Texts entry, in order, taken
+	// from translatedLines. Entries whose slot cannot be located are returned as "".
+	ExtractTranslations(result *ParseResult, translatedLines []string) []string
+}
+
+// StreamReconstructor is implemented by parsers that can write a reconstructed file
+// straight to w, re-reading the source instead of holding it in memory.
+type StreamReconstructor interface {
+	ReconstructTo(w io.Writer, result *ParseResult, translations map[string]string) (ReconstructReport, error)
 }
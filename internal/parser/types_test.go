@@ -0,0 +1,55 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTemp(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPercentIsLiteral(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   bool
+	}{
+		{"no format call", `UI.ShowMessage("提升50%攻击力")` + "\n", true},
+		{"string.format", `local s = string.format("获得%d金币", n)` + "\n", false},
+		{"format elsewhere in the file", "local msg = \"获得%d金币\"\nprint(msg:format(n))\n", false},
+	}
+	for _, mode := range []LuaMode{LuaModeLine, LuaModeTable} {
+		for _, tt := range tests {
+			t.Run(string(mode)+"/"+tt.name, func(t *testing.T) {
+				p := NewLuaParser()
+				p.SetMode(mode)
+				result, err := p.Parse(writeTemp(t, "a.lua", tt.source))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(result.Texts) != 1 {
+					t.Fatalf("extracted %d texts, want 1", len(result.Texts))
+				}
+				if got := PercentIsLiteral(result.FileType, result.Texts[0]); got != tt.want {
+					t.Errorf("PercentIsLiteral = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestPercentIsLiteralOtherFileTypes(t *testing.T) {
+	et := ExtractedText{Text: "获得%d金币", Context: map[string]string{}}
+	for _, fileType := range []string{"ini", "txt", "tsv", "po"} {
+		if PercentIsLiteral(fileType, et) {
+			t.Errorf("PercentIsLiteral(%q) = true, want false", fileType)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// barWidth is the number of cells in the drawn bar.
+const barWidth = 30
+
+// redrawInterval limits how often a bar is redrawn between updates.
+const redrawInterval = 100 * time.Millisecond
+
+var (
+	mu     sync.Mutex
+	out    io.Writer // terminal the bars are drawn on; nil disables bars
+	active *Bar      // bar currently on screen, redrawn below log lines
+)
+
+// Enable draws progress bars on w, which must be a terminal. Log output written
+// to w must go through Output, so log lines do not run into a bar.
+func Enable(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	out = w
+}
+
+// Output wraps w so every write first clears the bar on screen and redraws it
+// below the written text. With bars disabled writes pass through unchanged.
+func Output(w io.Writer) io.Writer {
+	return &clearingWriter{w: w}
+}
+
+type clearingWriter struct {
+	w io.Writer
+}
+
+func (cw *clearingWriter) Write(p []byte) (int, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if active == nil {
+		return cw.w.Write(p)
+	}
+	fmt.Fprint(out, "\r\033[K")
+	n, err := cw.w.Write(p)
+	active.draw()
+	return n, err
+}
+
+// Bar is a single-line progress bar for one phase of a run. A nil *Bar is valid
+// and does nothing, so callers need not check whether bars are enabled.
+type Bar struct {
+	label    string
+	total    int
+	done     int
+	start    time.Time
+	drawn    time.Time
+	finished bool
+}
+
+// New starts a bar counting up to total. It returns nil when bars are disabled
+// or there is nothing to count.
+func New(label string, total int) *Bar {
+	mu.Lock()
+	defer mu.Unlock()
+	if out == nil || total <= 0 {
+		return nil
+	}
+	b := &Bar{label: label, total: total, start: time.Now()}
+	active = b
+	b.draw()
+	return b
+}
+
+// Add advances the bar by n.
+func (b *Bar) Add(n int) {
+	if b == nil {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	b.done = min(b.done+n, b.total)
+	b.redraw()
+}
+
+// Set moves the bar to done. Its signature fits worker.Pool.SetProgress.
+func (b *Bar) Set(done, _ int) {
+	if b == nil {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	b.done = min(done, b.total)
+	b.redraw()
+}
+
+// Finish draws the bar one last time and ends its line. Later calls do nothing.
+func (b *Bar) Finish() {
+	if b == nil {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if b.finished {
+		return
+	}
+	b.finished = true
+	b.draw()
+	fmt.Fprintln(out)
+	if active == b {
+		active = nil
+	}
+}
+
+// redraw draws the bar unless it was drawn recently and is not complete.
+func (b *Bar) redraw() {
+	if b.done < b.total && time.Since(b.drawn) < redrawInterval {
+		return
+	}
+	b.draw()
+}
+
+// draw writes the bar over the current terminal line. The caller holds mu.
+func (b *Bar) draw() {
+	filled := barWidth * b.done / b.total
+	elapsed := time.Since(b.start).Round(time.Second)
+	fmt.Fprintf(out, "\r\033[K%s [%s%s] %d/%d %3d%% %s",
+		b.label,
+		strings.Repeat("=", filled),
+		strings.Repeat(" ", barWidth-filled),
+		b.done, b.total,
+		100*b.done/b.total,
+		elapsed,
+	)
+	b.drawn = time.Now()
+}
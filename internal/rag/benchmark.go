@@ -0,0 +1,109 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+
+	"rag-translator/internal/textutil"
+)
+
+// BenchmarkPair is a labeled retrieval example: Expected should be among the
+// nearest neighbors of Query. A query with several expected neighbors has one
+// pair per neighbor.
+type BenchmarkPair struct {
+	Query    string
+	Expected string
+}
+
+// BenchmarkResult is the retrieval quality of one embedding model on a sample.
+type BenchmarkResult struct {
+	Queries   int
+	Corpus    int     // texts searched: the expected neighbors and any distractors
+	RecallAt1 float64 // mean share of a query's expected neighbors ranked first
+	RecallAtK float64 // mean share of a query's expected neighbors in the top k
+	MRR       float64 // mean reciprocal rank of a query's best-ranked expected neighbor
+}
+
+// BenchmarkRecall embeds the expected neighbors and distractors of a sample with
+// ec, searches them in a MemoryVectorStore for each query and scores the top k
+// results. A corpus text equal to the query is not counted as a result.
+func BenchmarkRecall(ctx context.Context, ec *EmbeddingClient, pairs []BenchmarkPair, distractors []string, k int) (BenchmarkResult, error) {
+	expected := make(map[string]map[string]bool)
+	var queries []string
+	corpus := make(map[string]bool)
+	var corpusTexts []string
+	addCorpus := func(text string) {
+		if !corpus[text] {
+			corpus[text] = true
+			corpusTexts = append(corpusTexts, text)
+		}
+	}
+	for _, p := range pairs {
+		if expected[p.Query] == nil {
+			expected[p.Query] = make(map[string]bool)
+			queries = append(queries, p.Query)
+		}
+		expected[p.Query][p.Expected] = true
+		addCorpus(p.Expected)
+	}
+	for _, text := range distractors {
+		addCorpus(text)
+	}
+
+	vectors, err := ec.EmbedBatch(ctx, corpusTexts, 100)
+	if err != nil {
+		return BenchmarkResult{}, fmt.Errorf("embed corpus: %w", err)
+	}
+	store := NewMemoryVectorStore()
+	// Records are keyed by the raw text hash, not textutil.CanonicalHash as in
+	// ingest: results are matched to the labeled texts byte for byte, so labeled
+	// texts differing only in width or normalization must not replace each other.
+	records := make([]EmbeddingRecord, len(corpusTexts))
+	for i, text := range corpusTexts {
+		records[i] = EmbeddingRecord{Hash: textutil.Hash(text), Source: text, Vector: vectors[i]}
+	}
+	if err := store.Store(ctx, records); err != nil {
+		return BenchmarkResult{}, err
+	}
+
+	queryVectors, err := ec.EmbedBatch(ctx, queries, 100)
+	if err != nil {
+		return BenchmarkResult{}, fmt.Errorf("embed queries: %w", err)
+	}
+
+	result := BenchmarkResult{Queries: len(queries), Corpus: len(corpusTexts)}
+	for i, query := range queries {
+		// One extra result makes up for the query itself being in the corpus.
+		found, err := store.Search(ctx, queryVectors[i], k+1)
+		if err != nil {
+			return BenchmarkResult{}, err
+		}
+		want := expected[query]
+		hits, rank := 0, 0
+		for _, r := range found {
+			if r.Source == query {
+				continue
+			}
+			if rank++; rank > k {
+				break
+			}
+			if !want[r.Source] {
+				continue
+			}
+			if hits == 0 {
+				result.MRR += 1 / float64(rank)
+			}
+			if rank == 1 {
+				result.RecallAt1 += 1 / float64(len(want))
+			}
+			hits++
+		}
+		result.RecallAtK += float64(hits) / float64(len(want))
+	}
+	if n := float64(len(queries)); n > 0 {
+		result.RecallAt1 /= n
+		result.RecallAtK /= n
+		result.MRR /= n
+	}
+	return result, nil
+}
@@ -9,6 +9,8 @@ import (
 	"net/http"
 	"time"
 
+	"rag-translator/internal/apierror"
+
 	"github.com/rs/zerolog/log"
 )
 
@@ -19,11 +21,13 @@ type EmbeddingClient struct {
 	apiKey     string
 	model      string
 	dimensions int
+	maxRetries int // retries after a failed request; 0 fails on the first error
 	httpClient *http.Client
 }
 
-// NewEmbeddingClient creates a new Gemini embedding client.
-func NewEmbeddingClient(apiKey, model string, dimensions int) *EmbeddingClient {
+// NewEmbeddingClient creates a new Gemini embedding client that retries a failed
+// request up to maxRetries times.
+func NewEmbeddingClient(apiKey, model string, dimensions, maxRetries int) *EmbeddingClient {
 	if dimensions <= 0 {
 		dimensions = 768
 	}
@@ -31,12 +35,25 @@ func NewEmbeddingClient(apiKey, model string, dimensions int) *EmbeddingClient {
 		apiKey:     apiKey,
 		model:      model,
 		dimensions: dimensions,
+		maxRetries: maxRetries,
 		httpClient: &http.Client{
 			Timeout: 60 * time.Second,
 		},
 	}
 }
 
+// SetTransport sends requests through rt, e.g. a transport with proxy and CA
+// settings from httpclient.NewTransport.
+func (ec *EmbeddingClient) SetTransport(rt http.RoundTripper) {
+	ec.httpClient.Transport = rt
+}
+
+// SetTimeout bounds each request, including reading the response. The default
+// is 60s.
+func (ec *EmbeddingClient) SetTimeout(d time.Duration) {
+	ec.httpClient.Timeout = d
+}
+
 // --- Gemini Embedding API types ---
 
 type batchEmbedRequest struct {
@@ -66,11 +83,44 @@ type embeddingValues struct {
 }
 
 // Embed generates embeddings for a batch of texts using Gemini batchEmbedContents.
+// Transient failures are retried with a growing backoff.
 func (ec *EmbeddingClient) Embed(ctx context.Context, texts []string) ([][]float32, error) {
 	if len(texts) == 0 {
 		return nil, nil
 	}
 
+	var lastErr error
+	for attempt := 0; attempt <= ec.maxRetries; attempt++ {
+		if attempt > 0 {
+			backoff := time.Duration(attempt*2) * time.Second
+			log.Warn().Int("attempt", attempt+1).Dur("backoff", backoff).Msg("Retrying embedding request")
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(backoff):
+			}
+		}
+
+		results, err := ec.embed(ctx, texts)
+		if err == nil {
+			return results, nil
+		}
+		lastErr = err
+
+		// Don't retry on context cancellation.
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !apierror.Retryable(err) {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("embedding failed after %d retries: %w", ec.maxRetries, lastErr)
+}
+
+// embed makes one batchEmbedContents request.
+func (ec *EmbeddingClient) embed(ctx context.Context, texts []string) ([][]float32, error) {
 	modelPath := fmt.Sprintf("models/%s", ec.model)
 
 	requests := make([]singleEmbedRequest, len(texts))
@@ -111,7 +161,7 @@ func (ec *EmbeddingClient) Embed(ctx context.Context, texts []string) ([][]float
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("embedding API error (status %d): %s", resp.StatusCode, string(respBody))
+		return nil, fmt.Errorf("embedding request: %w", apierror.New(resp.StatusCode, string(respBody)))
 	}
 
 	var embedResp batchEmbedResponse
@@ -137,6 +187,23 @@ func (ec *EmbeddingClient) Embed(ctx context.Context, texts []string) ([][]float
 // EmbedBatch processes texts in batches, respecting API limits.
 // Gemini batchEmbedContents supports up to 100 texts per request.
 func (ec *EmbeddingClient) EmbedBatch(ctx context.Context, texts []string, batchSize int) ([][]float32, error) {
+	var allEmbeddings [][]float32
+	err := ec.EmbedEach(ctx, texts, batchSize, func(start int, embeddings [][]float32) error {
+		allEmbeddings = append(allEmbeddings, embeddings...)
+		return nil
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return allEmbeddings, nil
+}
+
+// EmbedEach embeds texts in batches like EmbedBatch, but hands each batch to fn as
+// soon as it is generated instead of accumulating the results. start is the index
+// in texts of the batch's first text. An error from fn stops processing. A batch
+// that fails to embed is passed to onErr, which may return nil to go on with the
+// next batch; a nil onErr stops at the first failure.
+func (ec *EmbeddingClient) EmbedEach(ctx context.Context, texts []string, batchSize int, fn func(start int, embeddings [][]float32) error, onErr func(err error) error) error {
 	if batchSize <= 0 {
 		batchSize = 100
 	}
@@ -145,8 +212,6 @@ func (ec *EmbeddingClient) EmbedBatch(ctx context.Context, texts []string, batch
 		batchSize = 100
 	}
 
-	var allEmbeddings [][]float32
-
 	for i := 0; i < len(texts); i += batchSize {
 		end := i + batchSize
 		if end > len(texts) {
@@ -156,19 +221,27 @@ func (ec *EmbeddingClient) EmbedBatch(ctx context.Context, texts []string, batch
 		batch := texts[i:end]
 		embeddings, err := ec.Embed(ctx, batch)
 		if err != nil {
-			return nil, fmt.Errorf("embed batch [%d:%d]: %w", i, end, err)
+			err = fmt.Errorf("embed batch [%d:%d]: %w", i, end, err)
+			if onErr == nil {
+				return err
+			}
+			if err := onErr(err); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(i, embeddings); err != nil {
+			return err
 		}
-
-		allEmbeddings = append(allEmbeddings, embeddings...)
 
 		log.Info().
 			Int("batch", i/batchSize+1).
-			Int("processed", len(allEmbeddings)).
+			Int("processed", end).
 			Int("total", len(texts)).
 			Msg("Embedding progress")
 	}
 
-	return allEmbeddings, nil
+	return nil
 }
 
 // EmbedQuery generates an embedding for a search query.
@@ -0,0 +1,63 @@
+package rag
+
+import (
+	"context"
+	"sync"
+
+	"rag-translator/internal/textutil"
+
+	"github.com/rs/zerolog/log"
+)
+
+// QueryEmbedder produces the embedding used for a similarity search query.
+type QueryEmbedder interface {
+	EmbedQuery(ctx context.Context, text string) ([]float32, error)
+}
+
+// CachedEmbedder serves query embeddings from vectors already stored at ingest time,
+// keyed by text hash, and only calls the embedding API for unknown texts.
+type CachedEmbedder struct {
+	client      *EmbeddingClient
+	vectorStore *VectorStore
+	mu          sync.RWMutex
+	memory      map[string][]float32 // hash → vector
+}
+
+// NewCachedEmbedder wraps an embedding client with a stored-vector lookup.
+func NewCachedEmbedder(ec *EmbeddingClient, vs *VectorStore) *CachedEmbedder {
+	return &CachedEmbedder{
+		client:      ec,
+		vectorStore: vs,
+		memory:      make(map[string][]float32),
+	}
+}
+
+// EmbedQuery returns the stored embedding for text if one exists, otherwise it
+// calls the embedding API. Results are memoized for the lifetime of the embedder.
+func (ce *CachedEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	hash := textutil.CanonicalHash(text)
+
+	ce.mu.RLock()
+	if v, ok := ce.memory[hash]; ok {
+		ce.mu.RUnlock()
+		return v, nil
+	}
+	ce.mu.RUnlock()
+
+	vec, found, err := ce.vectorStore.GetVectorByHash(ctx, hash)
+	if err != nil {
+		log.Warn().Err(err).Msg("Stored embedding lookup failed, calling embedding API")
+	}
+	if !found {
+		vec, err = ce.client.EmbedQuery(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ce.mu.Lock()
+	ce.memory[hash] = vec
+	ce.mu.Unlock()
+
+	return vec, nil
+}
@@ -0,0 +1,100 @@
+package rag
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+)
+
+// MemoryVectorStore is a VectorSearcher that keeps embeddings in memory and
+// searches them by brute-force cosine similarity. It suits tests and corpora
+// small enough that a linear scan per query is cheap.
+type MemoryVectorStore struct {
+	mu      sync.RWMutex
+	records map[string]EmbeddingRecord // hash → record
+}
+
+// NewMemoryVectorStore creates an empty in-memory vector store.
+func NewMemoryVectorStore() *MemoryVectorStore {
+	return &MemoryVectorStore{records: make(map[string]EmbeddingRecord)}
+}
+
+// Store upserts records by hash.
+func (ms *MemoryVectorStore) Store(ctx context.Context, records []EmbeddingRecord) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	for _, r := range records {
+		ms.records[r.Hash] = r
+	}
+	return nil
+}
+
+// ExistingHashes returns which of hashes have a stored record with a vector.
+func (ms *MemoryVectorStore) ExistingHashes(ctx context.Context, hashes []string) (map[string]bool, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	existing := make(map[string]bool)
+	for _, hash := range hashes {
+		if r, ok := ms.records[hash]; ok && len(r.Vector) > 0 {
+			existing[hash] = true
+		}
+	}
+	return existing, nil
+}
+
+// Search returns the topK stored records most similar to queryVector, best first.
+// Records whose vector length differs from the query's are ignored.
+func (ms *MemoryVectorStore) Search(ctx context.Context, queryVector []float32, topK int) ([]SearchResult, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	type scored struct {
+		hash   string
+		result SearchResult
+	}
+	matches := make([]scored, 0, len(ms.records))
+	for hash, r := range ms.records {
+		if len(r.Vector) == 0 || len(r.Vector) != len(queryVector) {
+			continue
+		}
+		matches = append(matches, scored{hash, SearchResult{
+			Source:  r.Source,
+			Context: r.Context,
+			Score:   cosineSimilarity(queryVector, r.Vector),
+			Weight:  r.Weight,
+		}})
+	}
+
+	// Ties are broken by hash, as in VectorStore, so results do not depend on map
+	// order.
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].result.Score != matches[j].result.Score {
+			return matches[i].result.Score > matches[j].result.Score
+		}
+		return matches[i].hash < matches[j].hash
+	})
+	results := make([]SearchResult, len(matches))
+	for i, m := range matches {
+		results[i] = m.result
+	}
+	if len(results) > topK {
+		results = results[:topK]
+	}
+	return results, nil
+}
+
+// cosineSimilarity returns the cosine similarity of two equal-length vectors, or 0
+// when either is all zeros.
+func cosineSimilarity(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -1,14 +1,18 @@
 package rag
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"slices"
+	"sort"
 	"strings"
 
 	"rag-translator/internal/graph"
 	"rag-translator/internal/textutil"
 
 	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/errgroup"
 )
 
 // RetrievalResult combines vector, graph, and seed context for a translation request.
@@ -26,67 +30,231 @@ type SeedQuerier interface {
 	FindSeedTranslations(ctx context.Context, text string) (map[string]string, error)
 }
 
+// GraphContextProvider supplies terminology and term relationships from the
+// knowledge graph. *graph.GraphQuerier is the Neo4j implementation and
+// *graph.StaticGraph an in-memory one for tests.
+type GraphContextProvider interface {
+	FindRelatedTermsInRegister(ctx context.Context, text, register string) (*graph.QueryResult, error)
+	GetAllTerminology(ctx context.Context) (map[string]string, error)
+	GetRegisterTerminology(ctx context.Context, register string) (map[string]string, error)
+}
+
 // Retriever combines vector store, knowledge graph, and seed corpus for RAG.
 type Retriever struct {
-	vectorStore     *VectorStore
-	embeddingClient *EmbeddingClient
-	graphQuerier    *graph.GraphQuerier
+	vectorStore     VectorSearcher
+	embeddingClient QueryEmbedder
+	graphQuerier    GraphContextProvider
 	seedQuerier     SeedQuerier // optional, nil if seeds not ingested yet
+	candidateFactor int         // vector search over-fetch before filtering
+	minSimilarity   float64     // similar texts scoring below this are dropped
+	dedupNormalized bool        // treat sources differing only in padding/punctuation as duplicates
 }
 
 // NewRetriever creates a new combined retriever.
-func NewRetriever(vs *VectorStore, ec *EmbeddingClient, gq *graph.GraphQuerier) *Retriever {
+func NewRetriever(vs VectorSearcher, ec QueryEmbedder, gq GraphContextProvider) *Retriever {
 	return &Retriever{
 		vectorStore:     vs,
 		embeddingClient: ec,
 		graphQuerier:    gq,
+		candidateFactor: 1,
 	}
 }
 
+// SetCandidateFactor makes vector search fetch topK × factor candidates, so that
+// topK remain after filtering. A factor below 1 is treated as 1.
+func (r *Retriever) SetCandidateFactor(factor int) {
+	r.candidateFactor = max(factor, 1)
+}
+
+// SetMinSimilarity drops similar texts scoring below score.
+func (r *Retriever) SetMinSimilarity(score float64) {
+	r.minSimilarity = score
+}
+
 // SetSeedQuerier attaches a seed querier for prioritized seed retrieval.
 func (r *Retriever) SetSeedQuerier(sq SeedQuerier) {
 	r.seedQuerier = sq
 }
 
 // Retrieve fetches relevant context for a given source text.
-// Priority order: seed translations > vector search > graph context.
+// Priority order: seed translations > vector search > graph context. The three
+// lookups are independent, so they run concurrently; a failed lookup is logged
+// and leaves its part of the result empty.
 func (r *Retriever) Retrieve(ctx context.Context, sourceText string, topK int) (*RetrievalResult, error) {
+	return r.RetrieveInRegister(ctx, sourceText, "", topK)
+}
+
+// RetrieveInRegister is like Retrieve, but graph terms use their rendering in
+// register where they have one, see graph.RegisterFromContext.
+func (r *Retriever) RetrieveInRegister(ctx context.Context, sourceText, register string, topK int) (*RetrievalResult, error) {
 	result := &RetrievalResult{}
+	g, gctx := errgroup.WithContext(ctx)
 
 	// 1. Seed translations (highest priority — manually verified).
 	if r.seedQuerier != nil {
-		seeds, err := r.seedQuerier.FindSeedTranslations(ctx, sourceText)
-		if err != nil {
-			log.Warn().Err(err).Msg("Seed query failed")
-		} else if len(seeds) > 0 {
-			result.SeedTranslations = seeds
-		}
+		g.Go(func() error {
+			seeds, err := r.seedQuerier.FindSeedTranslations(gctx, sourceText)
+			if err != nil {
+				log.Warn().Err(err).Msg("Seed query failed")
+			} else if len(seeds) > 0 {
+				result.SeedTranslations = seeds
+			}
+			return nil
+		})
 	}
 
 	// 2. Vector similarity search.
-	queryVec, err := r.embeddingClient.EmbedQuery(ctx, sourceText)
-	if err != nil {
-		log.Warn().Err(err).Str("text", textutil.Truncate(sourceText, 50)).Msg("Failed to embed query, skipping vector search")
-	} else {
-		similar, err := r.vectorStore.Search(ctx, queryVec, topK)
+	g.Go(func() error {
+		queryVec, err := r.embeddingClient.EmbedQuery(gctx, sourceText)
+		if err != nil {
+			log.Warn().Err(err).Str("text", textutil.Truncate(sourceText, 50)).Msg("Failed to embed query, skipping vector search")
+			return nil
+		}
+		candidates, err := r.vectorStore.Search(gctx, queryVec, topK*r.candidateFactor)
 		if err != nil {
 			log.Warn().Err(err).Msg("Vector search failed")
 		} else {
-			result.SimilarTexts = similar
+			result.SimilarTexts = r.filterSimilar(sourceText, candidates, topK)
 		}
-	}
+		return nil
+	})
 
 	// 3. Graph knowledge retrieval.
-	graphCtx, err := r.graphQuerier.FindRelatedTerms(ctx, sourceText)
-	if err != nil {
-		log.Warn().Err(err).Msg("Graph query failed")
-	} else {
-		result.GraphContext = graphCtx
-	}
+	g.Go(func() error {
+		graphCtx, err := r.graphQuerier.FindRelatedTermsInRegister(gctx, sourceText, register)
+		if err != nil {
+			log.Warn().Err(err).Msg("Graph query failed")
+		} else {
+			result.GraphContext = graphCtx
+		}
+		return nil
+	})
 
+	// Each goroutine writes a distinct field, so no locking is needed.
+	_ = g.Wait()
 	return result, nil
 }
 
+// SetDedupNormalized makes similar texts whose sources differ only in surrounding
+// whitespace and punctuation count as duplicates. Exact duplicates are always dropped.
+func (r *Retriever) SetDedupNormalized(enabled bool) {
+	r.dedupNormalized = enabled
+}
+
+// filterSimilar cuts vector search candidates down to the topK worth showing the
+// model: candidates below the minimum similarity are dropped, as is the query text
+// itself, which teaches nothing about how to translate it. The rest are ranked by
+// weighted score, see SearchResult.Rank, and a source stored from several files is
+// kept once, at its best rank.
+func (r *Retriever) filterSimilar(query string, candidates []SearchResult, topK int) []SearchResult {
+	ranked := slices.Clone(candidates)
+	slices.SortStableFunc(ranked, func(a, b SearchResult) int {
+		return cmp.Compare(b.Rank(), a.Rank())
+	})
+
+	seen := map[string]bool{r.dedupKey(query): true}
+	kept := make([]SearchResult, 0, min(len(ranked), topK))
+	for _, c := range ranked {
+		if len(kept) == topK {
+			break
+		}
+		key := r.dedupKey(c.Source)
+		if c.Score < r.minSimilarity || seen[key] {
+			continue
+		}
+		seen[key] = true
+		kept = append(kept, c)
+	}
+	return kept
+}
+
+// dedupKey returns the form of source compared when dropping duplicates.
+func (r *Retriever) dedupKey(source string) string {
+	if r.dedupNormalized {
+		return textutil.Normalize(source)
+	}
+	return source
+}
+
+// MergeResults combines the retrieval results of several texts into one compact
+// result for a batch prompt. Duplicates are dropped and each of seeds, similar texts
+// and relationships is capped at maxItems: the longest seeds, the highest-ranking
+// similar texts, and relationships in a stable order. Graph terms are left out
+// since batch prompts carry their own terminology section.
+func MergeResults(results []*RetrievalResult, maxItems int) *RetrievalResult {
+	seeds := make(map[string]string)
+	bestScore := make(map[string]SearchResult)
+	relSeen := make(map[graph.RelationshipResult]bool)
+	var relationships []graph.RelationshipResult
+
+	for _, r := range results {
+		if r == nil {
+			continue
+		}
+		for src, dst := range r.SeedTranslations {
+			seeds[src] = dst
+		}
+		for _, st := range r.SimilarTexts {
+			if prev, ok := bestScore[st.Source]; !ok || st.Rank() > prev.Rank() {
+				bestScore[st.Source] = st
+			}
+		}
+		if r.GraphContext != nil {
+			for _, rel := range r.GraphContext.Relationships {
+				if !relSeen[rel] {
+					relSeen[rel] = true
+					relationships = append(relationships, rel)
+				}
+			}
+		}
+	}
+
+	merged := &RetrievalResult{}
+
+	if len(seeds) > 0 {
+		merged.SeedTranslations = make(map[string]string)
+		for i, src := range graph.SortedTermKeys(seeds) {
+			if i == maxItems {
+				break
+			}
+			merged.SeedTranslations[src] = seeds[src]
+		}
+	}
+
+	for _, st := range bestScore {
+		merged.SimilarTexts = append(merged.SimilarTexts, st)
+	}
+	sort.Slice(merged.SimilarTexts, func(i, j int) bool {
+		a, b := merged.SimilarTexts[i], merged.SimilarTexts[j]
+		if a.Rank() != b.Rank() {
+			return a.Rank() > b.Rank()
+		}
+		return a.Source < b.Source
+	})
+	if len(merged.SimilarTexts) > maxItems {
+		merged.SimilarTexts = merged.SimilarTexts[:maxItems]
+	}
+
+	sort.Slice(relationships, func(i, j int) bool {
+		a, b := relationships[i], relationships[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.To < b.To
+	})
+	if len(relationships) > maxItems {
+		relationships = relationships[:maxItems]
+	}
+	if len(relationships) > 0 {
+		merged.GraphContext = &graph.QueryResult{Relationships: relationships}
+	}
+
+	return merged
+}
+
 // BuildContextString formats retrieval results into a string for the prompt.
 // Seed translations appear first for highest priority.
 func (r *Retriever) BuildContextString(result *RetrievalResult) string {
@@ -95,8 +263,8 @@ func (r *Retriever) BuildContextString(result *RetrievalResult) string {
 	// Seed translations first — these are manually verified and highest priority.
 	if len(result.SeedTranslations) > 0 {
 		sb.WriteString("=== Verified Seed Translations (USE THESE AS REFERENCE) ===\n")
-		for src, dst := range result.SeedTranslations {
-			sb.WriteString(fmt.Sprintf("• %s → %s\n", src, dst))
+		for _, src := range graph.SortedTermKeys(result.SeedTranslations) {
+			sb.WriteString(fmt.Sprintf("• %s → %s\n", src, result.SeedTranslations[src]))
 		}
 		sb.WriteString("\n")
 	}
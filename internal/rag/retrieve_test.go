@@ -0,0 +1,280 @@
+package rag
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"rag-translator/internal/graph"
+	"rag-translator/internal/textutil"
+)
+
+// staticEmbedder embeds texts with fixed vectors.
+type staticEmbedder map[string][]float32
+
+func (e staticEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	if v, ok := e[text]; ok {
+		return v, nil
+	}
+	return nil, fmt.Errorf("no vector for %q", text)
+}
+
+// newTestRetriever builds a Retriever over in-memory stores: a StaticGraph in place
+// of Neo4j and a MemoryVectorStore in place of PostgreSQL.
+func newTestRetriever(t *testing.T) *Retriever {
+	t.Helper()
+	vectors := staticEmbedder{
+		"获得金币":  {1, 0, 0},
+		"获得宝石":  {0.9, 0.1, 0},
+		"获得金币！": {1, 0.01, 0},
+		"离开门派":  {0, 0, 1},
+		"装备神兵":  {0, 1, 0},
+	}
+	store := NewMemoryVectorStore()
+	var records []EmbeddingRecord
+	for _, text := range []string{"获得宝石", "获得金币！", "离开门派"} {
+		records = append(records, EmbeddingRecord{Hash: textutil.Hash(text), Source: text, Vector: vectors[text]})
+	}
+	if err := store.Store(context.Background(), records); err != nil {
+		t.Fatal(err)
+	}
+
+	g := graph.NewStaticGraph(
+		[]graph.WuxiaTerm{
+			{Chinese: "金", Vietnamese: "Kim", Category: "general"},
+			{Chinese: "金币", Vietnamese: "Kim tệ", Category: "item"},
+			{Chinese: "宝石", Vietnamese: "Bảo thạch", Category: "item"},
+			{Chinese: "门派", Vietnamese: "Môn phái", Category: "faction",
+				Registers: map[string]string{graph.RegisterFormal: "Tông môn"}},
+		},
+		[]graph.Relationship{
+			{FromChinese: "金币", RelType: "EXCHANGES_FOR", ToChinese: "宝石"},
+			{FromChinese: "门派", RelType: "LOCATED_IN", ToChinese: "华山"},
+		},
+	)
+	return NewRetriever(store, vectors, g)
+}
+
+func TestRetrieveInRegister(t *testing.T) {
+	tests := []struct {
+		name          string
+		text          string
+		register      string
+		minSimilarity float64
+		terms         []string
+		relationships int
+		similar       []string
+	}{
+		{
+			name:          "terms longest first",
+			text:          "获得金币",
+			terms:         []string{"金币 → Kim tệ", "金 → Kim"},
+			relationships: 1,
+			similar:       []string{"获得金币！", "获得宝石"},
+		},
+		{
+			name:          "minimum similarity",
+			text:          "获得金币",
+			minSimilarity: 0.999,
+			terms:         []string{"金币 → Kim tệ", "金 → Kim"},
+			relationships: 1,
+			similar:       []string{"获得金币！"},
+		},
+		{
+			name:          "register rendering",
+			text:          "离开门派",
+			register:      graph.RegisterFormal,
+			terms:         []string{"门派 → Tông môn"},
+			relationships: 1,
+			similar:       []string{"获得宝石"}, // the query itself took a candidate slot
+		},
+		{
+			name:    "no terms",
+			text:    "装备神兵",
+			similar: []string{"获得宝石", "获得金币！"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRetriever(t)
+			r.SetMinSimilarity(tt.minSimilarity)
+			result, err := r.RetrieveInRegister(context.Background(), tt.text, tt.register, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var terms []string
+			for _, term := range result.GraphContext.Terms {
+				terms = append(terms, term.Chinese+" → "+term.Vietnamese)
+			}
+			if !reflect.DeepEqual(terms, tt.terms) {
+				t.Errorf("terms = %q, want %q", terms, tt.terms)
+			}
+			if got := len(result.GraphContext.Relationships); got != tt.relationships {
+				t.Errorf("%d relationships, want %d", got, tt.relationships)
+			}
+
+			var similar []string
+			for _, st := range result.SimilarTexts {
+				similar = append(similar, st.Source)
+			}
+			if !reflect.DeepEqual(similar, tt.similar) {
+				t.Errorf("similar = %q, want %q", similar, tt.similar)
+			}
+		})
+	}
+}
+
+func TestRetrieveEmbeddingFailure(t *testing.T) {
+	r := newTestRetriever(t)
+	result, err := r.Retrieve(context.Background(), "宝石没有向量", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.SimilarTexts) != 0 {
+		t.Errorf("similar texts %v without a query vector", result.SimilarTexts)
+	}
+	if result.GraphContext == nil || len(result.GraphContext.Terms) != 1 {
+		t.Errorf("graph context = %+v, want the 宝石 term despite the failed embedding", result.GraphContext)
+	}
+}
+
+// barrier blocks each caller of wait until n callers have arrived, or fails after
+// a timeout, which is what a sequential caller runs into.
+type barrier struct {
+	wg      sync.WaitGroup
+	arrived chan struct{}
+}
+
+func newBarrier(n int) *barrier {
+	b := &barrier{arrived: make(chan struct{})}
+	b.wg.Add(n)
+	go func() {
+		b.wg.Wait()
+		close(b.arrived)
+	}()
+	return b
+}
+
+func (b *barrier) wait() error {
+	b.wg.Done()
+	select {
+	case <-b.arrived:
+		return nil
+	case <-time.After(2 * time.Second):
+		return fmt.Errorf("lookups did not run concurrently")
+	}
+}
+
+type barrierEmbedder struct {
+	QueryEmbedder
+	b *barrier
+}
+
+func (e barrierEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	if err := e.b.wait(); err != nil {
+		return nil, err
+	}
+	return e.QueryEmbedder.EmbedQuery(ctx, text)
+}
+
+type barrierGraph struct {
+	*graph.StaticGraph
+	b *barrier
+}
+
+func (g barrierGraph) FindRelatedTermsInRegister(ctx context.Context, text, register string) (*graph.QueryResult, error) {
+	if err := g.b.wait(); err != nil {
+		return nil, err
+	}
+	return g.StaticGraph.FindRelatedTermsInRegister(ctx, text, register)
+}
+
+type barrierSeeds struct {
+	seeds map[string]string
+	b     *barrier
+}
+
+func (s barrierSeeds) FindSeedTranslations(ctx context.Context, text string) (map[string]string, error) {
+	if err := s.b.wait(); err != nil {
+		return nil, err
+	}
+	return s.seeds, nil
+}
+
+func TestRetrieveRunsLookupsConcurrently(t *testing.T) {
+	base := newTestRetriever(t)
+	b := newBarrier(3)
+	r := NewRetriever(base.vectorStore, barrierEmbedder{base.embeddingClient, b}, barrierGraph{base.graphQuerier.(*graph.StaticGraph), b})
+	r.SetSeedQuerier(barrierSeeds{map[string]string{"金币": "Kim tệ"}, b})
+
+	result, err := r.Retrieve(context.Background(), "获得金币", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.SeedTranslations) != 1 {
+		t.Errorf("seeds = %v, want the 金币 seed", result.SeedTranslations)
+	}
+	if len(result.SimilarTexts) == 0 {
+		t.Error("no similar texts")
+	}
+	if result.GraphContext == nil || len(result.GraphContext.Terms) == 0 {
+		t.Errorf("graph context = %+v, want terms", result.GraphContext)
+	}
+}
+
+func TestSearchEqualScoresStable(t *testing.T) {
+	texts := []string{"获得金币", "获得宝石", "获得神兵", "获得秘籍", "获得坐骑"}
+	byHash := slices.Clone(texts)
+	slices.SortFunc(byHash, func(a, b string) int { return cmp.Compare(textutil.Hash(a), textutil.Hash(b)) })
+
+	// The same records stored in two orders; every vector scores the same.
+	reversed := slices.Clone(texts)
+	slices.Reverse(reversed)
+	var searches [][]string
+	for _, order := range [][]string{texts, reversed} {
+		store := NewMemoryVectorStore()
+		var records []EmbeddingRecord
+		for _, text := range order {
+			records = append(records, EmbeddingRecord{Hash: textutil.Hash(text), Source: text, Vector: []float32{1, 0, 0}})
+		}
+		if err := store.Store(context.Background(), records); err != nil {
+			t.Fatal(err)
+		}
+		for range 3 {
+			results, err := store.Search(context.Background(), []float32{1, 0, 0}, len(texts))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var sources []string
+			for _, r := range results {
+				sources = append(sources, r.Source)
+			}
+			searches = append(searches, sources)
+		}
+
+		// Equal ranks keep the store's order when the retriever cuts them down.
+		r := NewRetriever(store, staticEmbedder{"离开门派": {1, 0, 0}}, graph.NewStaticGraph(nil, nil))
+		result, err := r.Retrieve(context.Background(), "离开门派", 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var similar []string
+		for _, st := range result.SimilarTexts {
+			similar = append(similar, st.Source)
+		}
+		if !slices.Equal(similar, byHash[:3]) {
+			t.Errorf("similar = %q, want %q", similar, byHash[:3])
+		}
+	}
+	for i, sources := range searches {
+		if !slices.Equal(sources, byHash) {
+			t.Errorf("search %d = %q, want %q in hash order", i+1, sources, byHash)
+		}
+	}
+}
@@ -2,16 +2,25 @@ package rag
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"rag-translator/internal/dbgen"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	pgvector "github.com/pgvector/pgvector-go"
 	"github.com/rs/zerolog/log"
 )
 
+// VectorSearcher stores embeddings and finds the ones most similar to a query.
+type VectorSearcher interface {
+	Store(ctx context.Context, records []EmbeddingRecord) error
+	Search(ctx context.Context, queryVector []float32, topK int) ([]SearchResult, error)
+}
+
 // VectorStore handles pgvector-backed embedding storage and similarity search.
+// It is the production VectorSearcher.
 type VectorStore struct {
 	pool    *pgxpool.Pool
 	queries *dbgen.Queries
@@ -32,39 +41,49 @@ type EmbeddingRecord struct {
 	Context  string
 	FilePath string
 	Vector   []float32
+	Weight   float64 // ranking weight, see WeightRule; 0 stores 1
 }
 
 // SearchResult represents a similarity search match.
 type SearchResult struct {
 	Source  string
 	Context string
-	Score   float64
+	Score   float64 // cosine similarity to the query
+	Weight  float64 // stored ranking weight, see Rank
 }
 
-// Store batch-inserts embedding records via sqlc.
+// Store batch-upserts embedding records via sqlc. Re-storing a hash replaces its
+// context and vector, so an interrupted ingest can safely be run again.
 func (vs *VectorStore) Store(ctx context.Context, records []EmbeddingRecord) error {
 	if len(records) == 0 {
 		return nil
 	}
 
 	for _, r := range records {
-		err := vs.queries.InsertEmbeddingWithVector(ctx, dbgen.InsertEmbeddingWithVectorParams{
+		weight := r.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		err := vs.queries.UpsertEmbeddingWithVector(ctx, dbgen.UpsertEmbeddingWithVectorParams{
 			Hash:     r.Hash,
 			Source:   r.Source,
 			Context:  r.Context,
 			FilePath: r.FilePath,
 			Column5:  pgvector.NewVector(r.Vector),
+			Weight:   weight,
 		})
 		if err != nil {
-			return fmt.Errorf("insert embedding %s: %w", r.Hash, err)
+			return fmt.Errorf("upsert embedding %s: %w", r.Hash, err)
 		}
 	}
 
-	log.Info().Int("count", len(records)).Msg("Stored embeddings")
+	log.Debug().Int("count", len(records)).Msg("Stored embeddings")
 	return nil
 }
 
-// Search finds the top-K most similar embeddings to the query vector.
+// Search finds the top-K most similar embeddings to the query vector. Equal
+// scores are ordered by text hash, so the same query always returns the same
+// results in the same order.
 func (vs *VectorStore) Search(ctx context.Context, queryVector []float32, topK int) ([]SearchResult, error) {
 	rows, err := vs.queries.SearchSimilarEmbeddings(ctx, dbgen.SearchSimilarEmbeddingsParams{
 		Column1: pgvector.NewVector(queryVector),
@@ -80,8 +99,63 @@ func (vs *VectorStore) Search(ctx context.Context, queryVector []float32, topK i
 			Source:  row.Source,
 			Context: row.Context,
 			Score:   row.Similarity,
+			Weight:  row.Weight,
 		})
 	}
 
 	return results, nil
 }
+
+// GetVectorByHash returns the stored embedding for a text hash.
+// The boolean is false when no embedding is stored for the hash.
+func (vs *VectorStore) GetVectorByHash(ctx context.Context, hash string) ([]float32, bool, error) {
+	vec, err := vs.queries.GetEmbeddingVectorByHash(ctx, hash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("get vector by hash: %w", err)
+	}
+	return vec.Slice(), true, nil
+}
+
+// ExistingHashes returns which of hashes already have a stored embedding.
+func (vs *VectorStore) ExistingHashes(ctx context.Context, hashes []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	if len(hashes) == 0 {
+		return existing, nil
+	}
+	rows, err := vs.queries.ListExistingEmbeddingHashes(ctx, hashes)
+	if err != nil {
+		return nil, fmt.Errorf("list existing embedding hashes: %w", err)
+	}
+	for _, hash := range rows {
+		existing[hash] = true
+	}
+	return existing, nil
+}
+
+// Sources returns the source text of every stored embedding, keyed by hash.
+func (vs *VectorStore) Sources(ctx context.Context) (map[string]string, error) {
+	rows, err := vs.queries.ListEmbeddingSources(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list embedding sources: %w", err)
+	}
+	sources := make(map[string]string, len(rows))
+	for _, row := range rows {
+		sources[row.Hash] = row.Source
+	}
+	return sources, nil
+}
+
+// Delete removes the embeddings with the given hashes and returns how many were deleted.
+func (vs *VectorStore) Delete(ctx context.Context, hashes []string) (int64, error) {
+	if len(hashes) == 0 {
+		return 0, nil
+	}
+	deleted, err := vs.queries.DeleteEmbeddingsByHash(ctx, hashes)
+	if err != nil {
+		return 0, fmt.Errorf("delete embeddings: %w", err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,63 @@
+package rag
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// WeightRule gives the embeddings of texts from files matching Pattern a ranking
+// weight. Weights above 1 favour the files as retrieval examples; weights below 1
+// demote them.
+type WeightRule struct {
+	// Pattern is a path.Match glob. Patterns containing "/" match the path relative
+	// to the input directory; others match the file name.
+	Pattern string
+	Weight  float64
+}
+
+// ParseWeightRules parses "glob=weight" items, such as "story/*.lua=1.5".
+func ParseWeightRules(items []string) ([]WeightRule, error) {
+	rules := make([]WeightRule, 0, len(items))
+	for _, item := range items {
+		pattern, value, ok := strings.Cut(item, "=")
+		pattern = strings.TrimSpace(pattern)
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("weight rule %q: expected glob=weight", item)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("weight rule %q: bad pattern: %w", item, err)
+		}
+		weight, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || weight <= 0 {
+			return nil, fmt.Errorf("weight rule %q: weight must be a positive number", item)
+		}
+		rules = append(rules, WeightRule{Pattern: pattern, Weight: weight})
+	}
+	return rules, nil
+}
+
+// FileWeight returns the weight of the first rule matching relPath
+// (slash-separated), or 1 when none does.
+func FileWeight(rules []WeightRule, relPath string) float64 {
+	for _, r := range rules {
+		target := relPath
+		if !strings.Contains(r.Pattern, "/") {
+			target = path.Base(relPath)
+		}
+		if ok, _ := path.Match(r.Pattern, target); ok {
+			return r.Weight
+		}
+	}
+	return 1
+}
+
+// Rank is the score search results are ordered by: the similarity scaled by the
+// stored weight. A zero weight, from a record stored without one, counts as 1.
+func (r SearchResult) Rank() float64 {
+	if r.Weight == 0 {
+		return r.Score
+	}
+	return r.Score * r.Weight
+}
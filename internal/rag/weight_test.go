@@ -0,0 +1,80 @@
+package rag
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"rag-translator/internal/graph"
+	"rag-translator/internal/textutil"
+)
+
+func TestParseWeightRules(t *testing.T) {
+	rules, err := ParseWeightRules([]string{"story/*.lua=1.5", " *.txt = 0.5 "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []WeightRule{{Pattern: "story/*.lua", Weight: 1.5}, {Pattern: "*.txt", Weight: 0.5}}
+	if !slices.Equal(rules, want) {
+		t.Errorf("rules = %+v, want %+v", rules, want)
+	}
+
+	for _, item := range []string{"story/*.lua", "=1.5", "*.lua=abc", "*.lua=0", "*.lua=-1", "[=1"} {
+		if _, err := ParseWeightRules([]string{item}); err == nil {
+			t.Errorf("ParseWeightRules(%q) succeeded, want an error", item)
+		}
+	}
+}
+
+func TestFileWeight(t *testing.T) {
+	rules := []WeightRule{
+		{Pattern: "story/*.lua", Weight: 1.5},
+		{Pattern: "*.lua", Weight: 1.2},
+		{Pattern: "*.txt", Weight: 0.5},
+	}
+	tests := []struct {
+		path string
+		want float64
+	}{
+		{"story/main.lua", 1.5},
+		{"ui/tips.lua", 1.2}, // first matching rule wins
+		{"story/sub/deep.lua", 1.2},
+		{"data/items.txt", 0.5},
+		{"config.ini", 1},
+	}
+	for _, tt := range tests {
+		if got := FileWeight(rules, tt.path); got != tt.want {
+			t.Errorf("FileWeight(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestWeightedRanking(t *testing.T) {
+	store := NewMemoryVectorStore()
+	records := []EmbeddingRecord{
+		{Source: "获得金币", Vector: []float32{1, 0.1, 0}, Weight: 0.5}, // most similar, demoted
+		{Source: "获得宝石", Vector: []float32{1, 0.3, 0}, Weight: 1.5}, // promoted
+		{Source: "获得神兵", Vector: []float32{1, 0.2, 0}},              // unweighted counts as 1
+		{Source: "离开门派", Vector: []float32{0.2, 1, 0}, Weight: 2},   // promoted but dissimilar
+	}
+	for i := range records {
+		records[i].Hash = textutil.Hash(records[i].Source)
+	}
+	if err := store.Store(context.Background(), records); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRetriever(store, staticEmbedder{"获得": {1, 0, 0}}, graph.NewStaticGraph(nil, nil))
+	result, err := r.Retrieve(context.Background(), "获得", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, st := range result.SimilarTexts {
+		got = append(got, st.Source)
+	}
+	want := []string{"获得宝石", "获得神兵", "获得金币"}
+	if !slices.Equal(got, want) {
+		t.Errorf("ranked %q, want %q", got, want)
+	}
+}
@@ -7,9 +7,11 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 
 	"rag-translator/internal/textutil"
+	"rag-translator/internal/worker"
 
 	"github.com/rs/zerolog/log"
 )
@@ -21,15 +23,49 @@ type SeedEntry struct {
 	File           string `json:"file"`
 	Function       string `json:"function,omitempty"`
 	EntityType     string `json:"entity_type,omitempty"`
+	Provenance     string `json:"provenance,omitempty"` // where the pair came from, e.g. "git:v1.0..v1.1"
+	Quality        string `json:"quality,omitempty"`    // review label, e.g. "expert"; see SeedStore.SetQualities
 	Hash           string `json:"hash"`
 }
 
+// VariableMismatchMode selects what IngestFromGit does with a pair whose source and
+// translation differ in interpolation variables, format specifiers or markup tags.
+type VariableMismatchMode string
+
+const (
+	// VariableMismatchReject drops the pair and logs it.
+	VariableMismatchReject VariableMismatchMode = "reject"
+	// VariableMismatchFlag keeps the pair and logs a warning for review.
+	VariableMismatchFlag VariableMismatchMode = "flag"
+	// VariableMismatchOff keeps every pair unchecked.
+	VariableMismatchOff VariableMismatchMode = "off"
+)
+
 // GitIngestor extracts translation pairs from Git diffs.
-type GitIngestor struct{}
+type GitIngestor struct {
+	workers          int    // concurrent per-file git diff invocations
+	quality          string // quality label given to every extracted entry
+	variableMismatch VariableMismatchMode
+}
 
 // NewGitIngestor creates a new Git ingestor.
 func NewGitIngestor() *GitIngestor {
-	return &GitIngestor{}
+	return &GitIngestor{workers: 1, variableMismatch: VariableMismatchReject}
+}
+
+// SetVariableMismatchMode selects how pairs with mismatched variables are handled.
+func (gi *GitIngestor) SetVariableMismatchMode(mode VariableMismatchMode) {
+	gi.variableMismatch = mode
+}
+
+// SetWorkers sets how many changed files are diffed concurrently.
+func (gi *GitIngestor) SetWorkers(n int) {
+	gi.workers = n
+}
+
+// SetQuality sets the quality label given to every extracted entry.
+func (gi *GitIngestor) SetQuality(quality string) {
+	gi.quality = quality
 }
 
 // supportedExts lists file extensions to process.
@@ -40,6 +76,7 @@ var supportedExts = map[string]bool{
 }
 
 // IngestFromGit extracts seed translation pairs by diffing two git refs for a given folder.
+// Entries are tagged with the provenance "git:<base>..<target>" and the configured quality.
 func (gi *GitIngestor) IngestFromGit(ctx context.Context, repoRoot, commitBase, commitTarget, folder string) ([]SeedEntry, error) {
 	files, err := gi.getChangedFiles(ctx, repoRoot, commitBase, commitTarget, folder)
 	if err != nil {
@@ -48,48 +85,118 @@ func (gi *GitIngestor) IngestFromGit(ctx context.Context, repoRoot, commitBase,
 
 	log.Info().Int("files", len(files)).Msg("Found changed files in Git diff")
 
-	var allEntries []SeedEntry
-
+	var supported []string
 	for _, file := range files {
-		ext := strings.ToLower(filepath.Ext(file))
-		if !supportedExts[ext] {
-			continue
+		if supportedExts[strings.ToLower(filepath.Ext(file))] {
+			supported = append(supported, file)
 		}
+	}
+
+	// Each file is diffed by its own git process, so files are independent.
+	// Results come back in input order, keeping the entries deterministic.
+	pool := worker.NewPool[string, []SeedEntry](gi.workers,
+		func(ctx context.Context, file string) ([]SeedEntry, error) {
+			return gi.extractPairsFromDiff(ctx, repoRoot, commitBase, commitTarget, file)
+		},
+	)
+	results := pool.Execute(ctx, supported)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-		entries, err := gi.extractPairsFromDiff(ctx, repoRoot, commitBase, commitTarget, file)
-		if err != nil {
-			log.Warn().Err(err).Str("file", file).Msg("Failed to extract pairs from diff")
+	provenance := fmt.Sprintf("git:%s..%s", commitBase, commitTarget)
+	var allEntries []SeedEntry
+	rejected := 0
+	for _, r := range results {
+		if r.Err != nil {
+			log.Warn().Err(r.Err).Str("file", r.Input).Msg("Failed to extract pairs from diff")
 			continue
 		}
 
-		allEntries = append(allEntries, entries...)
-		log.Debug().Str("file", file).Int("pairs", len(entries)).Msg("Extracted translation pairs")
+		for _, e := range r.Result {
+			if !gi.checkVariables(e) {
+				rejected++
+				continue
+			}
+			e.Provenance = provenance
+			e.Quality = gi.quality
+			allEntries = append(allEntries, e)
+		}
+		log.Debug().Str("file", r.Input).Int("pairs", len(r.Result)).Msg("Extracted translation pairs")
 	}
 
-	log.Info().Int("total_pairs", len(allEntries)).Msg("Git diff ingestion complete")
+	log.Info().Int("total_pairs", len(allEntries)).Int("rejected_pairs", rejected).Msg("Git diff ingestion complete")
 	return allEntries, nil
 }
 
+// checkVariables reports whether e is kept under the variable mismatch mode,
+// logging a pair whose source and translation placeholders differ.
+func (gi *GitIngestor) checkVariables(e SeedEntry) bool {
+	if gi.variableMismatch == VariableMismatchOff {
+		return true
+	}
+	src, dst := placeholders(e.SourceText), placeholders(e.TranslatedText)
+	if slices.Equal(src, dst) {
+		return true
+	}
+	event := log.Warn()
+	if gi.variableMismatch == VariableMismatchReject {
+		event = log.Info()
+	}
+	event.
+		Str("file", e.File).
+		Str("source", textutil.Truncate(e.SourceText, 60)).
+		Str("translation", textutil.Truncate(e.TranslatedText, 60)).
+		Strs("source_vars", src).
+		Strs("translation_vars", dst).
+		Str("mode", string(gi.variableMismatch)).
+		Msg("Seed pair variables differ")
+	return gi.variableMismatch != VariableMismatchReject
+}
+
 // getChangedFiles retrieves the list of changed files between two commits in a folder.
 func (gi *GitIngestor) getChangedFiles(ctx context.Context, repoRoot, commitBase, commitTarget, folder string) ([]string, error) {
-	cmd := exec.CommandContext(ctx, "git", "diff", "--name-only", commitBase, commitTarget, "--", folder)
-	cmd.Dir = repoRoot
+	files, err := gitLines(ctx, repoRoot, "diff", "--name-only", commitBase, commitTarget, "--", folder)
+	if err != nil {
+		return nil, fmt.Errorf("git diff --name-only: %w", err)
+	}
+	return files, nil
+}
+
+// ChangedFilesSince lists the files under dir that differ from ref in the working
+// tree, plus untracked files that are not ignored. Paths are relative to dir.
+func ChangedFilesSince(ctx context.Context, dir, ref string) ([]string, error) {
+	changed, err := gitLines(ctx, dir, "-c", "core.quotePath=false", "diff", "--name-only", "--relative", ref, "--", ".")
+	if err != nil {
+		return nil, fmt.Errorf("git diff --name-only %s: %w", ref, err)
+	}
+	untracked, err := gitLines(ctx, dir, "-c", "core.quotePath=false", "ls-files", "--others", "--exclude-standard", "--", ".")
+	if err != nil {
+		return nil, fmt.Errorf("git ls-files: %w", err)
+	}
+	return append(changed, untracked...), nil
+}
+
+// gitLines runs git with args in dir and returns the non-empty lines of its output.
+func gitLines(ctx context.Context, dir string, args ...string) ([]string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
 
 	output, err := cmd.Output()
 	if err != nil {
-		return nil, fmt.Errorf("git diff --name-only: %w", err)
+		return nil, err
 	}
 
-	var files []string
+	var lines []string
 	scanner := bufio.NewScanner(strings.NewReader(string(output)))
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 		if line != "" {
-			files = append(files, line)
+			lines = append(lines, line)
 		}
 	}
 
-	return files, nil
+	return lines, nil
 }
 
 // diffHunk represents a group of removed/added lines from a diff.
@@ -180,7 +287,7 @@ func matchPairs(hunk diffHunk, ext, file string) []SeedEntry {
 	for i := 0; i < pairCount; i++ {
 		srcText, dstText, fnName := extractTextPair(hunk.removed[i], hunk.added[i], ext)
 
-		if srcText == "" || dstText == "" || !textutil.ContainsChinese(srcText) {
+		if srcText == "" || dstText == "" || !textutil.ContainsSource(srcText) {
 			continue
 		}
 
@@ -190,7 +297,7 @@ func matchPairs(hunk diffHunk, ext, file string) []SeedEntry {
 			File:           file,
 			Function:       fnName,
 			EntityType:     detectEntityType(file, fnName, srcText),
-			Hash:           textutil.Hash(srcText),
+			Hash:           textutil.CanonicalHash(srcText),
 		})
 	}
 
@@ -261,7 +368,7 @@ func extractTXTPair(source, translated string) (string, string, string) {
 	}
 
 	for i := range srcCols {
-		if srcCols[i] != dstCols[i] && textutil.ContainsChinese(srcCols[i]) {
+		if srcCols[i] != dstCols[i] && textutil.ContainsSource(srcCols[i]) {
 			return srcCols[i], dstCols[i], ""
 		}
 	}
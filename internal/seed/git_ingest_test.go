@@ -0,0 +1,105 @@
+package seed
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// gitRepo creates a repository with files, keyed by path, committed as base and
+// then rewritten with translated as target.
+func gitRepo(t *testing.T, files, translated map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(contents map[string]string) {
+		t.Helper()
+		for name, content := range contents {
+			path := filepath.Join(dir, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	run("init", "-q")
+	write(files)
+	run("add", "-A")
+	run("commit", "-q", "-m", "base")
+	run("tag", "base")
+	write(translated)
+	run("add", "-A")
+	run("commit", "-q", "-m", "target")
+	run("tag", "target")
+	return dir
+}
+
+func TestIngestFromGitManyFiles(t *testing.T) {
+	const fileCount = 40
+	files := make(map[string]string)
+	translated := make(map[string]string)
+	want := make(map[string]string)
+	for i := range fileCount {
+		name := fmt.Sprintf("script/quest%02d.lua", i)
+		files[name] = fmt.Sprintf("ShowMsg(\"获得物品%d\")\nlocal x = 1\nShowMsg(\"完成任务%d\")\n", i, i)
+		translated[name] = fmt.Sprintf("ShowMsg(\"Nhận vật phẩm %d\")\nlocal x = 1\nShowMsg(\"Hoàn thành nhiệm vụ %d\")\n", i, i)
+		want[fmt.Sprintf("获得物品%d", i)] = fmt.Sprintf("Nhận vật phẩm %d", i)
+		want[fmt.Sprintf("完成任务%d", i)] = fmt.Sprintf("Hoàn thành nhiệm vụ %d", i)
+	}
+	files["script/icon.png"] = "png"
+	translated["script/icon.png"] = "png2"
+	repo := gitRepo(t, files, translated)
+
+	var sequential []SeedEntry
+	for _, workers := range []int{1, 8} {
+		gi := NewGitIngestor()
+		gi.SetWorkers(workers)
+		entries, err := gi.IngestFromGit(context.Background(), repo, "base", "target", "script")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := make(map[string]string, len(entries))
+		for _, e := range entries {
+			if prev, dup := got[e.SourceText]; dup {
+				t.Errorf("%d workers: %q extracted twice (%q, %q)", workers, e.SourceText, prev, e.TranslatedText)
+			}
+			got[e.SourceText] = e.TranslatedText
+			if e.Provenance != "git:base..target" {
+				t.Errorf("%d workers: provenance = %q", workers, e.Provenance)
+			}
+		}
+		if len(got) != len(want) {
+			t.Errorf("%d workers: %d pairs, want %d", workers, len(got), len(want))
+		}
+		for src, dst := range want {
+			if got[src] != dst {
+				t.Errorf("%d workers: %q → %q, want %q", workers, src, got[src], dst)
+			}
+		}
+
+		// Whatever order the workers finish in, entries keep the order of the files.
+		if sequential == nil {
+			sequential = entries
+		} else if !slices.Equal(entries, sequential) {
+			t.Errorf("%d workers: entries differ in order from a sequential run", workers)
+		}
+	}
+}
@@ -4,13 +4,16 @@ import (
 	"context"
 	"fmt"
 
+	"rag-translator/internal/graph"
+
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
 	"github.com/rs/zerolog/log"
 )
 
 // GraphSeeder creates and updates Neo4j nodes for seed translation entries.
 type GraphSeeder struct {
-	driver neo4j.DriverWithContext
+	driver    neo4j.DriverWithContext
+	qualities []string // FindSeedTranslations only returns seeds with these labels; empty means all
 }
 
 // NewGraphSeeder creates a new graph seeder.
@@ -18,12 +21,15 @@ func NewGraphSeeder(driver neo4j.DriverWithContext) *GraphSeeder {
 	return &GraphSeeder{driver: driver}
 }
 
+// SetQualities restricts FindSeedTranslations to seeds labelled with one of
+// qualities. Empty returns all seeds.
+func (gs *GraphSeeder) SetQualities(qualities []string) {
+	gs.qualities = qualities
+}
+
 // EnsureSchema creates constraints for seed nodes.
 func (gs *GraphSeeder) EnsureSchema(ctx context.Context) error {
-	session := gs.driver.NewSession(ctx, neo4j.SessionConfig{})
-	defer session.Close(ctx)
-
-	_, err := session.Run(ctx,
+	err := graph.WriteStatement(ctx, gs.driver,
 		"CREATE CONSTRAINT IF NOT EXISTS FOR (s:SeedTranslation) REQUIRE s.hash IS UNIQUE",
 		nil,
 	)
@@ -36,93 +42,112 @@ func (gs *GraphSeeder) EnsureSchema(ctx context.Context) error {
 }
 
 // UpsertSeedNodes creates or updates SeedTranslation nodes and links them to matching Term nodes.
-func (gs *GraphSeeder) UpsertSeedNodes(ctx context.Context, entries []SeedEntry) error {
+// Each entry is written in its own transaction on a shared session, so a node is never
+// left without its links. It returns the number of entries written; failures are logged and skipped.
+func (gs *GraphSeeder) UpsertSeedNodes(ctx context.Context, entries []SeedEntry) (int, error) {
 	session := gs.driver.NewSession(ctx, neo4j.SessionConfig{})
 	defer session.Close(ctx)
 
+	upserted := 0
 	for _, e := range entries {
-		// Create/update the SeedTranslation node.
-		_, err := session.Run(ctx, `
-			MERGE (s:SeedTranslation {hash: $hash})
-			SET s.source_text = $source,
-			    s.translated_text = $translated,
-			    s.file = $file,
-			    s.function_name = $function,
-			    s.entity_type = $entity_type,
-			    s.is_seed = true
-		`, map[string]any{
-			"hash":        e.Hash,
-			"source":      e.SourceText,
-			"translated":  e.TranslatedText,
-			"file":        e.File,
-			"function":    e.Function,
-			"entity_type": e.EntityType,
+		_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			return nil, upsertSeedNode(ctx, tx, e)
 		})
 		if err != nil {
 			log.Warn().Err(err).Str("hash", e.Hash).Msg("Failed to upsert seed node")
 			continue
 		}
+		upserted++
+	}
 
-		// Link to matching Term nodes (terminology that appears in the source text).
-		_, err = session.Run(ctx, `
-			MATCH (term:Term)
-			WHERE $source CONTAINS term.chinese
-			MATCH (s:SeedTranslation {hash: $hash})
-			MERGE (s)-[:DEMONSTRATES_TERM]->(term)
-		`, map[string]any{
-			"source": e.SourceText,
-			"hash":   e.Hash,
-		})
-		if err != nil {
-			log.Warn().Err(err).Str("hash", e.Hash).Msg("Failed to link seed to terms")
-		}
+	log.Info().Int("entries", len(entries)).Int("upserted", upserted).Msg("Upserted seed nodes in graph")
+	return upserted, nil
+}
 
-		// Also link to TextNode if exists (from prior ingestion).
-		_, err = session.Run(ctx, `
-			MATCH (t:TextNode {text: $source})
-			MATCH (s:SeedTranslation {hash: $hash})
-			MERGE (s)-[:TRANSLATES]->(t)
-		`, map[string]any{
-			"source": e.SourceText,
-			"hash":   e.Hash,
-		})
-		if err != nil {
-			// Not an error — TextNode may not exist yet.
-			log.Debug().Str("hash", e.Hash).Msg("No matching TextNode for seed")
-		}
+// upsertSeedNode writes one SeedTranslation node and its links inside tx.
+func upsertSeedNode(ctx context.Context, tx neo4j.ManagedTransaction, e SeedEntry) error {
+	// Create/update the SeedTranslation node.
+	err := graph.RunInTx(ctx, tx, `
+		MERGE (s:SeedTranslation {hash: $hash})
+		SET s.source_text = $source,
+		    s.translated_text = $translated,
+		    s.file = $file,
+		    s.function_name = $function,
+		    s.entity_type = $entity_type,
+		    s.provenance = $provenance,
+		    s.quality = $quality,
+		    s.is_seed = true
+	`, map[string]any{
+		"hash":        e.Hash,
+		"source":      e.SourceText,
+		"translated":  e.TranslatedText,
+		"file":        e.File,
+		"function":    e.Function,
+		"entity_type": e.EntityType,
+		"provenance":  e.Provenance,
+		"quality":     e.Quality,
+	})
+	if err != nil {
+		return fmt.Errorf("upsert seed node: %w", err)
+	}
+
+	// Link to matching Term nodes (terminology that appears in the source text).
+	err = graph.RunInTx(ctx, tx, `
+		MATCH (term:Term)
+		WHERE $source CONTAINS term.chinese
+		MATCH (s:SeedTranslation {hash: $hash})
+		MERGE (s)-[:DEMONSTRATES_TERM]->(term)
+	`, map[string]any{
+		"source": e.SourceText,
+		"hash":   e.Hash,
+	})
+	if err != nil {
+		return fmt.Errorf("link seed to terms: %w", err)
 	}
 
-	log.Info().Int("entries", len(entries)).Msg("Upserted seed nodes in graph")
+	// Also link to TextNode if exists (from prior ingestion). No match is not an error.
+	err = graph.RunInTx(ctx, tx, `
+		MATCH (t:TextNode {text: $source})
+		MATCH (s:SeedTranslation {hash: $hash})
+		MERGE (s)-[:TRANSLATES]->(t)
+	`, map[string]any{
+		"source": e.SourceText,
+		"hash":   e.Hash,
+	})
+	if err != nil {
+		return fmt.Errorf("link seed to text node: %w", err)
+	}
 	return nil
 }
 
 // FindSeedTranslations queries the graph for seed translations relevant to a source text.
 // Returns source→translated pairs from seed entries whose source_text appears in the input
-// or whose associated terms match.
+// or whose associated terms match, limited to the qualities set by SetQualities.
 func (gs *GraphSeeder) FindSeedTranslations(ctx context.Context, text string) (map[string]string, error) {
-	session := gs.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
-	defer session.Close(ctx)
-
 	// Find seeds where the source text contains matching terms, or where the seed's
 	// source text overlaps with the query.
-	result, err := session.Run(ctx, `
+	qualities := gs.qualities
+	if qualities == nil {
+		qualities = []string{}
+	}
+	records, err := graph.ReadRecords(ctx, gs.driver, `
 		MATCH (s:SeedTranslation)
-		WHERE $text CONTAINS s.source_text
-		   OR s.source_text CONTAINS $text
+		WHERE ($text CONTAINS s.source_text OR s.source_text CONTAINS $text)
+		  AND (size($qualities) = 0 OR coalesce(s.quality, '') IN $qualities)
 		RETURN s.source_text AS source, s.translated_text AS translated
 		UNION
 		MATCH (term:Term)
 		WHERE $text CONTAINS term.chinese
 		MATCH (s:SeedTranslation)-[:DEMONSTRATES_TERM]->(term)
+		WHERE size($qualities) = 0 OR coalesce(s.quality, '') IN $qualities
 		RETURN s.source_text AS source, s.translated_text AS translated
-	`, map[string]any{"text": text})
+	`, map[string]any{"text": text, "qualities": qualities})
 	if err != nil {
 		return nil, fmt.Errorf("find seed translations: %w", err)
 	}
 
 	pairs := make(map[string]string)
-	for result.Next(ctx) {
-		record := result.Record()
+	for _, record := range records {
 		source, _ := record.Get("source")
 		translated, _ := record.Get("translated")
 		pairs[fmt.Sprintf("%v", source)] = fmt.Sprintf("%v", translated)
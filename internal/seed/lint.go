@@ -0,0 +1,69 @@
+package seed
+
+import (
+	"fmt"
+	"slices"
+	"unicode/utf8"
+
+	"rag-translator/internal/interpolation"
+	"rag-translator/internal/textutil"
+)
+
+// minRatioRunes is the shortest source whose length ratio is checked: a single
+// word legitimately grows many times longer in translation.
+const minRatioRunes = 4
+
+// LintIssue is a seed entry that looks wrong, with the reasons why.
+type LintIssue struct {
+	Entry    SeedEntry
+	Problems []string
+}
+
+// Lint checks every entry for the marks of a noisy diff-mined pair: a source with
+// no source-language text, a translation still containing it, interpolation
+// variables or markup tags that differ between the two, and a translation more
+// than maxRatio times longer or shorter than its source. A maxRatio of 0 skips
+// the length check.
+func Lint(entries []SeedEntry, maxRatio float64) []LintIssue {
+	var issues []LintIssue
+	for _, e := range entries {
+		if problems := lintEntry(e, maxRatio); len(problems) > 0 {
+			issues = append(issues, LintIssue{Entry: e, Problems: problems})
+		}
+	}
+	return issues
+}
+
+// lintEntry returns the problems of one entry.
+func lintEntry(e SeedEntry, maxRatio float64) []string {
+	var problems []string
+	if !textutil.ContainsSource(e.SourceText) {
+		problems = append(problems, "source has no source-language text")
+	}
+	if textutil.ContainsSource(e.TranslatedText) {
+		problems = append(problems, "translation contains source-language text")
+	}
+	if src, dst := placeholders(e.SourceText), placeholders(e.TranslatedText); !slices.Equal(src, dst) {
+		problems = append(problems, fmt.Sprintf("variables differ: source %v, translation %v", src, dst))
+	}
+	srcLen := utf8.RuneCountInString(e.SourceText)
+	dstLen := utf8.RuneCountInString(e.TranslatedText)
+	if maxRatio > 0 && srcLen >= minRatioRunes {
+		if ratio := float64(dstLen) / float64(srcLen); ratio > maxRatio || ratio*maxRatio < 1 {
+			problems = append(problems, fmt.Sprintf("length ratio %.1f", ratio))
+		}
+	}
+	return problems
+}
+
+// placeholders returns the sorted interpolation variables, format specifiers and
+// markup tags of text.
+func placeholders(text string) []string {
+	_, mappings := interpolation.ProtectWithOptions(text, interpolation.Options{Tags: true})
+	vars := make([]string, len(mappings))
+	for i, m := range mappings {
+		vars[i] = m.Original
+	}
+	slices.Sort(vars)
+	return vars
+}
@@ -8,21 +8,53 @@ import (
 	"strings"
 
 	"rag-translator/internal/dbgen"
+	"rag-translator/internal/textutil"
 
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/zerolog/log"
 )
 
+// NearDuplicateMode selects how FilterNearDuplicates treats a seed whose normalized source text
+// matches an existing seed with a different hash.
+type NearDuplicateMode string
+
+const (
+	// NearDuplicatesOff stores every distinct hash.
+	NearDuplicatesOff NearDuplicateMode = "off"
+	// NearDuplicatesMerge keeps the existing seed and skips the near-duplicate.
+	NearDuplicatesMerge NearDuplicateMode = "merge"
+	// NearDuplicatesFlag stores the near-duplicate and logs a warning for review.
+	NearDuplicatesFlag NearDuplicateMode = "flag"
+)
+
 // SeedStore handles persistence of seed translation pairs in PostgreSQL and file export.
 type SeedStore struct {
-	queries *dbgen.Queries
+	queries        *dbgen.Queries
+	nearDuplicates NearDuplicateMode
+	qualities      []string // FindSeedTranslations only returns seeds with these labels; nil means all
 }
 
 // NewSeedStore creates a new seed store.
 func NewSeedStore(pool *pgxpool.Pool) *SeedStore {
 	return &SeedStore{
-		queries: dbgen.New(pool),
+		queries:        dbgen.New(pool),
+		nearDuplicates: NearDuplicatesOff,
+	}
+}
+
+// SetNearDuplicateMode enables near-duplicate detection in FilterNearDuplicates.
+func (ss *SeedStore) SetNearDuplicateMode(mode NearDuplicateMode) {
+	ss.nearDuplicates = mode
+}
+
+// SetQualities restricts FindSeedTranslations to seeds labelled with one of
+// qualities, so only trusted seeds reach the prompts. Empty returns all seeds.
+func (ss *SeedStore) SetQualities(qualities []string) {
+	if len(qualities) == 0 {
+		qualities = nil
 	}
+	ss.qualities = qualities
 }
 
 // Upsert inserts or updates seed entries, deduplicating by hash.
@@ -35,6 +67,8 @@ func (ss *SeedStore) Upsert(ctx context.Context, entries []SeedEntry) (inserted,
 			File:           e.File,
 			FunctionName:   e.Function,
 			EntityType:     e.EntityType,
+			Provenance:     e.Provenance,
+			Quality:        e.Quality,
 		})
 		if execErr != nil {
 			return inserted, updated, fmt.Errorf("upsert seed entry: %w", execErr)
@@ -48,6 +82,62 @@ func (ss *SeedStore) Upsert(ctx context.Context, entries []SeedEntry) (inserted,
 	return inserted, updated, nil
 }
 
+// FilterNearDuplicates checks entries against stored seeds and earlier entries by
+// normalized source text. In merge mode near-duplicates are dropped; in flag mode
+// they are kept and logged. With detection off, entries are returned unchanged.
+func (ss *SeedStore) FilterNearDuplicates(ctx context.Context, entries []SeedEntry) ([]SeedEntry, error) {
+	if ss.nearDuplicates == NearDuplicatesOff {
+		return entries, nil
+	}
+
+	existing, err := ss.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byNorm := make(map[string]SeedEntry, len(existing)+len(entries))
+	for _, e := range existing {
+		byNorm[textutil.Normalize(e.SourceText)] = e
+	}
+
+	kept := make([]SeedEntry, 0, len(entries))
+	merged, flagged := 0, 0
+	for _, e := range entries {
+		norm := textutil.Normalize(e.SourceText)
+		prev, ok := byNorm[norm]
+		if !ok || prev.Hash == e.Hash {
+			byNorm[norm] = e
+			kept = append(kept, e)
+			continue
+		}
+
+		if ss.nearDuplicates == NearDuplicatesMerge {
+			merged++
+			log.Debug().
+				Str("source", e.SourceText).
+				Str("merged_into", prev.SourceText).
+				Msg("Merged near-duplicate seed")
+			continue
+		}
+
+		flagged++
+		log.Warn().
+			Str("source", e.SourceText).
+			Str("translated", e.TranslatedText).
+			Str("existing_source", prev.SourceText).
+			Str("existing_translated", prev.TranslatedText).
+			Msg("Near-duplicate seed")
+		kept = append(kept, e)
+	}
+
+	log.Info().
+		Int("merged", merged).
+		Int("flagged", flagged).
+		Str("mode", string(ss.nearDuplicates)).
+		Msg("Checked seeds for near-duplicates")
+	return kept, nil
+}
+
 // GetAll retrieves all seed entries from the store.
 func (ss *SeedStore) GetAll(ctx context.Context) ([]SeedEntry, error) {
 	rows, err := ss.queries.GetAllSeedTranslations(ctx)
@@ -64,6 +154,8 @@ func (ss *SeedStore) GetAll(ctx context.Context) ([]SeedEntry, error) {
 			File:           row.File,
 			Function:       row.FunctionName,
 			EntityType:     row.EntityType,
+			Provenance:     row.Provenance,
+			Quality:        row.Quality,
 		})
 	}
 
@@ -86,12 +178,71 @@ func (ss *SeedStore) GetByEntityType(ctx context.Context, entityType string) ([]
 			File:           row.File,
 			Function:       row.FunctionName,
 			EntityType:     row.EntityType,
+			Provenance:     row.Provenance,
+			Quality:        row.Quality,
+		})
+	}
+
+	return entries, nil
+}
+
+// Search returns up to limit seed entries whose source or translated text contains
+// query (case-insensitive), optionally restricted to one entity type and quality.
+func (ss *SeedStore) Search(ctx context.Context, query, entityType, quality string, limit int) ([]SeedEntry, error) {
+	rows, err := ss.queries.SearchSeedTranslations(ctx, dbgen.SearchSeedTranslationsParams{
+		Query:      escapeLike(query),
+		EntityType: pgtype.Text{String: entityType, Valid: entityType != ""},
+		Quality:    pgtype.Text{String: quality, Valid: quality != ""},
+		MaxResults: int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("search seed entries: %w", err)
+	}
+
+	entries := make([]SeedEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, SeedEntry{
+			Hash:           row.Hash,
+			SourceText:     row.SourceText,
+			TranslatedText: row.TranslatedText,
+			File:           row.File,
+			Function:       row.FunctionName,
+			EntityType:     row.EntityType,
+			Provenance:     row.Provenance,
+			Quality:        row.Quality,
 		})
 	}
 
 	return entries, nil
 }
 
+// seedQueryLimit caps the seed translations returned for one text.
+const seedQueryLimit = 20
+
+// FindSeedTranslations returns source→translated pairs from seeds whose source text
+// appears in text or contains it, longest first, limited to the qualities set by
+// SetQualities. It implements rag.SeedQuerier with SQL, so seed retrieval does not
+// need Neo4j. Matching seeds contained in text scans seed_translations on every
+// call, as the trigram index only serves the reverse match.
+func (ss *SeedStore) FindSeedTranslations(ctx context.Context, text string) (map[string]string, error) {
+	rows, err := ss.queries.FindSeedTranslationsForText(ctx, dbgen.FindSeedTranslationsForTextParams{
+		Text:       text,
+		Pattern:    escapeLike(text),
+		Qualities:  ss.qualities,
+		MaxResults: seedQueryLimit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("find seed translations: %w", err)
+	}
+
+	pairs := make(map[string]string, len(rows))
+	for _, row := range rows {
+		pairs[row.SourceText] = row.TranslatedText
+	}
+
+	return pairs, nil
+}
+
 // ExportTSV writes all seed entries to a TSV file.
 func (ss *SeedStore) ExportTSV(ctx context.Context, outputPath string) error {
 	entries, err := ss.GetAll(ctx)
@@ -105,15 +256,17 @@ func (ss *SeedStore) ExportTSV(ctx context.Context, outputPath string) error {
 	}
 	defer f.Close()
 
-	fmt.Fprintln(f, "source_text\ttranslated_text\tfile\tfunction\tentity_type")
+	fmt.Fprintln(f, "source_text\ttranslated_text\tfile\tfunction\tentity_type\tprovenance\tquality")
 
 	for _, e := range entries {
-		fmt.Fprintf(f, "%s\t%s\t%s\t%s\t%s\n",
+		fmt.Fprintf(f, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
 			escapeTSV(e.SourceText),
 			escapeTSV(e.TranslatedText),
 			e.File,
 			e.Function,
 			e.EntityType,
+			e.Provenance,
+			e.Quality,
 		)
 	}
 
@@ -161,6 +314,11 @@ func (ss *SeedStore) BuildTranslationMap(ctx context.Context) (map[string]string
 	return m, nil
 }
 
+// escapeLike escapes LIKE wildcards so a search query matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
 // escapeTSV replaces tabs and newlines in a string for TSV safety.
 func escapeTSV(s string) string {
 	s = strings.ReplaceAll(s, "\t", "\\t")
@@ -0,0 +1,169 @@
+package seed
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"rag-translator/internal/dbgen"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// seedRow is one row of the fake seed_translations table.
+type seedRow struct {
+	source, translated, quality string
+}
+
+// fakeSeedDB answers FindSeedTranslationsForText by evaluating its WHERE clause
+// in Go, with LIKE escapes interpreted as PostgreSQL does.
+type fakeSeedDB struct {
+	rows []seedRow
+}
+
+func (db *fakeSeedDB) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, errors.New("not implemented")
+}
+
+func (db *fakeSeedDB) QueryRow(context.Context, string, ...any) pgx.Row {
+	return nil
+}
+
+func (db *fakeSeedDB) Query(_ context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if !strings.Contains(sql, "name: FindSeedTranslationsForText") {
+		return nil, errors.New("unexpected query")
+	}
+	text, pattern := args[0].(string), args[1].(string)
+	qualities, limit := args[2].([]string), int(args[3].(int32))
+
+	like := regexp.MustCompile("(?is)^" + likeRegexp("%"+pattern+"%") + "$")
+	var matched []seedRow
+	for _, r := range db.rows {
+		if qualities != nil && !slices.Contains(qualities, r.quality) {
+			continue
+		}
+		if strings.Contains(text, r.source) || like.MatchString(r.source) {
+			matched = append(matched, r)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if len(matched[i].source) != len(matched[j].source) {
+			return len(matched[i].source) > len(matched[j].source)
+		}
+		return matched[i].source < matched[j].source
+	})
+	if len(matched) > limit {
+		matched = matched[:limit]
+	}
+	return &fakeSeedRows{rows: matched, pos: -1}, nil
+}
+
+// likeRegexp translates a LIKE pattern with the default backslash escape.
+func likeRegexp(pattern string) string {
+	var sb strings.Builder
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch c := runes[i]; {
+		case c == '\\' && i+1 < len(runes):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(runes[i])))
+		case c == '%':
+			sb.WriteString(".*")
+		case c == '_':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
+
+type fakeSeedRows struct {
+	rows []seedRow
+	pos  int
+}
+
+func (r *fakeSeedRows) Close()                                       {}
+func (r *fakeSeedRows) Err() error                                   { return nil }
+func (r *fakeSeedRows) CommandTag() pgconn.CommandTag                { return pgconn.CommandTag{} }
+func (r *fakeSeedRows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (r *fakeSeedRows) Values() ([]any, error)                       { return nil, nil }
+func (r *fakeSeedRows) RawValues() [][]byte                          { return nil }
+func (r *fakeSeedRows) Conn() *pgx.Conn                              { return nil }
+
+func (r *fakeSeedRows) Next() bool {
+	r.pos++
+	return r.pos < len(r.rows)
+}
+
+func (r *fakeSeedRows) Scan(dest ...any) error {
+	*dest[0].(*string) = r.rows[r.pos].source
+	*dest[1].(*string) = r.rows[r.pos].translated
+	return nil
+}
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"张无忌", "张无忌"},
+		{"50%", `50\%`},
+		{"a_b", `a\_b`},
+		{`C:\path`, `C:\\path`},
+		{`\%`, `\\\%`},
+	}
+	for _, tt := range tests {
+		if got := escapeLike(tt.in); got != tt.want {
+			t.Errorf("escapeLike(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFindSeedTranslations(t *testing.T) {
+	db := &fakeSeedDB{rows: []seedRow{
+		{"张无忌", "Trương Vô Kỵ", "verified"},
+		{"明教", "Minh Giáo", "verified"},
+		{"暴击50%提升", "Chí mạng tăng 50%", "verified"},
+		{"50点伤害", "50 sát thương", "verified"},
+		{"a_b_c", "abc", "verified"},
+		{"axb", "axb", "verified"},
+		{`路径C:\x`, "đường dẫn", "verified"},
+		{"峨眉派", "Nga Mi Phái", "draft"},
+	}}
+	tests := []struct {
+		name      string
+		text      string
+		qualities []string
+		want      []string
+	}{
+		{"seeds contained in the text", "张无忌加入了明教", nil, []string{"张无忌", "明教"}},
+		{"seed containing the text", "无忌", nil, []string{"张无忌"}},
+		{"percent matches literally", "50%", nil, []string{"暴击50%提升"}},
+		{"underscore matches literally", "a_b", nil, []string{"a_b_c"}},
+		{"backslash matches literally", `C:\x`, nil, []string{`路径C:\x`}},
+		{"quality filter", "峨眉派与明教", []string{"verified"}, []string{"明教"}},
+		{"no match", "少林寺", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss := &SeedStore{queries: dbgen.New(db)}
+			ss.SetQualities(tt.qualities)
+			pairs, err := ss.FindSeedTranslations(context.Background(), tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := slices.Sorted(maps.Keys(pairs))
+			want := slices.Clone(tt.want)
+			slices.Sort(want)
+			if !slices.Equal(got, want) {
+				t.Errorf("seeds = %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -14,6 +14,7 @@ import (
 type VectorSeeder struct {
 	embeddingClient *rag.EmbeddingClient
 	vectorStore     *rag.VectorStore
+	weight          float64 // ranking weight of seed embeddings
 }
 
 // NewVectorSeeder creates a new vector seeder.
@@ -21,9 +22,16 @@ func NewVectorSeeder(ec *rag.EmbeddingClient, vs *rag.VectorStore) *VectorSeeder
 	return &VectorSeeder{
 		embeddingClient: ec,
 		vectorStore:     vs,
+		weight:          1,
 	}
 }
 
+// SetWeight sets the ranking weight stored with seed embeddings. A weight above
+// that of ingested texts makes verified seeds win over them as similar examples.
+func (vs *VectorSeeder) SetWeight(weight float64) {
+	vs.weight = weight
+}
+
 // IngestEmbeddings generates embeddings for seed entries and stores them in pgvector.
 // Seed entries get a special "seed=true" context marker for prioritized retrieval.
 func (vs *VectorSeeder) IngestEmbeddings(ctx context.Context, entries []SeedEntry, batchSize int) error {
@@ -49,33 +57,36 @@ func (vs *VectorSeeder) IngestEmbeddings(ctx context.Context, entries []SeedEntr
 
 	log.Info().Int("unique_texts", len(texts)).Msg("Generating seed embeddings")
 
-	// Generate embeddings in batches.
-	embeddings, err := vs.embeddingClient.EmbedBatch(ctx, texts, batchSize)
+	// Generate embeddings in batches, storing each batch as soon as it is ready.
+	stored := 0
+	err := vs.embeddingClient.EmbedEach(ctx, texts, batchSize, func(start int, embeddings [][]float32) error {
+		var records []rag.EmbeddingRecord
+		for k, vec := range embeddings {
+			i := start + k
+			if vec == nil {
+				log.Warn().Str("text", textutil.Truncate(texts[i], 30)).Msg("Missing embedding for seed text")
+				continue
+			}
+			records = append(records, rag.EmbeddingRecord{
+				Hash:     hashes[i],
+				Source:   texts[i],
+				Context:  contextStrs[i],
+				FilePath: "",
+				Vector:   vec,
+				Weight:   vs.weight,
+			})
+		}
+		if err := vs.vectorStore.Store(ctx, records); err != nil {
+			return fmt.Errorf("store seed embeddings: %w", err)
+		}
+		stored += len(records)
+		return nil
+	}, nil)
 	if err != nil {
 		return fmt.Errorf("generate seed embeddings: %w", err)
 	}
 
-	// Build records for vector store.
-	var records []rag.EmbeddingRecord
-	for i, text := range texts {
-		if i >= len(embeddings) || embeddings[i] == nil {
-			log.Warn().Str("text", textutil.Truncate(text, 30)).Msg("Missing embedding for seed text")
-			continue
-		}
-		records = append(records, rag.EmbeddingRecord{
-			Hash:     hashes[i],
-			Source:   text,
-			Context:  contextStrs[i],
-			FilePath: "",
-			Vector:   embeddings[i],
-		})
-	}
-
-	if err := vs.vectorStore.Store(ctx, records); err != nil {
-		return fmt.Errorf("store seed embeddings: %w", err)
-	}
-
-	log.Info().Int("stored", len(records)).Msg("Seed embeddings stored in pgvector")
+	log.Info().Int("stored", stored).Msg("Seed embeddings stored in pgvector")
 	return nil
 }
 
@@ -0,0 +1,27 @@
+package segment
+
+import "strings"
+
+// commonWords are everyday and game-UI words that Segmented mode segments texts
+// over along with the terms, so that a one- or two-character term such as 金 does
+// not match inside an ordinary word such as 金币 or 黄金. Words that are likely
+// glossary terms themselves, such as sect or skill names, are left out.
+var commonWords = strings.Fields(`
+	金币 金钱 金色 金额 黄金 现金 奖金 资金 银两 银子 银币 铜钱 元宝 钻石
+	经验 等级 升级 强化 精炼 合成 分解 镶嵌 打造 修理 耐久
+	任务 主线 支线 日常 活动 副本 挑战 战斗 战场 比赛 排行 排名
+	技能 天赋 属性 状态 效果 冷却 持续 时间 次数 数量 上限 下限
+	装备 武器 防具 饰品 坐骑 宠物 背包 仓库 道具 物品 材料 宝石 宝箱 礼包 奖励
+	获得 获取 领取 消耗 使用 购买 出售 兑换 交易 赠送 商店 商城 价格
+	玩家 角色 队伍 队长 队友 好友 朋友 师父 弟子 徒弟 敌人 对手 目标
+	攻击 攻击力 防御 防御力 生命 生命值 气血 内力 法力 体力 精力 速度
+	暴击 命中 闪避 格挡 伤害 治疗 恢复 增加 减少 提升 降低 提高
+	确认 确定 取消 返回 关闭 打开 开始 结束 继续 退出 进入 离开 前往 到达
+	成功 失败 完成 开启 解锁 选择 设置 系统 提示 公告 邮件 消息 聊天 频道
+	地图 区域 地方 城市 城镇 村庄 山洞 地下 天空 天气 天下 江湖 世界
+	今天 明天 昨天 现在 已经 正在 马上 立即 之后 之前 以后 以前 时候
+	可以 不能 不可 无法 需要 必须 应该 能够 没有 不是 还是 或者 但是 因为 所以 如果 虽然
+	自己 我们 你们 他们 大家 别人 什么 怎么 为什么 这里 那里 这个 那个 哪里
+	知道 觉得 认为 看到 听到 出现 发现 遇到 击败 打败 杀死 死亡 复活
+	一起 一下 一些 一定 一直 一样 一般 不过 东西 事情 问题 方法 办法
+`)
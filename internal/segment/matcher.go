@@ -0,0 +1,90 @@
+package segment
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Mode selects how terms are found in text. Modes trade recall for precision:
+// substring matching is cheapest and finds every occurrence, including terms
+// inside longer terms or words; the others segment each text first and drop
+// such partial matches.
+type Mode string
+
+const (
+	// Substring matches a term wherever it occurs: 青龙 matches in 青龙剑法 even
+	// when 青龙剑 is a term of its own.
+	Substring Mode = "substring"
+	// Longest scans the text left to right and takes the longest term starting at
+	// each position, so a term inside a longer matched term does not match: with
+	// both terms, 青龙剑法 matches 青龙剑 only.
+	Longest Mode = "longest"
+	// Segmented matches a term that aligns with word boundaries, being one word or
+	// several consecutive words of the text segmented over the terms, a built-in
+	// list of common words and an optional word list. The word lists keep terms
+	// from matching inside ordinary words: 金 does not match in 金币.
+	Segmented Mode = "segmented"
+)
+
+// ParseMode parses a term match mode name.
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(s); m {
+	case Substring, Longest, Segmented:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown term match mode %q: expected substring, longest or segmented", s)
+}
+
+// Matcher finds terms in text with one Mode. A nil Matcher matches substrings.
+type Matcher struct {
+	mode Mode
+	seg  Segmenter // nil for Substring
+}
+
+// NewMatcher creates a matcher for mode. Longest and Segmented segment texts over
+// words, which should hold every term matched; Segmented may add further words
+// and always adds the built-in common words.
+func NewMatcher(mode Mode, words []string) *Matcher {
+	m := &Matcher{mode: mode}
+	switch mode {
+	case Longest:
+		m.seg = NewDictSegmenter(words)
+	case Segmented:
+		m.seg = NewDictSegmenter(append(slices.Clip(words), commonWords...))
+	}
+	return m
+}
+
+// Mode returns the matching mode.
+func (m *Matcher) Mode() Mode {
+	if m == nil {
+		return Substring
+	}
+	return m.mode
+}
+
+// ContainsTerm reports whether term occurs in text under the matcher's mode.
+func (m *Matcher) ContainsTerm(text, term string) bool {
+	switch m.Mode() {
+	case Longest:
+		return containsToken(m.seg, text, term)
+	case Segmented:
+		return ContainsTerm(m.seg, text, term)
+	default:
+		return term != "" && strings.Contains(text, term)
+	}
+}
+
+// containsToken reports whether term is one whole token of text.
+func containsToken(seg Segmenter, text, term string) bool {
+	if term == "" || !strings.Contains(text, term) {
+		return false
+	}
+	for _, token := range seg.Segment(text) {
+		if token == term {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package segment
+
+import "testing"
+
+func TestMatcherContainsTerm(t *testing.T) {
+	terms := []string{"金", "青龙", "青龙剑", "华山"}
+	tests := []struct {
+		name string
+		text string
+		term string
+		want map[Mode]bool
+	}{
+		{"whole word", "获得金", "金", map[Mode]bool{Substring: true, Longest: true, Segmented: true}},
+		{"inside a common word", "获得金币", "金", map[Mode]bool{Substring: true, Longest: true, Segmented: false}},
+		{"inside another common word", "黄金宝箱", "金", map[Mode]bool{Substring: true, Longest: true, Segmented: false}},
+		{"inside a longer term", "青龙剑法", "青龙", map[Mode]bool{Substring: true, Longest: false, Segmented: false}},
+		{"longer term", "青龙剑法", "青龙剑", map[Mode]bool{Substring: true, Longest: true, Segmented: true}},
+		{"between common words", "前往华山击败敌人", "华山", map[Mode]bool{Substring: true, Longest: true, Segmented: true}},
+		{"absent", "获得银两", "金", map[Mode]bool{Substring: false, Longest: false, Segmented: false}},
+	}
+	for _, mode := range []Mode{Substring, Longest, Segmented} {
+		m := NewMatcher(mode, terms)
+		for _, tt := range tests {
+			t.Run(string(mode)+"/"+tt.name, func(t *testing.T) {
+				if got := m.ContainsTerm(tt.text, tt.term); got != tt.want[mode] {
+					t.Errorf("ContainsTerm(%q, %q) = %v, want %v", tt.text, tt.term, got, tt.want[mode])
+				}
+			})
+		}
+	}
+}
+
+func TestMatcherExtraWords(t *testing.T) {
+	m := NewMatcher(Segmented, []string{"龙", "恐龙"})
+	if m.ContainsTerm("远古恐龙", "龙") {
+		t.Error("term matched inside a word from the word list")
+	}
+	if !NewMatcher(Segmented, []string{"龙"}).ContainsTerm("远古恐龙", "龙") {
+		t.Error("term not matched where no word covers it")
+	}
+}
+
+func TestNilMatcher(t *testing.T) {
+	var m *Matcher
+	if m.Mode() != Substring || !m.ContainsTerm("获得金币", "金") {
+		t.Error("nil Matcher does not match substrings")
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for _, s := range []string{"substring", "longest", "segmented"} {
+		if m, err := ParseMode(s); err != nil || string(m) != s {
+			t.Errorf("ParseMode(%q) = %q, %v", s, m, err)
+		}
+	}
+	if _, err := ParseMode("fuzzy"); err == nil {
+		t.Error("ParseMode accepted an unknown mode")
+	}
+}
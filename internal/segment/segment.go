@@ -0,0 +1,81 @@
+package segment
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Segmenter splits text into word tokens so term matching can respect word boundaries.
+type Segmenter interface {
+	// Segment returns the tokens of text in order. Concatenating them yields text.
+	Segment(text string) []string
+}
+
+// DictSegmenter is a forward maximum-matching segmenter driven by a word list.
+// Runs of characters not covered by the dictionary are emitted one rune at a time.
+type DictSegmenter struct {
+	words  map[string]struct{}
+	maxLen int // longest dictionary word, in runes
+}
+
+// NewDictSegmenter creates a segmenter from the given dictionary words.
+func NewDictSegmenter(words []string) *DictSegmenter {
+	ds := &DictSegmenter{words: make(map[string]struct{}, len(words))}
+	for _, w := range words {
+		if w == "" {
+			continue
+		}
+		ds.words[w] = struct{}{}
+		if n := utf8.RuneCountInString(w); n > ds.maxLen {
+			ds.maxLen = n
+		}
+	}
+	return ds
+}
+
+// Segment splits text using forward maximum matching against the dictionary.
+func (ds *DictSegmenter) Segment(text string) []string {
+	runes := []rune(text)
+	var tokens []string
+
+	for i := 0; i < len(runes); {
+		end := i + 1
+		for n := min(ds.maxLen, len(runes)-i); n > 1; n-- {
+			if _, ok := ds.words[string(runes[i:i+n])]; ok {
+				end = i + n
+				break
+			}
+		}
+		tokens = append(tokens, string(runes[i:end]))
+		i = end
+	}
+
+	return tokens
+}
+
+// ContainsTerm reports whether term occurs in text. With a nil segmenter this is a
+// plain substring check; otherwise the term must align with token boundaries, i.e.
+// equal the concatenation of one or more consecutive tokens.
+func ContainsTerm(seg Segmenter, text, term string) bool {
+	if term == "" || !strings.Contains(text, term) {
+		return false
+	}
+	if seg == nil {
+		return true
+	}
+
+	tokens := seg.Segment(text)
+	for i := range tokens {
+		if !strings.HasPrefix(term, tokens[i]) {
+			continue
+		}
+		joined := ""
+		for j := i; j < len(tokens) && len(joined) < len(term); j++ {
+			joined += tokens[j]
+		}
+		if joined == term {
+			return true
+		}
+	}
+	return false
+}
@@ -3,25 +3,238 @@ package textutil
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"unicode"
+
+	"golang.org/x/text/unicode/norm"
 )
 
-// ContainsChinese checks if a string contains Chinese characters.
-func ContainsChinese(s string) bool {
+// ScriptDetector reports whether text contains characters from a set of Unicode scripts.
+type ScriptDetector struct {
+	tables []*unicode.RangeTable
+}
+
+// NewScriptDetector creates a detector for the given Unicode script names
+// (as in unicode.Scripts, e.g. "Han", "Hiragana", "Katakana", "Thai").
+func NewScriptDetector(names ...string) (*ScriptDetector, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no scripts given")
+	}
+	tables := make([]*unicode.RangeTable, 0, len(names))
+	for _, name := range names {
+		t, ok := unicode.Scripts[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown Unicode script %q", name)
+		}
+		tables = append(tables, t)
+	}
+	return &ScriptDetector{tables: tables}, nil
+}
+
+// Contains checks if s contains at least one character of the detector's scripts.
+func (d *ScriptDetector) Contains(s string) bool {
 	for _, r := range s {
-		if unicode.Is(unicode.Han, r) {
+		if unicode.In(r, d.tables...) {
 			return true
 		}
 	}
 	return false
 }
 
-// Hash computes a SHA-256 hex hash of a string for deduplication.
+// ContainsScript checks if s contains characters of the named Unicode script.
+// Unknown script names never match.
+func ContainsScript(s, scriptName string) bool {
+	t, ok := unicode.Scripts[scriptName]
+	if !ok {
+		return false
+	}
+	for _, r := range s {
+		if unicode.Is(t, r) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	sourceMu       sync.RWMutex
+	sourceDetector = &ScriptDetector{tables: []*unicode.RangeTable{unicode.Han}}
+)
+
+// SetSourceScripts configures which Unicode scripts ContainsSource detects,
+// by script name. The default is Han.
+func SetSourceScripts(names ...string) error {
+	d, err := NewScriptDetector(names...)
+	if err != nil {
+		return err
+	}
+
+	sourceMu.Lock()
+	sourceDetector = d
+	sourceMu.Unlock()
+	return nil
+}
+
+// ContainsSource checks if a string contains characters of the configured source language.
+func ContainsSource(s string) bool {
+	sourceMu.RLock()
+	d := sourceDetector
+	sourceMu.RUnlock()
+	return d.Contains(s)
+}
+
+// ContainsChinese checks if a string contains Chinese characters.
+func ContainsChinese(s string) bool {
+	return ContainsScript(s, "Han")
+}
+
+// Hash computes a SHA-256 hex hash of the exact bytes of a string. Use
+// CanonicalHash for cache and deduplication keys.
 func Hash(s string) string {
 	h := sha256.Sum256([]byte(s))
 	return hex.EncodeToString(h[:])
 }
 
+// foldWidth makes Canonical fold full-width ASCII forms to their ASCII equivalents.
+var foldWidth atomic.Bool
+
+// SetFoldWidth configures whether Canonical folds full-width ASCII ("ＨＰ１０！")
+// and the ideographic space to ASCII. Off by default: folding changes the hash
+// of most texts with full-width punctuation, so existing keys stop matching.
+func SetFoldWidth(fold bool) {
+	foldWidth.Store(fold)
+}
+
+// Canonical returns s in Unicode normalization form NFC, with full-width ASCII
+// folded when SetFoldWidth is on. Visually identical strings that differ only in
+// these forms have the same canonical form.
+func Canonical(s string) string {
+	s = norm.NFC.String(s)
+	if !foldWidth.Load() {
+		return s
+	}
+	return strings.Map(foldFullWidth, s)
+}
+
+// foldFullWidth maps a full-width ASCII form or the ideographic space to ASCII.
+func foldFullWidth(r rune) rune {
+	switch {
+	case r >= '\uFF01' && r <= '\uFF5E':
+		return r - 0xFEE0
+	case r == '\u3000':
+		return ' '
+	}
+	return r
+}
+
+// CanonicalHash hashes the canonical form of s, so NFC/NFD variants (and, when
+// folding is on, full-width/half-width variants) share one key. A string already
+// in canonical form hashes the same as with Hash.
+func CanonicalHash(s string) string {
+	return Hash(Canonical(s))
+}
+
+// VariantKey returns the form shared by texts that differ only in padding, in
+// no-break versus ordinary spaces, or in full-width versus half-width ASCII
+// ("攻击力：10 " and "攻击力:10"), whatever SetFoldWidth says. Such variants can
+// share one translation, repadded to each.
+func VariantKey(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r == '\u00A0' || r == '\u202F' {
+			return ' '
+		}
+		return foldFullWidth(r)
+	}, norm.NFC.String(s))
+	return strings.TrimSpace(s)
+}
+
+// WidthForms maps ASCII punctuation to the full-width form a text writes it in,
+// or a full-width form to its ASCII punctuation, see VariantForms.
+type WidthForms map[rune]rune
+
+// Apply rewrites the characters of s that forms maps.
+func (f WidthForms) Apply(s string) string {
+	if len(f) == 0 {
+		return s
+	}
+	return strings.Map(func(r rune) rune {
+		if to, ok := f[r]; ok {
+			return to
+		}
+		return r
+	}, s)
+}
+
+// VariantForms compares a variant with the text rep it shares a VariantKey with
+// and returns, keyed by rep's form, the other width the variant writes punctuation
+// in: for rep "攻击力:10" and variant "攻击力：10" it maps ':' to '：'. Applied to
+// the translation of rep, the forms give the variant's punctuation. It reports
+// false when no such mapping exists: the texts differ in the width of letters or
+// digits, which a translation does not carry over, or a character of rep becomes
+// both widths in the variant.
+func VariantForms(rep, variant string) (WidthForms, bool) {
+	a := []rune(strings.TrimSpace(norm.NFC.String(rep)))
+	b := []rune(strings.TrimSpace(norm.NFC.String(variant)))
+	if len(a) != len(b) {
+		return nil, false
+	}
+	forms := make(WidthForms)
+	kept := make(map[rune]bool)
+	for i, r := range a {
+		switch {
+		case b[i] == r:
+			kept[r] = true
+			continue
+		case unicode.IsSpace(r):
+			continue // spaces inside the text stay as the translation writes them
+		case foldFullWidth(r) != foldFullWidth(b[i]):
+			return nil, false
+		}
+		if folded := foldFullWidth(r); !unicode.IsPunct(folded) && !unicode.IsSymbol(folded) {
+			return nil, false
+		}
+		if to, ok := forms[r]; ok && to != b[i] {
+			return nil, false
+		}
+		forms[r] = b[i]
+	}
+	for r := range forms {
+		if kept[r] {
+			return nil, false
+		}
+	}
+	return forms, true
+}
+
+// Normalize trims leading and trailing whitespace and punctuation, so strings that
+// differ only in surrounding punctuation ("获得经验" and "获得经验！") compare equal.
+func Normalize(s string) string {
+	return strings.TrimFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	})
+}
+
+// SplitPadding splits s into its leading whitespace, its core and its trailing
+// whitespace. UI strings sometimes carry padding for alignment that the model
+// would drop, so only the core is sent for translation.
+func SplitPadding(s string) (lead, core, trail string) {
+	core = strings.TrimLeftFunc(s, unicode.IsSpace)
+	lead = s[:len(s)-len(core)]
+	trimmed := strings.TrimRightFunc(core, unicode.IsSpace)
+	trail = core[len(trimmed):]
+	return lead, trimmed, trail
+}
+
+// Repad gives translated the leading and trailing whitespace of source in place
+// of its own.
+func Repad(source, translated string) string {
+	lead, _, trail := SplitPadding(source)
+	return lead + strings.TrimSpace(translated) + trail
+}
+
 // Truncate shortens a string to maxLen, appending "..." if truncated.
 func Truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
@@ -0,0 +1,137 @@
+package textutil
+
+import "testing"
+
+func TestCanonicalHash(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		fold bool
+		same bool
+	}{
+		{"NFC and NFD", "Caf\u00e9", "Cafe\u0301", false, true},
+		{"NFC and NFD with folding", "Caf\u00e9", "Cafe\u0301", true, true},
+		{"Hangul NFC and NFD", "\ud55c", "\u1112\u1161\u11ab", false, true},
+		{"full-width punctuation without folding", "攻击力：10", "攻击力:10", false, false},
+		{"full-width punctuation with folding", "攻击力：10", "攻击力:10", true, true},
+		{"full-width letters and digits with folding", "ＨＰ１０！", "HP10!", true, true},
+		{"ideographic space with folding", "确认\u3000取消", "确认 取消", true, true},
+		{"different texts", "金币", "宝石", true, false},
+		{"CJK punctuation is not folded", "你好。", "你好.", true, false},
+	}
+	defer SetFoldWidth(false)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetFoldWidth(tt.fold)
+			if got := CanonicalHash(tt.a) == CanonicalHash(tt.b); got != tt.same {
+				t.Errorf("CanonicalHash(%q) == CanonicalHash(%q) is %v, want %v", tt.a, tt.b, got, tt.same)
+			}
+			if tt.a != tt.b && Hash(tt.a) == Hash(tt.b) {
+				t.Errorf("Hash(%q) == Hash(%q), want raw hashes to differ", tt.a, tt.b)
+			}
+		})
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		in   string
+		fold bool
+		want string
+	}{
+		{"Cafe\u0301", false, "Caf\u00e9"},
+		{"ＨＰ：１０", false, "ＨＰ：１０"},
+		{"ＨＰ：１０", true, "HP:10"},
+		{"确认\u3000", true, "确认 "},
+		{"plain text", true, "plain text"},
+	}
+	defer SetFoldWidth(false)
+	for _, tt := range tests {
+		SetFoldWidth(tt.fold)
+		if got := Canonical(tt.in); got != tt.want {
+			t.Errorf("Canonical(%q) with fold %v = %q, want %q", tt.in, tt.fold, got, tt.want)
+		}
+	}
+}
+
+func TestCanonicalHashOfCanonicalText(t *testing.T) {
+	for _, s := range []string{"", "金币", "Caf\u00e9", "HP: 10"} {
+		if CanonicalHash(s) != Hash(s) {
+			t.Errorf("CanonicalHash(%q) != Hash(%q) for text already in canonical form", s, s)
+		}
+	}
+}
+
+func TestVariantKey(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"攻击力：10 ", "攻击力:10", true},
+		{"  确认  ", "确认", true},
+		{"确认\u00a0取消", "确认 取消", true},
+		{"确认", "取消", false},
+	}
+	for _, tt := range tests {
+		if got := VariantKey(tt.a) == VariantKey(tt.b); got != tt.same {
+			t.Errorf("VariantKey(%q) == VariantKey(%q) is %v, want %v", tt.a, tt.b, got, tt.same)
+		}
+	}
+}
+
+func TestRepad(t *testing.T) {
+	tests := []struct {
+		source     string
+		translated string
+		want       string
+	}{
+		{"  确认  ", "Confirm", "  Confirm  "},
+		{"确认", "  Confirm\n", "Confirm"},
+		{"\t确认", "Confirm", "\tConfirm"},
+		{"确认\u3000", "Confirm", "Confirm\u3000"},
+		{"  ", "", "  "},
+	}
+	for _, tt := range tests {
+		if got := Repad(tt.source, tt.translated); got != tt.want {
+			t.Errorf("Repad(%q, %q) = %q, want %q", tt.source, tt.translated, got, tt.want)
+		}
+		lead, core, trail := SplitPadding(tt.source)
+		if lead+core+trail != tt.source {
+			t.Errorf("SplitPadding(%q) = %q, %q, %q, which do not rejoin", tt.source, lead, core, trail)
+		}
+	}
+}
+
+func TestVariantForms(t *testing.T) {
+	tests := []struct {
+		name       string
+		rep        string
+		variant    string
+		translated string
+		want       string
+		ok         bool
+	}{
+		{"padding only", "确认", "  确认 ", "OK", "OK", true},
+		{"full-width colon", "攻击力:10", "攻击力：10", "Tấn công: 10", "Tấn công： 10", true},
+		{"half-width colon", "攻击力：10", "攻击力:10 ", "Tấn công：10", "Tấn công:10", true},
+		{"several marks", "确定吗?(是/否)", "确定吗？（是/否）", "Chắc chưa? (Có/Không)", "Chắc chưa？ （Có/Không）", true},
+		{"no-break space", "攻击 10", "攻击\u00a010", "Tấn công 10", "Tấn công 10", true},
+		{"full-width digits", "HP10", "HP１０", "", "", false},
+		{"full-width letters", "HP:10", "ＨＰ:10", "", "", false},
+		{"mixed widths of one mark", "甲:乙:丙", "甲：乙:丙", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if VariantKey(tt.rep) != VariantKey(tt.variant) {
+				t.Fatalf("%q and %q are not variants", tt.rep, tt.variant)
+			}
+			forms, ok := VariantForms(tt.rep, tt.variant)
+			if ok != tt.ok {
+				t.Fatalf("VariantForms(%q, %q) ok = %v, want %v", tt.rep, tt.variant, ok, tt.ok)
+			}
+			if got := forms.Apply(tt.translated); ok && got != tt.want {
+				t.Errorf("Apply(%q) = %q, want %q", tt.translated, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package translation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"rag-translator/internal/textutil"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// batchIDKey is the context key of WithBatchID.
+type batchIDKey struct{}
+
+// NewBatchID returns the ID of batch number n holding texts: the number and a
+// short hash of the texts, so that the same batch has the same ID in every run.
+func NewBatchID(n int, texts []string) string {
+	return fmt.Sprintf("%04d-%s", n, textutil.Hash(strings.Join(texts, "\x00"))[:8])
+}
+
+// WithBatchID returns ctx carrying batch ID id. OpusClient logs it as batch_id
+// with every message about a request made with the returned context.
+func WithBatchID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, batchIDKey{}, id)
+}
+
+// BatchID returns the batch ID carried by ctx, or "" when there is none.
+func BatchID(ctx context.Context) string {
+	id, _ := ctx.Value(batchIDKey{}).(string)
+	return id
+}
+
+// Logger returns the global logger, with the batch ID of ctx as context.
+func Logger(ctx context.Context) *zerolog.Logger {
+	l := log.Logger
+	if id := BatchID(ctx); id != "" {
+		l = l.With().Str("batch_id", id).Logger()
+	}
+	return &l
+}
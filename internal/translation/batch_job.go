@@ -0,0 +1,212 @@
+package translation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"rag-translator/internal/apierror"
+
+	"github.com/rs/zerolog/log"
+)
+
+// MaxBatchJobBytes bounds the encoded requests of one batch job, below the API's
+// limit on inlined requests. Callers split larger workloads into several jobs.
+const MaxBatchJobBytes = 16 << 20
+
+// BatchRequest is one prompt of an asynchronous batch job.
+type BatchRequest struct {
+	// Key identifies the request's result and must be unique within the job.
+	Key          string
+	SystemPrompt string
+	UserPrompt   string
+}
+
+// BatchResult is the outcome of one BatchRequest.
+type BatchResult struct {
+	Text string
+	// Truncated reports that the response was cut off at the output token limit.
+	Truncated bool
+	// Err is set when the request failed within an otherwise finished job.
+	Err error
+}
+
+// --- Gemini Batch API request/response types ---
+
+type geminiBatchRequest struct {
+	Batch geminiBatch `json:"batch"`
+}
+
+type geminiBatch struct {
+	DisplayName string           `json:"displayName"`
+	InputConfig geminiBatchInput `json:"inputConfig"`
+}
+
+type geminiBatchInput struct {
+	Requests geminiInlinedRequests `json:"requests"`
+}
+
+type geminiInlinedRequests struct {
+	Requests []geminiInlinedRequest `json:"requests"`
+}
+
+type geminiInlinedRequest struct {
+	Request  geminiRequest     `json:"request"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// geminiOperation is the long-running operation of a batch job.
+type geminiOperation struct {
+	Name     string `json:"name"`
+	Done     bool   `json:"done"`
+	Metadata struct {
+		State string `json:"state"`
+	} `json:"metadata"`
+	Response *struct {
+		InlinedResponses struct {
+			InlinedResponses []geminiInlinedResponse `json:"inlinedResponses"`
+		} `json:"inlinedResponses"`
+	} `json:"response,omitempty"`
+	Error *geminiError `json:"error,omitempty"`
+}
+
+type geminiInlinedResponse struct {
+	Response *geminiResponse   `json:"response,omitempty"`
+	Error    *geminiError      `json:"error,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// SubmitBatch submits requests as one asynchronous batch job and returns the job
+// name to pass to PollBatch. Their encoded size should stay within
+// MaxBatchJobBytes.
+func (oc *OpusClient) SubmitBatch(ctx context.Context, displayName string, requests []BatchRequest) (string, error) {
+	body := geminiBatchRequest{Batch: geminiBatch{DisplayName: displayName}}
+	for _, r := range requests {
+		body.Batch.InputConfig.Requests.Requests = append(body.Batch.InputConfig.Requests.Requests, geminiInlinedRequest{
+			Request:  newGeminiRequest(r.SystemPrompt, r.UserPrompt),
+			Metadata: map[string]string{"key": r.Key},
+		})
+	}
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return "", fmt.Errorf("marshal batch job: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/%s:batchGenerateContent?key=%s", geminiBaseURL, oc.model, oc.apiKey)
+	var op geminiOperation
+	if err := oc.callJSON(ctx, http.MethodPost, url, bodyBytes, &op); err != nil {
+		return "", fmt.Errorf("submit batch job: %w", err)
+	}
+	if op.Name == "" {
+		return "", fmt.Errorf("submit batch job: response names no job")
+	}
+
+	log.Info().Str("job", op.Name).Int("requests", len(requests)).Int("bytes", len(bodyBytes)).Msg("Submitted batch job")
+	return op.Name, nil
+}
+
+// PollBatch checks the batch job every interval until it finishes and returns
+// its results by request key. A job that fails, expires or is cancelled as a
+// whole is an error; a failed request within a finished job is reported in its
+// result, and a request the job returned nothing for has no result. Transient
+// errors while polling are logged and the next check proceeds.
+func (oc *OpusClient) PollBatch(ctx context.Context, name string, interval time.Duration) (map[string]BatchResult, error) {
+	url := fmt.Sprintf("%s/%s?key=%s", geminiAPIURL, name, oc.apiKey)
+	for {
+		var op geminiOperation
+		err := oc.callJSON(ctx, http.MethodGet, url, nil, &op)
+		switch {
+		case ctx.Err() != nil:
+			return nil, ctx.Err()
+		case err != nil && !apierror.Retryable(err):
+			return nil, fmt.Errorf("poll batch job %s: %w", name, err)
+		case err != nil:
+			log.Warn().Err(err).Str("job", name).Msg("Polling batch job failed, retrying")
+		case op.Done:
+			return batchResults(ctx, name, &op)
+		default:
+			log.Info().Str("job", name).Str("state", op.Metadata.State).Dur("next_check", interval).Msg("Batch job running")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// batchResults extracts the per-request results of a finished job.
+func batchResults(ctx context.Context, name string, op *geminiOperation) (map[string]BatchResult, error) {
+	if op.Error != nil {
+		return nil, fmt.Errorf("batch job %s: %w", name, op.Error.err())
+	}
+	if !strings.HasSuffix(op.Metadata.State, "_SUCCEEDED") || op.Response == nil {
+		return nil, fmt.Errorf("batch job %s ended in state %s", name, op.Metadata.State)
+	}
+
+	results := make(map[string]BatchResult)
+	failed := 0
+	for i, r := range op.Response.InlinedResponses.InlinedResponses {
+		key := r.Metadata["key"]
+		if key == "" {
+			log.Warn().Str("job", name).Int("index", i).Msg("Batch job response without request key, ignoring it")
+			continue
+		}
+		var result BatchResult
+		switch {
+		case r.Error != nil:
+			result.Err = r.Error.err()
+		case r.Response == nil:
+			result.Err = fmt.Errorf("empty response")
+		default:
+			text, finishReason, err := answer(ctx, r.Response)
+			result = BatchResult{Text: text, Truncated: finishReason == finishMaxTokens, Err: err}
+		}
+		if result.Err != nil {
+			failed++
+		}
+		results[key] = result
+	}
+
+	log.Info().Str("job", name).Int("results", len(results)).Int("failed", failed).Msg("Batch job finished")
+	return results, nil
+}
+
+// callJSON sends one API request and decodes the JSON response into out.
+func (oc *OpusClient) callJSON(ctx context.Context, method, url string, body []byte, out any) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := oc.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("API call: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return apierror.New(resp.StatusCode, string(respBody))
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("unmarshal response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package translation
+
+import "sync"
+
+// BatchSizer adapts the number of texts per batch prompt during a run. A clean
+// response grows the size by one; a truncated or misaligned response halves it,
+// so a run settles just below the size the content and model can handle.
+type BatchSizer struct {
+	mu       sync.Mutex
+	size     int
+	min, max int
+}
+
+// NewBatchSizer creates a sizer starting at size and kept within [lo, hi]. When
+// hi is 0 the size never changes. Sizes below 1 are raised to 1, since an empty
+// batch translates nothing.
+func NewBatchSizer(size, lo, hi int) *BatchSizer {
+	size, lo = max(size, 1), max(lo, 1)
+	if hi == 0 {
+		lo, hi = size, size
+	}
+	hi = max(hi, lo)
+	size = min(max(size, lo), hi)
+	return &BatchSizer{size: size, min: lo, max: hi}
+}
+
+// Size returns the size for the next batch.
+func (bs *BatchSizer) Size() int {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	return bs.size
+}
+
+// RecordClean notes a response with exactly one segment per text.
+func (bs *BatchSizer) RecordClean() {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.size = min(bs.size+1, bs.max)
+}
+
+// RecordMisaligned notes a response that was cut off or held the wrong number of
+// segments.
+func (bs *BatchSizer) RecordMisaligned() {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.size = max(bs.size/2, bs.min)
+}
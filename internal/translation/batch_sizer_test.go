@@ -0,0 +1,71 @@
+package translation
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNewBatchSizerClamps(t *testing.T) {
+	tests := []struct {
+		name          string
+		size, lo, hi  int
+		want, afterUp int
+	}{
+		{"fixed", 10, 1, 0, 10, 10},
+		{"fixed zero", 0, 1, 0, 1, 1},
+		{"fixed negative", -5, 1, 0, 1, 1},
+		{"tuned", 4, 2, 8, 4, 5},
+		{"tuned zero bounds", 0, 0, 8, 1, 2},
+		{"size above max", 20, 1, 8, 8, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bs := NewBatchSizer(tt.size, tt.lo, tt.hi)
+			if got := bs.Size(); got != tt.want {
+				t.Errorf("Size = %d, want %d", got, tt.want)
+			}
+			bs.RecordClean()
+			if got := bs.Size(); got != tt.afterUp {
+				t.Errorf("Size after RecordClean = %d, want %d", got, tt.afterUp)
+			}
+			for range 5 {
+				bs.RecordMisaligned()
+			}
+			if got := bs.Size(); got < 1 {
+				t.Errorf("Size after RecordMisaligned = %d, want at least 1", got)
+			}
+		})
+	}
+}
+
+func TestBatchSizerAdapts(t *testing.T) {
+	tests := []struct {
+		name         string
+		size, lo, hi int
+		events       string // c for a clean response, m for a misaligned one
+		want         []int  // size after each event
+	}{
+		{"alternating", 8, 2, 16, "cmcmcm", []int{9, 4, 5, 2, 3, 2}},
+		{"held at min", 8, 2, 16, "mmmc", []int{4, 2, 2, 3}},
+		{"held at max", 15, 1, 16, "ccmc", []int{16, 16, 8, 9}},
+		{"recovers after a run of failures", 6, 1, 8, "mmcccc", []int{3, 1, 2, 3, 4, 5}},
+		{"fixed size ignores responses", 10, 1, 0, "mcmc", []int{10, 10, 10, 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bs := NewBatchSizer(tt.size, tt.lo, tt.hi)
+			var got []int
+			for _, e := range tt.events {
+				if e == 'c' {
+					bs.RecordClean()
+				} else {
+					bs.RecordMisaligned()
+				}
+				got = append(got, bs.Size())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("sizes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package translation
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"rag-translator/internal/apierror"
+)
+
+// CircuitBreaker trips after a run of consecutive translation failures, so a
+// systemic problem such as an invalid key or exhausted quota stops the run early
+// instead of failing every remaining batch. An auth failure trips it at once,
+// since every later request would fail the same way. A blocked or invalid request
+// is a problem of its texts, not of the run, and is not counted.
+type CircuitBreaker struct {
+	mu          sync.Mutex
+	threshold   int // 0 disables the breaker
+	consecutive int
+	lastErr     error
+	fatalErr    error // first auth failure; trips the breaker for good
+}
+
+// NewCircuitBreaker creates a breaker that trips after threshold consecutive failures.
+// A threshold of 0 never trips.
+func NewCircuitBreaker(threshold int) *CircuitBreaker {
+	return &CircuitBreaker{threshold: threshold}
+}
+
+// RecordSuccess resets the consecutive failure count.
+func (cb *CircuitBreaker) RecordSuccess() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.consecutive = 0
+	cb.lastErr = nil
+}
+
+// RecordFailure counts one more consecutive failure, unless err is specific to the
+// texts sent: a blocked or invalid request neither counts nor resets the run.
+func (cb *CircuitBreaker) RecordFailure(err error) {
+	if errors.Is(err, apierror.ErrBlocked) || errors.Is(err, apierror.ErrInvalidRequest) {
+		return
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.consecutive++
+	cb.lastErr = err
+	if cb.fatalErr == nil && errors.Is(err, apierror.ErrAuth) {
+		cb.fatalErr = err
+	}
+}
+
+// Err returns a non-nil error once the breaker has tripped.
+func (cb *CircuitBreaker) Err() error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.threshold == 0 {
+		return nil
+	}
+	if cb.fatalErr != nil {
+		return fmt.Errorf("circuit breaker tripped on an authentication failure: %w", cb.fatalErr)
+	}
+	if cb.consecutive < cb.threshold {
+		return nil
+	}
+	return fmt.Errorf("circuit breaker tripped after %d consecutive translation failures: %w", cb.consecutive, cb.lastErr)
+}
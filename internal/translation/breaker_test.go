@@ -0,0 +1,48 @@
+package translation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"rag-translator/internal/apierror"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	server := apierror.New(500, "internal")
+	tests := []struct {
+		name     string
+		failures []error
+		tripped  bool
+	}{
+		{"below threshold", []error{server, server}, false},
+		{"at threshold", []error{server, server, server}, true},
+		{"timeouts", []error{context.DeadlineExceeded, context.DeadlineExceeded, context.DeadlineExceeded}, true},
+		{"auth failure trips at once", []error{apierror.New(401, "unauthorized")}, true},
+		{"invalid key trips at once", []error{apierror.New(400, "API_KEY_INVALID")}, true},
+		{"blocked not counted", []error{server, apierror.Blocked("SAFETY"), server}, false},
+		{"invalid request not counted", []error{apierror.New(400, "bad"), apierror.New(400, "bad"), apierror.New(400, "bad")}, false},
+		{"wrapped blocked not counted", []error{server, server, errors.Join(errors.New("batch"), apierror.Blocked("SAFETY"))}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb := NewCircuitBreaker(3)
+			for _, err := range tt.failures {
+				cb.RecordFailure(err)
+			}
+			if got := cb.Err() != nil; got != tt.tripped {
+				t.Errorf("tripped = %v, want %v (err %v)", got, tt.tripped, cb.Err())
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerSuccessResets(t *testing.T) {
+	cb := NewCircuitBreaker(2)
+	cb.RecordFailure(apierror.New(503, "unavailable"))
+	cb.RecordSuccess()
+	cb.RecordFailure(apierror.New(503, "unavailable"))
+	if err := cb.Err(); err != nil {
+		t.Errorf("Err() = %v after a success between failures", err)
+	}
+}
@@ -0,0 +1,168 @@
+package translation
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// NameIssue reports a proper noun rendered inconsistently across cached translations.
+type NameIssue struct {
+	// Name is the source-language proper noun.
+	Name string
+	// Renderings maps each known rendering found in use to the sources of the
+	// cached texts that use it.
+	Renderings map[string][]string
+	// Unmatched lists sources that contain Name but whose translation uses none of
+	// its known renderings.
+	Unmatched []string
+}
+
+// Preferred returns the rendering used by the most texts, preferring the longer
+// and then the lexicographically smaller one on ties.
+func (i NameIssue) Preferred() string {
+	renderings := make([]string, 0, len(i.Renderings))
+	for r := range i.Renderings {
+		renderings = append(renderings, r)
+	}
+	sort.Slice(renderings, func(a, b int) bool {
+		ra, rb := renderings[a], renderings[b]
+		if na, nb := len(i.Renderings[ra]), len(i.Renderings[rb]); na != nb {
+			return na > nb
+		}
+		if la, lb := utf8.RuneCountInString(ra), utf8.RuneCountInString(rb); la != lb {
+			return la > lb
+		}
+		return ra < rb
+	})
+	if len(renderings) == 0 {
+		return ""
+	}
+	return renderings[0]
+}
+
+// LoadNameList reads a proper noun list with one name per line, optionally
+// followed by tab-separated known renderings. Blank lines and lines starting with
+// # are ignored.
+func LoadNameList(path string) (map[string][]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open name list: %w", err)
+	}
+	defer file.Close()
+
+	names := make(map[string][]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		name := strings.TrimSpace(fields[0])
+		for _, r := range fields[1:] {
+			if r = strings.TrimSpace(r); r != "" {
+				names[name] = append(names[name], r)
+			}
+		}
+		if _, ok := names[name]; !ok {
+			names[name] = nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan name list: %w", err)
+	}
+	return names, nil
+}
+
+// CheckNameConsistency finds proper nouns rendered more than one way in cached, a
+// source → translation map. The known renderings of a name are those given in
+// names plus the cached translation of the name on its own. A text uses the
+// longest known rendering its translation contains.
+func CheckNameConsistency(names map[string][]string, cached map[string]string) []NameIssue {
+	sources := make([]string, 0, len(cached))
+	for src := range cached {
+		sources = append(sources, src)
+	}
+	sort.Strings(sources)
+
+	nameKeys := make([]string, 0, len(names))
+	for name := range names {
+		nameKeys = append(nameKeys, name)
+	}
+	sort.Strings(nameKeys)
+
+	var issues []NameIssue
+	for _, name := range nameKeys {
+		renderings := knownRenderings(names[name], cached[name])
+		if len(renderings) == 0 {
+			continue
+		}
+
+		issue := NameIssue{Name: name, Renderings: make(map[string][]string)}
+		for _, src := range sources {
+			if !strings.Contains(src, name) {
+				continue
+			}
+			matched := false
+			for _, r := range renderings {
+				if strings.Contains(cached[src], r) {
+					issue.Renderings[r] = append(issue.Renderings[r], src)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				issue.Unmatched = append(issue.Unmatched, src)
+			}
+		}
+
+		if len(issue.Renderings) > 1 || len(issue.Unmatched) > 0 {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+// knownRenderings returns the distinct non-empty renderings, longest first.
+func knownRenderings(listed []string, cachedAlone string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	candidates := append([]string{cachedAlone}, listed...)
+	for _, r := range candidates {
+		if r != "" && !seen[r] {
+			seen[r] = true
+			out = append(out, r)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return utf8.RuneCountInString(out[i]) > utf8.RuneCountInString(out[j])
+	})
+	return out
+}
+
+// NormalizeNames rewrites every cached translation that uses a non-preferred
+// rendering of a name to use the preferred one, and returns the changed entries.
+// Unmatched texts cannot be fixed automatically and are left alone.
+func NormalizeNames(issues []NameIssue, cached map[string]string) map[string]string {
+	fixed := make(map[string]string)
+	for _, issue := range issues {
+		preferred := issue.Preferred()
+		for r, srcs := range issue.Renderings {
+			if r == preferred {
+				continue
+			}
+			for _, src := range srcs {
+				current, ok := fixed[src]
+				if !ok {
+					current = cached[src]
+				}
+				fixed[src] = strings.ReplaceAll(current, r, preferred)
+			}
+		}
+	}
+	return fixed
+}
@@ -0,0 +1,49 @@
+package translation
+
+import (
+	"math"
+	"unicode"
+)
+
+// Heuristic token ratios for Gemini-family tokenizers. CJK ideographs are close to
+// one token each, while Latin-script text averages about four bytes per token.
+const (
+	bytesPerLatinToken = 4.0
+	// outputTokensPerHan approximates the Vietnamese tokens produced per Chinese character.
+	outputTokensPerHan = 1.6
+)
+
+// EstimateTokens approximates the number of tokens a text consumes as model input.
+func EstimateTokens(s string) int {
+	han := 0
+	otherBytes := 0
+	for _, r := range s {
+		if unicode.Is(unicode.Han, r) {
+			han++
+		} else {
+			otherBytes += len(string(r))
+		}
+	}
+	return han + int(math.Ceil(float64(otherBytes)/bytesPerLatinToken))
+}
+
+// EstimateOutputTokens approximates the number of tokens the Vietnamese translation
+// of a Chinese source text consumes as model output.
+func EstimateOutputTokens(source string) int {
+	han := 0
+	otherBytes := 0
+	for _, r := range source {
+		if unicode.Is(unicode.Han, r) {
+			han++
+		} else {
+			otherBytes += len(string(r))
+		}
+	}
+	return int(math.Ceil(float64(han)*outputTokensPerHan)) + int(math.Ceil(float64(otherBytes)/bytesPerLatinToken))
+}
+
+// EstimateBatchOverhead returns the fixed input tokens paid once per batch request:
+// the system prompt plus the batch instructions.
+func (pb *PromptBuilder) EstimateBatchOverhead() int {
+	return EstimateTokens(pb.GetSystemPrompt()) + EstimateTokens(pb.BuildBatchUserPrompt(nil, nil, nil, nil))
+}
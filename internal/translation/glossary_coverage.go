@@ -0,0 +1,113 @@
+package translation
+
+import (
+	"sort"
+	"strings"
+
+	"rag-translator/internal/textutil"
+)
+
+// TermCount is how often a glossary term or candidate substring occurs in a corpus.
+type TermCount struct {
+	Text  string
+	Count int
+}
+
+// sortCounts orders counts by count, most frequent first, then by text.
+func sortCounts(counts []TermCount) {
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Text < counts[j].Text
+	})
+}
+
+// TermFrequencies counts the occurrences of each term in texts, a text → number
+// of occurrences in the corpus map. Every term is listed, unused ones with 0.
+func TermFrequencies(terms []string, texts map[string]int) []TermCount {
+	counts := make([]TermCount, 0, len(terms))
+	for _, term := range terms {
+		n := 0
+		if term != "" {
+			for text, occurrences := range texts {
+				n += strings.Count(text, term) * occurrences
+			}
+		}
+		counts = append(counts, TermCount{Text: term, Count: n})
+	}
+	sortCounts(counts)
+	return counts
+}
+
+// CandidateTerms returns the source-script substrings of minN to maxN characters
+// that occur at least minCount times in texts and are neither part of a term nor
+// contain one: likely glossary additions. A substring that only ever occurs inside
+// a longer candidate, with the same count, is left out in favour of it.
+func CandidateTerms(terms []string, texts map[string]int, minN, maxN, minCount int) []TermCount {
+	grams := make(map[string]int)
+	for text, occurrences := range texts {
+		for _, run := range sourceRuns(text) {
+			for n := minN; n <= maxN; n++ {
+				for i := 0; i+n <= len(run); i++ {
+					grams[string(run[i:i+n])] += occurrences
+				}
+			}
+		}
+	}
+
+	// A gram whose count equals that of a longer gram extending it only occurs
+	// inside that gram.
+	subsumed := make(map[string]bool)
+	for gram, count := range grams {
+		runes := []rune(gram)
+		if len(runes) <= minN {
+			continue
+		}
+		for _, part := range []string{string(runes[1:]), string(runes[:len(runes)-1])} {
+			if grams[part] == count {
+				subsumed[part] = true
+			}
+		}
+	}
+
+	var candidates []TermCount
+	for gram, count := range grams {
+		if count < minCount || subsumed[gram] || overlapsTerm(gram, terms) {
+			continue
+		}
+		candidates = append(candidates, TermCount{Text: gram, Count: count})
+	}
+	sortCounts(candidates)
+	return candidates
+}
+
+// sourceRuns splits text into its maximal runs of source-script characters.
+func sourceRuns(text string) [][]rune {
+	var runs [][]rune
+	var run []rune
+	for _, r := range text {
+		if textutil.ContainsSource(string(r)) {
+			run = append(run, r)
+			continue
+		}
+		if len(run) > 0 {
+			runs = append(runs, run)
+			run = nil
+		}
+	}
+	if len(run) > 0 {
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+// overlapsTerm reports whether gram is part of a term or contains one.
+func overlapsTerm(gram string, terms []string) bool {
+	for _, term := range terms {
+		if term != "" && (strings.Contains(term, gram) || strings.Contains(gram, term)) {
+			return true
+		}
+	}
+	return false
+}
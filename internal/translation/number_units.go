@@ -0,0 +1,106 @@
+package translation
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultVietnameseNumberUnits maps Chinese numeric units to the words Vietnamese
+// localization conventionally uses for them.
+var DefaultVietnameseNumberUnits = map[string]string{
+	"万": "vạn",
+	"亿": "ức",
+}
+
+// NumberUnits rewrites Chinese numeric units that a translation left attached to
+// a number, such as 10万, into their target-language word: 10 vạn.
+type NumberUnits struct {
+	units   map[string]string
+	pattern *regexp.Regexp // a number followed by one of the units; nil when there are none
+}
+
+// NewNumberUnits creates a rewriter for units, a Chinese unit → rendering table.
+func NewNumberUnits(units map[string]string) *NumberUnits {
+	nu := &NumberUnits{units: units}
+	if len(units) == 0 {
+		return nu
+	}
+	keys := make([]string, 0, len(units))
+	for unit := range units {
+		keys = append(keys, regexp.QuoteMeta(unit))
+	}
+	// Longer units first, so a multi-character unit wins over its prefix.
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+	nu.pattern = regexp.MustCompile(`(\d(?:[\d.,]*\d)?)\s*(` + strings.Join(keys, "|") + `)`)
+	return nu
+}
+
+// Apply returns translated with every number-plus-unit rewritten. Only texts whose
+// source contains one of the units are touched.
+func (nu *NumberUnits) Apply(source, translated string) string {
+	if nu.pattern == nil || !nu.containsUnit(source) {
+		return translated
+	}
+	var sb strings.Builder
+	last := 0
+	for _, loc := range nu.pattern.FindAllStringSubmatchIndex(translated, -1) {
+		sb.WriteString(translated[last:loc[0]])
+		sb.WriteString(translated[loc[2]:loc[3]])
+		sb.WriteString(" ")
+		sb.WriteString(nu.units[translated[loc[4]:loc[5]]])
+		// Keep the word apart from what follows, as in 1亿5000万 → 1 ức 5000 vạn.
+		if r, _ := utf8.DecodeRuneInString(translated[loc[1]:]); unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteString(" ")
+		}
+		last = loc[1]
+	}
+	sb.WriteString(translated[last:])
+	return sb.String()
+}
+
+// containsUnit reports whether s contains any of the units.
+func (nu *NumberUnits) containsUnit(s string) bool {
+	for unit := range nu.units {
+		if strings.Contains(s, unit) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadNumberUnits reads a unit table of tab-separated unit→rendering pairs. Blank
+// lines and lines starting with # are ignored.
+func LoadNumberUnits(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open number units file: %w", err)
+	}
+	defer file.Close()
+
+	units := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		unit, rendering, found := strings.Cut(line, "\t")
+		unit, rendering = strings.TrimSpace(unit), strings.TrimSpace(rendering)
+		if !found || unit == "" || rendering == "" {
+			return nil, fmt.Errorf("number units line %d: expected unit<TAB>rendering", lineNum)
+		}
+		units[unit] = rendering
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan number units file: %w", err)
+	}
+	return units, nil
+}
@@ -4,35 +4,54 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"time"
 
-	"github.com/rs/zerolog/log"
+	"rag-translator/internal/apierror"
 )
 
-const geminiBaseURL = "https://generativelanguage.googleapis.com/v1beta/models"
+const (
+	geminiAPIURL  = "https://generativelanguage.googleapis.com/v1beta"
+	geminiBaseURL = geminiAPIURL + "/models"
+)
 
 // OpusClient handles translation requests via the Google Gemini API.
 type OpusClient struct {
 	apiKey     string
 	model      string
+	maxRetries int // retries after a failed request; 0 fails on the first error
 	httpClient *http.Client
 }
 
-// NewOpusClient creates a new Gemini translation client.
-func NewOpusClient(apiKey, model string) *OpusClient {
+// NewOpusClient creates a new Gemini translation client that retries a failed
+// request up to maxRetries times.
+func NewOpusClient(apiKey, model string, maxRetries int) *OpusClient {
 	return &OpusClient{
-		apiKey: apiKey,
-		model:  model,
+		apiKey:     apiKey,
+		model:      model,
+		maxRetries: maxRetries,
 		httpClient: &http.Client{
 			Timeout: 120 * time.Second,
 		},
 	}
 }
 
+// SetTransport sends requests through rt, e.g. a transport with proxy and CA
+// settings from httpclient.NewTransport.
+func (oc *OpusClient) SetTransport(rt http.RoundTripper) {
+	oc.httpClient.Transport = rt
+}
+
+// SetTimeout bounds each request, including reading the response. The default
+// is 120s.
+func (oc *OpusClient) SetTimeout(d time.Duration) {
+	oc.httpClient.Timeout = d
+}
+
 // --- Gemini API request/response types ---
 
 type geminiRequest struct {
@@ -48,6 +67,16 @@ type geminiContent struct {
 
 type geminiPart struct {
 	Text string `json:"text"`
+	// Set in responses only. A part with a payload or a reasoning summary is never
+	// part of the answer, even when it also carries text.
+	Thought      bool            `json:"thought,omitempty"`
+	FunctionCall json.RawMessage `json:"functionCall,omitempty"`
+	InlineData   json.RawMessage `json:"inlineData,omitempty"`
+}
+
+// isAnswer reports whether p holds answer text.
+func (p geminiPart) isAnswer() bool {
+	return !p.Thought && p.FunctionCall == nil && p.InlineData == nil && p.Text != ""
 }
 
 type genConfig struct {
@@ -56,15 +85,33 @@ type genConfig struct {
 }
 
 type geminiResponse struct {
-	Candidates    []geminiCandidate `json:"candidates"`
-	UsageMetadata *geminiUsage      `json:"usageMetadata,omitempty"`
-	Error         *geminiError      `json:"error,omitempty"`
+	Candidates     []geminiCandidate     `json:"candidates"`
+	PromptFeedback *geminiPromptFeedback `json:"promptFeedback,omitempty"`
+	UsageMetadata  *geminiUsage          `json:"usageMetadata,omitempty"`
+	Error          *geminiError          `json:"error,omitempty"`
+}
+
+type geminiPromptFeedback struct {
+	BlockReason string `json:"blockReason,omitempty"`
+}
+
+// blockingFinishReasons are finish reasons of a response withheld by the model.
+var blockingFinishReasons = map[string]bool{
+	"SAFETY":             true,
+	"RECITATION":         true,
+	"BLOCKLIST":          true,
+	"PROHIBITED_CONTENT": true,
+	"SPII":               true,
 }
 
 type geminiCandidate struct {
-	Content geminiContent `json:"content"`
+	Content      geminiContent `json:"content"`
+	FinishReason string        `json:"finishReason,omitempty"`
 }
 
+// finishMaxTokens is the finish reason of a response cut off at MaxOutputTokens.
+const finishMaxTokens = "MAX_TOKENS"
+
 type geminiUsage struct {
 	PromptTokenCount     int `json:"promptTokenCount"`
 	CandidatesTokenCount int `json:"candidatesTokenCount"`
@@ -77,9 +124,14 @@ type geminiError struct {
 	Status  string `json:"status"`
 }
 
-// Translate sends a translation request to Gemini and returns the translated text.
-func (oc *OpusClient) Translate(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
-	reqBody := geminiRequest{
+// err converts an error reported in a response body to an API error.
+func (e *geminiError) err() error {
+	return apierror.New(e.Code, fmt.Sprintf("[%s] %s", e.Status, e.Message))
+}
+
+// newGeminiRequest builds the request for one translation prompt.
+func newGeminiRequest(systemPrompt, userPrompt string) geminiRequest {
+	return geminiRequest{
 		SystemInstruction: &geminiContent{
 			Parts: []geminiPart{{Text: systemPrompt}},
 		},
@@ -94,128 +146,247 @@ func (oc *OpusClient) Translate(ctx context.Context, systemPrompt, userPrompt st
 			Temperature:     0.3,
 		},
 	}
+}
+
+// Translate sends a translation request to Gemini and returns the translated text.
+func (oc *OpusClient) Translate(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	text, _, err := oc.generate(ctx, systemPrompt, userPrompt)
+	return text, err
+}
+
+// TranslateDetailed is like Translate but also reports whether the response was cut
+// off at the output token limit, in which case its end is missing.
+func (oc *OpusClient) TranslateDetailed(ctx context.Context, systemPrompt, userPrompt string) (string, bool, error) {
+	text, finishReason, err := oc.generate(ctx, systemPrompt, userPrompt)
+	return text, finishReason == finishMaxTokens, err
+}
 
-	bodyBytes, err := json.Marshal(reqBody)
+// generate sends a request with retries and returns the response text and the
+// candidate's finish reason.
+func (oc *OpusClient) generate(ctx context.Context, systemPrompt, userPrompt string) (string, string, error) {
+	bodyBytes, err := json.Marshal(newGeminiRequest(systemPrompt, userPrompt))
 	if err != nil {
-		return "", fmt.Errorf("marshal translation request: %w", err)
+		return "", "", fmt.Errorf("marshal translation request: %w", err)
 	}
 
 	var lastErr error
-	maxRetries := 3
-
-	for attempt := 0; attempt < maxRetries; attempt++ {
+	for attempt := 0; attempt <= oc.maxRetries; attempt++ {
 		if attempt > 0 {
 			backoff := time.Duration(attempt*2) * time.Second
-			log.Warn().Int("attempt", attempt+1).Dur("backoff", backoff).Msg("Retrying translation")
+			Logger(ctx).Warn().Int("attempt", attempt+1).Dur("backoff", backoff).Msg("Retrying translation")
 			select {
 			case <-ctx.Done():
-				return "", ctx.Err()
+				return "", "", ctx.Err()
 			case <-time.After(backoff):
 			}
 		}
 
-		result, err := oc.doRequest(ctx, bodyBytes)
+		result, finishReason, err := oc.doRequest(ctx, bodyBytes)
 		if err == nil {
-			return result, nil
+			return result, finishReason, nil
 		}
 		lastErr = err
 
 		// Don't retry on context cancellation.
 		if ctx.Err() != nil {
-			return "", ctx.Err()
+			return "", "", ctx.Err()
+		}
+		// Auth failures and invalid requests fail the same way every time.
+		if !apierror.Retryable(err) {
+			return "", "", fmt.Errorf("translation failed: %w", err)
 		}
 	}
 
-	return "", fmt.Errorf("translation failed after %d retries: %w", maxRetries, lastErr)
+	return "", "", fmt.Errorf("translation failed after %d retries: %w", oc.maxRetries, lastErr)
 }
 
-func (oc *OpusClient) doRequest(ctx context.Context, bodyBytes []byte) (string, error) {
+func (oc *OpusClient) doRequest(ctx context.Context, bodyBytes []byte) (string, string, error) {
 	url := fmt.Sprintf("%s/%s:generateContent?key=%s", geminiBaseURL, oc.model, oc.apiKey)
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(bodyBytes))
 	if err != nil {
-		return "", fmt.Errorf("create request: %w", err)
+		return "", "", fmt.Errorf("create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := oc.httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("API call: %w", err)
+		return "", "", fmt.Errorf("API call: %w", err)
 	}
 	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("read response: %w", err)
-	}
-
-	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
-		return "", fmt.Errorf("retryable error (status %d): %s", resp.StatusCode, string(respBody))
+		return "", "", fmt.Errorf("read response: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+		return "", "", apierror.New(resp.StatusCode, string(respBody))
 	}
 
 	var apiResp geminiResponse
 	if err := json.Unmarshal(respBody, &apiResp); err != nil {
-		return "", fmt.Errorf("unmarshal response: %w", err)
+		return "", "", fmt.Errorf("unmarshal response: %w", err)
 	}
 
+	return answer(ctx, &apiResp)
+}
+
+// errEmptyResponse is returned for a response without answer text.
+var errEmptyResponse = errors.New("empty response")
+
+// answer returns the answer text of a response and the candidate's finish reason.
+func answer(ctx context.Context, apiResp *geminiResponse) (string, string, error) {
 	if apiResp.Error != nil {
-		return "", fmt.Errorf("API error [%s]: %s", apiResp.Error.Status, apiResp.Error.Message)
+		return "", "", apiResp.Error.err()
 	}
 
 	if len(apiResp.Candidates) == 0 {
-		return "", fmt.Errorf("empty response: no candidates")
+		if apiResp.PromptFeedback != nil && apiResp.PromptFeedback.BlockReason != "" {
+			return "", "", apierror.Blocked("prompt blocked: " + apiResp.PromptFeedback.BlockReason)
+		}
+		return "", "", fmt.Errorf("%w: no candidates", errEmptyResponse)
 	}
 
-	// Extract text from the first candidate.
+	// Extract text from the first candidate, skipping parts that hold no answer.
 	var result strings.Builder
-	for _, p := range apiResp.Candidates[0].Content.Parts {
+	parts := apiResp.Candidates[0].Content.Parts
+	skipped := 0
+	for _, p := range parts {
+		if !p.isAnswer() {
+			skipped++
+			continue
+		}
 		result.WriteString(p.Text)
 	}
+	if skipped > 0 {
+		Logger(ctx).Debug().Int("skipped", skipped).Int("parts", len(parts)).Msg("Skipped response parts without answer text")
+	}
 
 	if apiResp.UsageMetadata != nil {
-		log.Debug().
+		Logger(ctx).Debug().
 			Int("prompt_tokens", apiResp.UsageMetadata.PromptTokenCount).
 			Int("output_tokens", apiResp.UsageMetadata.CandidatesTokenCount).
 			Msg("Translation complete")
 	}
 
-	return strings.TrimSpace(result.String()), nil
+	finishReason := apiResp.Candidates[0].FinishReason
+	if blockingFinishReasons[finishReason] && strings.TrimSpace(result.String()) == "" {
+		return "", "", apierror.Blocked("response withheld: " + finishReason)
+	}
+	if strings.TrimSpace(result.String()) == "" {
+		// An empty answer must not be taken, and cached, as a translation.
+		return "", "", fmt.Errorf("%w: no text in %d parts (finish reason %q)", errEmptyResponse, len(parts), finishReason)
+	}
+	if finishReason == finishMaxTokens {
+		Logger(ctx).Warn().Msg("Response hit the output token limit and was truncated")
+	}
+
+	return strings.TrimSpace(result.String()), finishReason, nil
 }
 
-// TranslateBatch translates multiple texts using a single API call for efficiency.
-func (oc *OpusClient) TranslateBatch(ctx context.Context, systemPrompt string, texts []string) ([]string, error) {
-	if len(texts) == 0 {
-		return nil, nil
+// TranslateBatchDetailed is like TranslateDetailed for a user prompt holding n
+// texts whose translations are expected as n |||-delimited segments. An empty,
+// short or malformed response that was not cut off is retried once with a
+// reinforced instruction, and the retry is kept when it is well formed or covers
+// more texts; a truncated response would only be truncated again. An empty
+// response is an error only when the retry fails too.
+func (oc *OpusClient) TranslateBatchDetailed(ctx context.Context, systemPrompt, userPrompt string, n int) (string, bool, error) {
+	response, finishReason, err := oc.generate(ctx, systemPrompt, userPrompt)
+	if (err != nil && !errors.Is(err, errEmptyResponse)) || finishReason == finishMaxTokens {
+		return response, finishReason == finishMaxTokens, err
 	}
 
-	// Build a combined prompt for batch translation.
-	var sb strings.Builder
-	sb.WriteString("Translate each of the following texts. Return ONLY the translations, one per line, in the same order.\n")
-	sb.WriteString("Use ||| as a delimiter between translations.\n\n")
-	for i, t := range texts {
-		sb.WriteString(fmt.Sprintf("[%d] %s\n", i+1, t))
+	problem := checkBatchResponse(response, n)
+	if problem == "" {
+		return response, false, nil
+	}
+	Logger(ctx).Warn().Str("problem", problem).Int("texts", n).Msg("Malformed batch response, retrying once")
+	firstErr := err
+	retried, finishReason, err := oc.generate(ctx, systemPrompt, reinforceBatchPrompt(userPrompt, n))
+	switch {
+	case err != nil && firstErr != nil:
+		return "", false, err
+	case err != nil:
+		Logger(ctx).Warn().Err(err).Msg("Batch retry failed, keeping first response")
+	case finishReason == finishMaxTokens:
+		return retried, true, nil
+	case preferRetry(response, retried, n):
+		response = retried
 	}
+	return response, false, nil
+}
 
-	response, err := oc.Translate(ctx, systemPrompt, sb.String())
-	if err != nil {
-		return nil, err
+// reinforceBatchPrompt prefixes a batch user prompt for n texts with an explicit
+// instruction on the expected segments.
+func reinforceBatchPrompt(userPrompt string, n int) string {
+	return fmt.Sprintf("You MUST return exactly %d translations separated by |||, one for each numbered text, "+
+		"with no numbering, notes or empty entries.\n\n%s", n, userPrompt)
+}
+
+// preferRetry reports whether the retried response for n texts should replace the
+// first: it is well formed, or has a segment for more texts.
+func preferRetry(first, retried string, n int) bool {
+	return checkBatchResponse(retried, n) == "" || filledSegments(retried, n) > filledSegments(first, n)
+}
+
+// checkBatchResponse describes what is wrong with a batch response for n texts, or
+// returns "" when it holds exactly n non-empty segments. A trailing delimiter is
+// tolerated.
+func checkBatchResponse(response string, n int) string {
+	if strings.TrimSpace(response) == "" {
+		return "empty"
+	}
+	parts := responseSegments(response)
+	switch {
+	case len(parts) < n:
+		return fmt.Sprintf("%d of %d segments", len(parts), n)
+	case len(parts) > n:
+		return fmt.Sprintf("%d segments for %d texts", len(parts), n)
+	}
+	for _, p := range parts {
+		if strings.TrimSpace(p) == "" {
+			return "empty segment"
+		}
 	}
+	return ""
+}
 
-	// Parse batch response.
-	parts := strings.Split(response, "|||")
-	results := make([]string, len(texts))
-	for i := range results {
-		if i < len(parts) {
-			results[i] = strings.TrimSpace(parts[i])
-		} else {
-			results[i] = texts[i] // fallback to original if parsing fails
+// responseSegments splits a batch response on its delimiters, tolerating a
+// trailing one.
+func responseSegments(response string) []string {
+	return strings.Split(strings.TrimSuffix(strings.TrimSpace(response), "|||"), "|||")
+}
+
+// BatchSegmentCount returns how many segments a batch response holds; an empty
+// response holds none.
+func BatchSegmentCount(response string) int {
+	if strings.TrimSpace(response) == "" {
+		return 0
+	}
+	return len(responseSegments(response))
+}
+
+// filledSegments counts the texts a batch response for n texts has a segment for.
+func filledSegments(response string, n int) int {
+	filled := 0
+	for _, seg := range SplitBatchResponse(response, n) {
+		if seg != "" {
+			filled++
 		}
 	}
+	return filled
+}
 
-	return results, nil
+// SplitBatchResponse splits a |||-delimited batch response into exactly n trimmed
+// segments, one per input index. Indices the response does not cover, or covers
+// with an empty segment, are returned as "" so callers can handle each one alone.
+func SplitBatchResponse(response string, n int) []string {
+	parts := strings.Split(response, "|||")
+	segments := make([]string, n)
+	for i := 0; i < n && i < len(parts); i++ {
+		segments[i] = strings.TrimSpace(parts[i])
+	}
+	return segments
 }
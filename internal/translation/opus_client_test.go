@@ -0,0 +1,156 @@
+package translation
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// roundTripFunc serves requests with a function.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// scriptedClient returns a client whose successive requests are answered with
+// replies, and a pointer to the user prompts it received.
+func scriptedClient(t *testing.T, replies ...string) (*OpusClient, *[]string) {
+	t.Helper()
+	var prompts []string
+	oc := NewOpusClient("key", "model", 0)
+	oc.SetTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		var req geminiRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		prompts = append(prompts, req.Contents[0].Parts[0].Text)
+		if len(prompts) > len(replies) {
+			t.Fatalf("unexpected request %d", len(prompts))
+		}
+		body, _ := json.Marshal(geminiResponse{Candidates: []geminiCandidate{{
+			Content:      geminiContent{Parts: []geminiPart{{Text: replies[len(prompts)-1]}}},
+			FinishReason: "STOP",
+		}}})
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(string(body)))}, nil
+	}))
+	return oc, &prompts
+}
+
+func TestTranslateBatchDetailed(t *testing.T) {
+	tests := []struct {
+		name     string
+		replies  []string
+		want     string
+		requests int
+		wantErr  bool
+	}{
+		{"well formed", []string{"a ||| b ||| c"}, "a ||| b ||| c", 1, false},
+		{"trailing delimiter", []string{"a ||| b ||| c |||"}, "a ||| b ||| c |||", 1, false},
+		{"empty then fixed", []string{"", "a ||| b ||| c"}, "a ||| b ||| c", 2, false},
+		{"empty twice", []string{"", ""}, "", 2, true},
+		{"short then fixed", []string{"a ||| b", "a ||| b ||| c"}, "a ||| b ||| c", 2, false},
+		{"short retry covers fewer", []string{"a ||| b", "a"}, "a ||| b", 2, false},
+		{"empty segment then fixed", []string{"a |||  ||| c", "a ||| b ||| c"}, "a ||| b ||| c", 2, false},
+		{"too many segments then fixed", []string{"a ||| b ||| c ||| d", "a ||| b ||| c"}, "a ||| b ||| c", 2, false},
+		{"no delimiters", []string{"a\nb\nc", "a\nb\nc"}, "a\nb\nc", 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oc, prompts := scriptedClient(t, tt.replies...)
+			got, truncated, err := oc.TranslateBatchDetailed(context.Background(), "system", "prompt", 3)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want || truncated {
+				t.Errorf("got %q (truncated %v), want %q", got, truncated, tt.want)
+			}
+			if len(*prompts) != tt.requests {
+				t.Fatalf("%d requests, want %d", len(*prompts), tt.requests)
+			}
+			if tt.requests > 1 && !strings.Contains((*prompts)[1], "exactly 3 translations") {
+				t.Errorf("retry prompt not reinforced: %q", (*prompts)[1])
+			}
+		})
+	}
+}
+
+func TestCheckBatchResponse(t *testing.T) {
+	tests := []struct {
+		response string
+		want     string
+	}{
+		{"a ||| b", ""},
+		{"a ||| b |||", ""},
+		{"", "empty"},
+		{"   ", "empty"},
+		{"a", "1 of 2 segments"},
+		{"a ||| b ||| c", "3 segments for 2 texts"},
+		{" ||| b", "empty segment"},
+	}
+	for _, tt := range tests {
+		if got := checkBatchResponse(tt.response, 2); got != tt.want {
+			t.Errorf("checkBatchResponse(%q, 2) = %q, want %q", tt.response, got, tt.want)
+		}
+	}
+}
+
+func TestSplitBatchResponse(t *testing.T) {
+	tests := []struct {
+		response string
+		want     []string
+	}{
+		{"a ||| b ||| c", []string{"a", "b", "c"}},
+		{"a ||| b", []string{"a", "b", ""}},
+		{"", []string{"", "", ""}},
+		{"a |||  ||| c ||| d", []string{"a", "", "c"}},
+	}
+	for _, tt := range tests {
+		got := SplitBatchResponse(tt.response, 3)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("SplitBatchResponse(%q, 3) = %q, want %q", tt.response, got, tt.want)
+		}
+	}
+}
+
+func TestAnswerMixedParts(t *testing.T) {
+	tests := []struct {
+		name    string
+		parts   string // JSON array of response parts
+		want    string
+		wantErr bool
+	}{
+		{"several text parts", `[{"text": "Xin "}, {"text": "chào"}]`, "Xin chào", false},
+		{"thought first", `[{"text": "The user wants a greeting.", "thought": true}, {"text": "Xin chào"}]`, "Xin chào", false},
+		{
+			"function call and inline data between text parts",
+			`[{"text": "Xin "}, {"functionCall": {"name": "lookup", "args": {}}}, {"text": "(image)", "inlineData": {"mimeType": "image/png", "data": ""}}, {"text": "chào"}]`,
+			"Xin chào", false,
+		},
+		{
+			"everything mixed",
+			`[{"text": "Plan: translate.", "thought": true}, {"text": "Nhận "}, {"inlineData": {"mimeType": "image/png", "data": ""}}, {"text": "Let me check.", "thought": true}, {"text": "được"}, {"functionCall": {"name": "lookup"}}]`,
+			"Nhận được", false,
+		},
+		{"only thoughts", `[{"text": "Thinking...", "thought": true}, {"functionCall": {"name": "lookup"}}]`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp geminiResponse
+			body := `{"candidates": [{"content": {"parts": ` + tt.parts + `}, "finishReason": "STOP"}]}`
+			if err := json.Unmarshal([]byte(body), &resp); err != nil {
+				t.Fatal(err)
+			}
+			got, _, err := answer(context.Background(), &resp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("answer = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package translation
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LoadOverrides reads a manual corrections file of tab-separated source→target pairs.
+// Blank lines and lines starting with # are ignored; \t, \n and \r escapes are decoded
+// the same way the seed corpus TSV export encodes them. A row whose target is still
+// empty is skipped, so a partly filled unresolved-strings export can be applied.
+func LoadOverrides(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open overrides file: %w", err)
+	}
+	defer file.Close()
+
+	overrides := make(map[string]string)
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 1024*1024)
+
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		source, target, found := strings.Cut(line, "\t")
+		if !found {
+			return nil, fmt.Errorf("overrides line %d: expected source<TAB>target", lineNum)
+		}
+		// Allow trailing columns (e.g. a reviewer note) after the target.
+		target, _, _ = strings.Cut(target, "\t")
+
+		source = unescapeTSV(source)
+		target = unescapeTSV(target)
+		if source == "" {
+			return nil, fmt.Errorf("overrides line %d: empty source", lineNum)
+		}
+		if target == "" {
+			continue
+		}
+		overrides[source] = target
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan overrides file: %w", err)
+	}
+
+	return overrides, nil
+}
+
+// unescapeTSV reverses the \t, \n and \r escaping used in TSV cells.
+func unescapeTSV(s string) string {
+	return strings.NewReplacer(`\t`, "\t", `\n`, "\n", `\r`, "\r").Replace(s)
+}
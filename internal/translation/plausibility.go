@@ -0,0 +1,121 @@
+package translation
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"rag-translator/internal/textutil"
+)
+
+// RejectRule names a check that keeps an implausible model output out of the
+// translation cache, where it would otherwise be served on every later run.
+type RejectRule string
+
+const (
+	// RejectEmpty rejects blank translations.
+	RejectEmpty RejectRule = "empty"
+	// RejectUnchanged rejects a translation identical to a source holding
+	// source-script text: the model echoed it untranslated.
+	RejectUnchanged RejectRule = "unchanged"
+	// RejectRefusal rejects translations matching a refusal pattern, such as an
+	// apology in place of the text.
+	RejectRefusal RejectRule = "refusal"
+)
+
+// DefaultRefusalPatterns match the refusals and apologies a model returns instead
+// of a translation. They name the request itself so that dialogue such as "I
+// cannot let you pass" is not mistaken for one.
+var DefaultRefusalPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(cannot|can't|can not|unable to|won't|will not)\s+(translate|provide (a|the|this) translation|help with (this|that) request|assist with (this|that) request)\b`),
+	regexp.MustCompile(`(?i)\bI'?m sorry,? but\b|\bI am sorry,? but\b`),
+	regexp.MustCompile(`(?i)\bas an AI\b|\blanguage model\b`),
+}
+
+// ParseRejectRules parses a comma-separated list of rule names. "none" disables
+// every rule.
+func ParseRejectRules(list string) ([]RejectRule, error) {
+	var rules []RejectRule
+	for _, name := range strings.Split(list, ",") {
+		switch r := RejectRule(strings.TrimSpace(name)); r {
+		case RejectEmpty, RejectUnchanged, RejectRefusal:
+			rules = append(rules, r)
+		case "", "none":
+		default:
+			return nil, fmt.Errorf("unknown rule %q, expected empty, unchanged, refusal or none", r)
+		}
+	}
+	return rules, nil
+}
+
+// LoadRefusalPatterns reads one regular expression per line. Blank lines and lines
+// starting with # are skipped.
+func LoadRefusalPatterns(path string) ([]*regexp.Regexp, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open refusal patterns file: %w", err)
+	}
+	defer file.Close()
+
+	var patterns []*regexp.Regexp
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		re, err := regexp.Compile(line)
+		if err != nil {
+			return nil, fmt.Errorf("refusal patterns line %d: %w", lineNum, err)
+		}
+		patterns = append(patterns, re)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan refusal patterns file: %w", err)
+	}
+	return patterns, nil
+}
+
+// PlausibilityCheck decides whether a model output may be cached as the
+// translation of its source.
+type PlausibilityCheck struct {
+	rules    map[RejectRule]bool
+	refusals []*regexp.Regexp
+}
+
+// NewPlausibilityCheck applies rules, with refusals as the patterns of
+// RejectRefusal. Nil refusals means DefaultRefusalPatterns.
+func NewPlausibilityCheck(rules []RejectRule, refusals []*regexp.Regexp) *PlausibilityCheck {
+	pc := &PlausibilityCheck{rules: make(map[RejectRule]bool, len(rules)), refusals: refusals}
+	for _, r := range rules {
+		pc.rules[r] = true
+	}
+	if pc.refusals == nil {
+		pc.refusals = DefaultRefusalPatterns
+	}
+	return pc
+}
+
+// Check returns an error naming the rule that rejects translated as the
+// translation of source, or nil when it passes them all.
+func (pc *PlausibilityCheck) Check(source, translated string) error {
+	if pc.rules[RejectEmpty] && strings.TrimSpace(translated) == "" {
+		return fmt.Errorf("rejected: empty translation")
+	}
+	if pc.rules[RejectUnchanged] && translated == source && textutil.ContainsSource(source) {
+		return fmt.Errorf("rejected: translation identical to source")
+	}
+	if pc.rules[RejectRefusal] {
+		for _, re := range pc.refusals {
+			// A pattern also found in the source is part of the text, not a refusal.
+			if re.MatchString(translated) && !re.MatchString(source) {
+				return fmt.Errorf("rejected: looks like a refusal (matches %q)", re.String())
+			}
+		}
+	}
+	return nil
+}
@@ -2,71 +2,400 @@ package translation
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
+	"text/template"
 
+	"rag-translator/internal/graph"
+	"rag-translator/internal/interpolation"
+	"rag-translator/internal/language"
 	"rag-translator/internal/rag"
+	"rag-translator/internal/segment"
 )
 
 // PromptBuilder constructs system and user prompts for translation.
-type PromptBuilder struct{}
+type PromptBuilder struct {
+	matcher        *segment.Matcher // optional, nil means plain substring matching
+	sourceLang     string
+	targetLang     string
+	targetCode     string // primary subtag of the target language, keys countExamples
+	systemTemplate *template.Template
+	batchTemplate  *template.Template // optional, nil means the built-in batch layout
+	singleTemplate *template.Template // optional, nil means the built-in single-text layout
+	systemPrompt   string             // systemTemplate rendered with the current settings
+	glossary       string             // optional full terminology for the system prompt
+	strictness     SeedStrictness
+}
+
+// SeedStrictness selects how much weight verified seed translations carry.
+type SeedStrictness string
+
+const (
+	// SeedStrictnessOff lists seeds as a reference the model may deviate from.
+	SeedStrictnessOff SeedStrictness = "off"
+	// SeedStrictnessSoft instructs the model to reuse seed translations verbatim.
+	SeedStrictnessSoft SeedStrictness = "soft"
+	// SeedStrictnessHard is soft, and callers also use an exact-source seed match
+	// directly instead of calling the model.
+	SeedStrictnessHard SeedStrictness = "hard"
+)
 
-// NewPromptBuilder creates a new prompt builder.
+// NewPromptBuilder creates a new prompt builder for the default zh→vi language pair.
 func NewPromptBuilder() *PromptBuilder {
-	return &PromptBuilder{}
+	pb := &PromptBuilder{systemTemplate: systemPromptTemplate, strictness: SeedStrictnessOff}
+	source, _ := language.Lookup(language.DefaultSource)
+	target, _ := language.Lookup(language.DefaultTarget)
+	pb.SetLanguages(source, target)
+	return pb
 }
 
-const systemPrompt = `You are a professional Vietnamese localizer specializing in Chinese wuxia MMORPG games, specifically 剑侠世界2 (Jianxia World 2).
+// systemPromptData holds the variables available to the system prompt template.
+type systemPromptData struct {
+	SourceLang string
+	TargetLang string
+	Glossary   string // full terminology section, empty unless SetGlossary was called
+}
+
+// batchPromptData holds the variables available to a batch prompt template.
+type batchPromptData struct {
+	SourceLang string
+	TargetLang string
+	Context    string // seed, retrieval and terminology sections
+	Texts      string // numbered texts, one per line
+	Count      int
+}
+
+// singlePromptData holds the variables available to a single-text prompt template.
+type singlePromptData struct {
+	SourceLang string
+	TargetLang string
+	Context    string // seed and retrieval sections
+	Text       string
+}
+
+var systemPromptTemplate = template.Must(template.New("system").Parse(`You are a professional {{.TargetLang}} localizer specializing in Chinese wuxia MMORPG games, specifically 剑侠世界2 (Jianxia World 2).
 
 Rules:
-1. Translate Simplified Chinese to Vietnamese.
+1. Translate {{.SourceLang}} to {{.TargetLang}}.
 2. Use correct wuxia terminology from the provided knowledge graph context.
-3. Preserve ALL placeholders like {{var_1}}, {{var_2}}, etc. — copy them exactly as-is into your translation.
+3. Preserve ALL placeholders like {{"{{"}}var_1{{"}}"}}, {{"{{"}}var_2{{"}}"}}, etc. — copy them exactly as-is into your translation.
 4. Preserve ALL formatting, syntax, and special characters.
-5. Output ONLY the Vietnamese translation, nothing else.
+5. Output ONLY the {{.TargetLang}} translation, nothing else.
 6. Do NOT add explanations, notes, or extra text.
-7. If a term has a standard wuxia Vietnamese translation, always use it.
+7. If a term has a standard wuxia {{.TargetLang}} translation, always use it.
 8. Maintain the same tone and register as the original.
-9. For game UI text, keep it concise and natural in Vietnamese.`
+9. For game UI text, keep it concise and natural in {{.TargetLang}}.{{if .Glossary}}
+
+{{.Glossary}}{{end}}`))
+
+// SetLanguages renders the system prompt for the given source and target languages.
+func (pb *PromptBuilder) SetLanguages(sourceLang, targetLang language.Language) {
+	pb.sourceLang = sourceLang.Name
+	pb.targetLang = targetLang.Name
+	pb.targetCode = language.Base(targetLang.Code)
+	pb.renderSystemPrompt()
+}
+
+// LoadTemplates replaces the built-in prompts with text/template definitions from
+// a file. A file may define "system", "batch" and "single" templates; one without
+// any of them is used as the system template. Templates not defined keep their
+// built-in form. See systemPromptData, batchPromptData and singlePromptData for
+// the available variables.
+func (pb *PromptBuilder) LoadTemplates(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read prompt template: %w", err)
+	}
+	tmpl, err := template.New("file").Parse(string(data))
+	if err != nil {
+		return fmt.Errorf("parse prompt template: %w", err)
+	}
+
+	system, batch, single := tmpl.Lookup("system"), tmpl.Lookup("batch"), tmpl.Lookup("single")
+	if system == nil && batch == nil && single == nil {
+		system = tmpl
+	}
+
+	// Execute each template once so mistakes surface now rather than mid-run.
+	checks := []struct {
+		t    *template.Template
+		data any
+	}{
+		{system, systemPromptData{}},
+		{batch, batchPromptData{}},
+		{single, singlePromptData{}},
+	}
+	for _, c := range checks {
+		if c.t == nil {
+			continue
+		}
+		if err := c.t.Execute(io.Discard, c.data); err != nil {
+			return fmt.Errorf("execute prompt template %q: %w", c.t.Name(), err)
+		}
+	}
+
+	if system != nil {
+		pb.systemTemplate = system
+	}
+	pb.batchTemplate = batch
+	pb.singleTemplate = single
+	pb.renderSystemPrompt()
+	return nil
+}
+
+// renderSystemPrompt renders the system template with the current settings.
+func (pb *PromptBuilder) renderSystemPrompt() {
+	pb.systemPrompt = pb.execute(pb.systemTemplate, systemPromptData{
+		SourceLang: pb.sourceLang,
+		TargetLang: pb.targetLang,
+		Glossary:   pb.glossary,
+	})
+}
+
+// SetTermMatcher selects how terminology for a prompt is matched in the texts;
+// see segment.Mode.
+func (pb *PromptBuilder) SetTermMatcher(m *segment.Matcher) {
+	pb.matcher = m
+}
+
+// SetSeedStrictness selects how seed translations are worded in prompts.
+func (pb *PromptBuilder) SetSeedStrictness(strictness SeedStrictness) {
+	pb.strictness = strictness
+}
+
+// SelectTerms returns the subset of terminologyMap whose Chinese terms occur in any of texts.
+func (pb *PromptBuilder) SelectTerms(texts []string, terminologyMap map[string]string) map[string]string {
+	relevant := make(map[string]string)
+	for _, text := range texts {
+		for zh, vi := range terminologyMap {
+			if pb.matcher.ContainsTerm(text, zh) {
+				relevant[zh] = vi
+			}
+		}
+	}
+	return relevant
+}
+
+// MergeTerms returns terms with the overrides that occur in any of texts layered
+// on top, so a directory glossary wins over the global terminology.
+func (pb *PromptBuilder) MergeTerms(texts []string, terms, overrides map[string]string) map[string]string {
+	selected := pb.SelectTerms(texts, overrides)
+	if len(selected) == 0 {
+		return terms
+	}
+	merged := make(map[string]string, len(terms)+len(selected))
+	for zh, vi := range terms {
+		merged[zh] = vi
+	}
+	for zh, vi := range selected {
+		merged[zh] = vi
+	}
+	return merged
+}
+
+// SetGlossary appends the complete terminology map to the system prompt. The
+// system prompt is then one stable prefix shared by every request, which providers
+// can cache, and batches no longer need their own terminology section.
+func (pb *PromptBuilder) SetGlossary(terminologyMap map[string]string) {
+	pb.glossary = formatTerminology(terminologyMap)
+	pb.renderSystemPrompt()
+}
 
 // GetSystemPrompt returns the system prompt for translation.
 func (pb *PromptBuilder) GetSystemPrompt() string {
-	return systemPrompt
+	return pb.systemPrompt
 }
 
-// BuildUserPrompt constructs the user prompt with RAG context.
-func (pb *PromptBuilder) BuildUserPrompt(text string, retriever *rag.Retriever, retrievalResult *rag.RetrievalResult) string {
+// BuildUserPrompt constructs the user prompt with RAG context. mapping is the
+// placeholder mapping of the protected text; counts among it get phrasing
+// guidance.
+func (pb *PromptBuilder) BuildUserPrompt(text string, mapping []interpolation.Mapping, retriever *rag.Retriever, retrievalResult *rag.RetrievalResult) string {
 	var sb strings.Builder
 
-	// Add retrieval context if available.
+	// Add retrieval context if available. Seeds are rendered here so their wording
+	// follows the seed strictness.
 	if retrievalResult != nil {
-		contextStr := retriever.BuildContextString(retrievalResult)
+		sb.WriteString(pb.formatSeeds(retrievalResult.SeedTranslations))
+		rest := *retrievalResult
+		rest.SeedTranslations = nil
+		contextStr := retriever.BuildContextString(&rest)
 		if contextStr != "" {
 			sb.WriteString(contextStr)
 		}
 	}
+	sb.WriteString(pb.formatCountGuidance([][]interpolation.Mapping{mapping}, false))
+
+	if pb.singleTemplate != nil {
+		return pb.execute(pb.singleTemplate, singlePromptData{
+			SourceLang: pb.sourceLang,
+			TargetLang: pb.targetLang,
+			Context:    sb.String(),
+			Text:       text,
+		})
+	}
 
 	sb.WriteString(fmt.Sprintf("Text to translate:\n%s", text))
 
 	return sb.String()
 }
 
-// BuildBatchUserPrompt constructs a prompt for batch translations.
-func (pb *PromptBuilder) BuildBatchUserPrompt(texts []string, terminologyMap map[string]string) string {
+// BuildBatchUserPrompt constructs a prompt for batch translations. Retrieval
+// context, when given, is rendered compactly: verified seed translations first as
+// the highest-priority reference, then similar texts and entity relationships.
+// Callers bound its size, e.g. with rag.MergeResults. mappings holds the
+// placeholder mappings of the protected texts, or nil.
+func (pb *PromptBuilder) BuildBatchUserPrompt(texts []string, mappings [][]interpolation.Mapping, terminologyMap map[string]string, retrieval *rag.RetrievalResult) string {
 	var sb strings.Builder
 
+	if retrieval != nil {
+		sb.WriteString(pb.formatBatchContext(retrieval))
+	}
+
 	// Add terminology context.
 	if len(terminologyMap) > 0 {
-		sb.WriteString("=== Terminology Reference ===\n")
-		for zh, vi := range terminologyMap {
-			sb.WriteString(fmt.Sprintf("• %s → %s\n", zh, vi))
-		}
+		sb.WriteString(formatTerminology(terminologyMap))
 		sb.WriteString("\n")
 	}
+	sb.WriteString(pb.formatCountGuidance(mappings, true))
 
-	sb.WriteString("Translate each text below. Return ONLY the translations, separated by ||| delimiter, in the same order.\n\n")
+	var numbered strings.Builder
 	for i, t := range texts {
-		sb.WriteString(fmt.Sprintf("[%d] %s\n", i+1, t))
+		numbered.WriteString(fmt.Sprintf("[%d] %s\n", i+1, t))
+	}
+
+	if pb.batchTemplate != nil {
+		return pb.execute(pb.batchTemplate, batchPromptData{
+			SourceLang: pb.sourceLang,
+			TargetLang: pb.targetLang,
+			Context:    sb.String(),
+			Texts:      numbered.String(),
+			Count:      len(texts),
+		})
+	}
+
+	sb.WriteString("Translate each text below. Return ONLY the translations, separated by ||| delimiter, in the same order.\n\n")
+	sb.WriteString(numbered.String())
+
+	return sb.String()
+}
+
+// execute renders a prompt template. Built-in templates are static and loaded ones
+// are checked by LoadTemplates, so execution is not expected to fail.
+func (pb *PromptBuilder) execute(t *template.Template, data any) string {
+	var sb strings.Builder
+	_ = t.Execute(&sb, data)
+	return sb.String()
+}
+
+// formatBatchContext renders merged retrieval context for a batch prompt, one
+// line per item and without the scores shown in single-text prompts.
+func (pb *PromptBuilder) formatBatchContext(result *rag.RetrievalResult) string {
+	var sb strings.Builder
+
+	sb.WriteString(pb.formatSeeds(result.SeedTranslations))
+
+	if len(result.SimilarTexts) > 0 {
+		sb.WriteString("=== Similar Texts ===\n")
+		for _, st := range result.SimilarTexts {
+			sb.WriteString(fmt.Sprintf("• %s\n", st.Source))
+		}
+		sb.WriteString("\n")
+	}
+
+	if result.GraphContext != nil && len(result.GraphContext.Relationships) > 0 {
+		sb.WriteString("=== Entity Relationships ===\n")
+		for _, rel := range result.GraphContext.Relationships {
+			sb.WriteString(fmt.Sprintf("• %s -[%s]-> %s\n", rel.From, rel.Type, rel.To))
+		}
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// formatSeeds renders a verified seed translation section worded for the seed
+// strictness, longest source first.
+func (pb *PromptBuilder) formatSeeds(seeds map[string]string) string {
+	if len(seeds) == 0 {
+		return ""
 	}
+	var sb strings.Builder
+	if pb.strictness == SeedStrictnessOff {
+		sb.WriteString("=== Verified Seed Translations (USE THESE AS REFERENCE) ===\n")
+	} else {
+		sb.WriteString("=== Verified Seed Translations (MANDATORY) ===\n")
+		sb.WriteString("These translations are verified. Wherever a source below appears in the text, translate it exactly as given; do not rephrase it.\n")
+	}
+	for _, src := range graph.SortedTermKeys(seeds) {
+		sb.WriteString(fmt.Sprintf("• %s → %s\n", src, seeds[src]))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
 
+// formatTerminology renders a terminology reference section in a stable order.
+func formatTerminology(terminologyMap map[string]string) string {
+	if len(terminologyMap) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("=== Terminology Reference ===\n")
+	for _, zh := range graph.SortedTermKeys(terminologyMap) {
+		sb.WriteString(fmt.Sprintf("• %s → %s\n", zh, terminologyMap[zh]))
+	}
+	return sb.String()
+}
+
+// countExample shows, for one target language, how a count is phrased so it reads
+// correctly for any number.
+type countExample struct {
+	example string   // 获得{{var_1}}个物品 rendered in the target language
+	avoid   []string // words implying one value or a plural, which the model tends to add
+}
+
+// countExamples holds the count phrasing examples by primary language subtag.
+// Targets without an entry get the guidance without an example.
+var countExamples = map[string]countExample{
+	"vi": {example: "Nhận được {{var_1}} vật phẩm", avoid: []string{"một", "những", "các"}},
+}
+
+// formatCountGuidance renders guidance for the placeholders standing for a count,
+// such as {0} in 获得{0}个物品, which the model tends to phrase for one value only.
+// With numbered, each placeholder is listed with the number of its text in a batch.
+func (pb *PromptBuilder) formatCountGuidance(mappings [][]interpolation.Mapping, numbered bool) string {
+	var counts []string
+	for i, mapping := range mappings {
+		for _, m := range mapping {
+			if !m.Count {
+				continue
+			}
+			if numbered {
+				counts = append(counts, fmt.Sprintf("[%d] %s", i+1, m.Placeholder))
+			} else {
+				counts = append(counts, m.Placeholder)
+			}
+		}
+	}
+	if len(counts) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("=== Counts ===\n")
+	sb.WriteString("These placeholders stand for a number followed by a measure word. Phrase them so the sentence reads correctly for any number, including 1: ")
+	if ex, ok := countExamples[pb.targetCode]; ok {
+		quoted := make([]string, len(ex.avoid))
+		for i, w := range ex.avoid {
+			quoted[i] = fmt.Sprintf("%q", w)
+		}
+		sb.WriteString(fmt.Sprintf("put the placeholder directly before the noun (获得{{var_1}}个物品 → %s), "+
+			"and do not add %s or translate the measure word as a separate noun.\n", ex.example, strings.Join(quoted, ", ")))
+	} else {
+		sb.WriteString("put the placeholder directly before the noun, and do not translate the measure word as a separate noun.\n")
+	}
+	for _, c := range counts {
+		sb.WriteString(fmt.Sprintf("• %s\n", c))
+	}
+	sb.WriteString("\n")
 	return sb.String()
 }
@@ -0,0 +1,139 @@
+package translation
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"rag-translator/internal/interpolation"
+	"rag-translator/internal/language"
+)
+
+func TestFormatCountGuidance(t *testing.T) {
+	_, mapping := interpolation.Protect("获得{0}个物品")
+	tests := []struct {
+		name    string
+		target  string
+		want    []string
+		notWant []string
+	}{
+		{"vietnamese example", "vi-VN", []string{"=== Counts ===", "Nhận được {{var_1}} vật phẩm", `"một"`, "• {{var_1}}"}, nil},
+		{"no example for thai", "th-TH", []string{"=== Counts ===", "• {{var_1}}"}, []string{"Nhận được", "một"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, _ := language.Lookup(language.DefaultSource)
+			target, err := language.Lookup(tt.target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pb := NewPromptBuilder()
+			pb.SetLanguages(source, target)
+			got := pb.formatCountGuidance([][]interpolation.Mapping{mapping}, false)
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("guidance missing %q:\n%s", w, got)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(got, w) {
+					t.Errorf("guidance contains %q:\n%s", w, got)
+				}
+			}
+		})
+	}
+
+	pb := NewPromptBuilder()
+	_, plain := interpolation.Protect("获得{0}")
+	if got := pb.formatCountGuidance([][]interpolation.Mapping{plain}, false); got != "" {
+		t.Errorf("guidance without counts = %q, want empty", got)
+	}
+}
+
+func TestFullGlossaryPrefixSavesTokens(t *testing.T) {
+	glossary := map[string]string{
+		"金币": "Kim tệ", "宝石": "Bảo thạch", "门派": "Môn phái",
+		"掌门": "Chưởng môn", "神兵": "Thần binh", "秘籍": "Bí tịch",
+	}
+	batches := [][]string{
+		{"获得金币和宝石", "门派掌门赏赐神兵"},
+		{"用金币购买秘籍", "宝石镶嵌神兵"},
+		{"掌门传授秘籍", "门派宝库有金币"},
+		{"神兵需要宝石", "秘籍记载门派武功"},
+		{"掌门的金币", "神兵与秘籍"},
+	}
+
+	// send translates every batch through a client that records its requests, and
+	// returns the system and user prompt of each.
+	send := func(full bool) (systems, users []string) {
+		oc := NewOpusClient("key", "model", 0)
+		oc.SetTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			var req geminiRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatalf("decode request: %v", err)
+			}
+			systems = append(systems, req.SystemInstruction.Parts[0].Text)
+			users = append(users, req.Contents[0].Parts[0].Text)
+			body, _ := json.Marshal(geminiResponse{Candidates: []geminiCandidate{{
+				Content:      geminiContent{Parts: []geminiPart{{Text: "a ||| b"}}},
+				FinishReason: "STOP",
+			}}})
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(string(body)))}, nil
+		}))
+
+		pb := NewPromptBuilder()
+		if full {
+			pb.SetGlossary(glossary)
+		}
+		for _, batch := range batches {
+			var terms map[string]string
+			if !full {
+				terms = pb.SelectTerms(batch, glossary)
+			}
+			userPrompt := pb.BuildBatchUserPrompt(batch, nil, terms, nil)
+			if _, _, err := oc.TranslateBatchDetailed(context.Background(), pb.GetSystemPrompt(), userPrompt, len(batch)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return systems, users
+	}
+
+	fullSystems, fullUsers := send(true)
+	perBatchSystems, perBatchUsers := send(false)
+
+	for i, system := range fullSystems {
+		if system != fullSystems[0] {
+			t.Fatalf("request %d has a different system prompt, so the prefix cannot be cached", i+1)
+		}
+	}
+	for zh, vi := range glossary {
+		if !strings.Contains(fullSystems[0], zh+" → "+vi) {
+			t.Errorf("prefix misses %s → %s", zh, vi)
+		}
+	}
+	for i, user := range fullUsers {
+		if strings.Contains(user, "Terminology Reference") {
+			t.Errorf("request %d repeats terminology after the prefix:\n%s", i+1, user)
+		}
+	}
+
+	// A cached prefix is paid once; everything else is paid per request.
+	tokens := func(prompts []string) int {
+		n := 0
+		for _, p := range prompts {
+			n += EstimateTokens(p)
+		}
+		return n
+	}
+	fullCost := EstimateTokens(fullSystems[0]) + tokens(fullUsers)
+	perBatchCost := tokens(perBatchSystems) + tokens(perBatchUsers)
+	if tokens(fullUsers) >= tokens(perBatchUsers) {
+		t.Errorf("per-request tokens with the full glossary = %d, want fewer than %d", tokens(fullUsers), tokens(perBatchUsers))
+	}
+	if fullCost >= perBatchCost {
+		t.Errorf("uncached tokens with the full glossary = %d, want fewer than %d", fullCost, perBatchCost)
+	}
+}
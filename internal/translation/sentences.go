@@ -0,0 +1,123 @@
+package translation
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// sentenceTerminators end a sentence. Repeats ("！！", "？！") stay with the sentence.
+const sentenceTerminators = "。！？!?"
+
+// sentenceClosers may follow a terminator and still belong to its sentence, as
+// the closing quote in 「走吧。」.
+const sentenceClosers = "」』”’）)】》\"'"
+
+// SentenceSplit is a long text cut into sentences. Joining Lead, then each
+// sentence followed by its separator, gives back the text exactly.
+type SentenceSplit struct {
+	Lead       string   // whitespace before the first sentence
+	Sentences  []string // each keeps its terminating punctuation
+	Separators []string // whitespace and line breaks after each sentence, kept verbatim
+}
+
+// SplitSentences cuts text after each run of sentence terminators (。！？) and at
+// line breaks, either real ones or the two-character escape \n used in Lua and
+// INI strings. Interpolation variables contain none of these, so each stays
+// whole within one sentence.
+func SplitSentences(text string) SentenceSplit {
+	lead := separatorLen(text)
+	s := SentenceSplit{Lead: text[:lead]}
+	rest := text[lead:]
+
+	for rest != "" {
+		end := sentenceEnd(rest)
+		sep := separatorLen(rest[end:])
+		s.Sentences = append(s.Sentences, rest[:end])
+		s.Separators = append(s.Separators, rest[end:end+sep])
+		rest = rest[end+sep:]
+	}
+	return s
+}
+
+// sentenceEnd returns the byte length of the sentence at the start of text.
+func sentenceEnd(text string) int {
+	for i := 0; i < len(text); {
+		if isLineBreak(text[i:]) {
+			return i
+		}
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		if !strings.ContainsRune(sentenceTerminators, r) {
+			continue
+		}
+		// Take further terminators and closing quotes, then stop.
+		for i < len(text) {
+			r, size := utf8.DecodeRuneInString(text[i:])
+			if !strings.ContainsRune(sentenceTerminators, r) && !strings.ContainsRune(sentenceClosers, r) {
+				break
+			}
+			i += size
+		}
+		return i
+	}
+	return len(text)
+}
+
+// separatorLen returns the byte length of the whitespace and line breaks at the
+// start of text.
+func separatorLen(text string) int {
+	n := 0
+	for n < len(text) {
+		if strings.HasPrefix(text[n:], `\n`) {
+			n += 2
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(text[n:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		n += size
+	}
+	return n
+}
+
+// isLineBreak reports whether text starts with a line break.
+func isLineBreak(text string) bool {
+	return text[0] == '\n' || text[0] == '\r' || strings.HasPrefix(text, `\n`)
+}
+
+// Join rebuilds the text with translated in place of the sentences, keeping the
+// lead and separators. translated must hold one entry per sentence. Chinese runs
+// sentences together, so where the source has no separator a space is put between
+// translations that end and start outside CJK text.
+func (s SentenceSplit) Join(translated []string) string {
+	var b strings.Builder
+	b.WriteString(s.Lead)
+	for i, sentence := range translated {
+		b.WriteString(sentence)
+		sep := s.Separators[i]
+		if sep == "" && i+1 < len(translated) && needsGap(sentence, translated[i+1]) {
+			sep = " "
+		}
+		b.WriteString(sep)
+	}
+	return b.String()
+}
+
+// needsGap reports whether a space belongs between two adjacent sentences.
+func needsGap(prev, next string) bool {
+	last, _ := utf8.DecodeLastRuneInString(prev)
+	first, _ := utf8.DecodeRuneInString(next)
+	if prev == "" || next == "" || unicode.IsSpace(last) || unicode.IsSpace(first) {
+		return false
+	}
+	return !isCJK(last) && !isCJK(first)
+}
+
+// isCJK reports whether r is a CJK character or CJK/full-width punctuation, which
+// are written without spaces between sentences.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) ||
+		(r >= 0x3000 && r <= 0x303F) || (r >= 0xFF00 && r <= 0xFFEF)
+}
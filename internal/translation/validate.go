@@ -0,0 +1,60 @@
+package translation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// fullWidthPairs folds full-width and typographic brackets/quotes to their ASCII
+// forms, since translating Chinese punctuation to ASCII is legitimate.
+var fullWidthPairs = strings.NewReplacer(
+	"（", "(", "）", ")",
+	"【", "[", "】", "]", "［", "[", "］", "]",
+	"｛", "{", "｝", "}",
+	"“", `"`, "”", `"`, "＂", `"`,
+	"‘", "'", "’", "'", "＇", "'",
+)
+
+// balanceChars are the characters whose counts must survive translation.
+const balanceChars = `()[]{}"'`
+
+// ValidateBalance checks that a translation keeps the brackets and quotes of its
+// source: the same count of each of ()[]{}"' and, when the source brackets nest
+// correctly, correctly nested brackets. Unbalanced output breaks Lua/INI files
+// when written back.
+func ValidateBalance(source, translated string) error {
+	src := fullWidthPairs.Replace(source)
+	dst := fullWidthPairs.Replace(translated)
+
+	for _, ch := range balanceChars {
+		want := strings.Count(src, string(ch))
+		got := strings.Count(dst, string(ch))
+		if want != got {
+			return fmt.Errorf("%q count mismatch: source has %d, translation has %d", ch, want, got)
+		}
+	}
+
+	if bracketsNested(src) && !bracketsNested(dst) {
+		return fmt.Errorf("brackets are not properly nested in translation")
+	}
+
+	return nil
+}
+
+// bracketsNested reports whether every ()[]{} bracket in s is closed in order.
+func bracketsNested(s string) bool {
+	closers := map[rune]rune{')': '(', ']': '[', '}': '{'}
+	var stack []rune
+	for _, r := range s {
+		switch r {
+		case '(', '[', '{':
+			stack = append(stack, r)
+		case ')', ']', '}':
+			if len(stack) == 0 || stack[len(stack)-1] != closers[r] {
+				return false
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	return len(stack) == 0
+}
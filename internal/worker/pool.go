@@ -3,6 +3,7 @@ package worker
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 
 	"github.com/rs/zerolog/log"
 )
@@ -19,8 +20,9 @@ type ProcessFunc[T any, R any] func(ctx context.Context, input T) (R, error)
 
 // Pool is a generic worker pool with configurable concurrency.
 type Pool[T any, R any] struct {
-	workers int
-	process ProcessFunc[T, R]
+	workers  int
+	process  ProcessFunc[T, R]
+	progress func(done, total int) // optional, called after each task
 }
 
 // NewPool creates a new worker pool.
@@ -34,6 +36,13 @@ func NewPool[T any, R any](workers int, fn ProcessFunc[T, R]) *Pool[T, R] {
 	}
 }
 
+// SetProgress registers fn to be called with the number of finished tasks after
+// each one completes. fn is called from the worker goroutines and must be safe
+// for concurrent use.
+func (p *Pool[T, R]) SetProgress(fn func(done, total int)) {
+	p.progress = fn
+}
+
 // Execute runs all inputs through the worker pool and returns results.
 // Supports context cancellation and graceful shutdown.
 func (p *Pool[T, R]) Execute(ctx context.Context, inputs []T) []Task[T, R] {
@@ -41,6 +50,7 @@ func (p *Pool[T, R]) Execute(ctx context.Context, inputs []T) []Task[T, R] {
 	inputCh := make(chan int, len(inputs))
 
 	var wg sync.WaitGroup
+	var done atomic.Int64
 
 	// Start workers.
 	for w := 0; w < p.workers; w++ {
@@ -64,6 +74,9 @@ func (p *Pool[T, R]) Execute(ctx context.Context, inputs []T) []Task[T, R] {
 					if err != nil {
 						log.Error().Err(err).Int("worker", workerID).Int("index", idx).Msg("Task failed")
 					}
+					if p.progress != nil {
+						p.progress(int(done.Add(1)), len(inputs))
+					}
 				}
 			}
 		}(w)